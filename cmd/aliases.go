@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
 
 	"github.com/jackreid/task/internal/model"
@@ -12,75 +13,86 @@ func runReady(args []string) error {
 	return runList(append([]string{"-s", "todo"}, args...))
 }
 
-// runTake sets a task status to 'progress'
-// Alias for: task update $id -s progress
+// runTake sets task status to 'progress'
+// Alias for: task update $id... -s progress
 func runTake(args []string) error {
-	if len(args) < 1 {
-		errorf("Error: task ID is required")
-		fmt.Fprintln(stderr, "Usage: task take <id>")
-		return fmt.Errorf("task ID is required")
-	}
-	return updateTaskStatus(args[0], model.StatusProgress)
+	return runStatusAlias("take", model.StatusProgress, args)
 }
 
-// runComplete sets a task status to 'done'
-// Alias for: task update $id -s done
+// runComplete sets task status to 'done'
+// Alias for: task update $id... -s done
 func runComplete(args []string) error {
-	if len(args) < 1 {
-		errorf("Error: task ID is required")
-		fmt.Fprintln(stderr, "Usage: task complete <id>")
-		return fmt.Errorf("task ID is required")
-	}
-	return updateTaskStatus(args[0], model.StatusDone)
+	return runStatusAlias("complete", model.StatusDone, args)
 }
 
-// runBlock sets a task status to 'blocked'
-// Alias for: task update $id -s blocked
+// runBlock sets task status to 'blocked'
+// Alias for: task update $id... -s blocked
 func runBlock(args []string) error {
-	if len(args) < 1 {
-		errorf("Error: task ID is required")
-		fmt.Fprintln(stderr, "Usage: task block <id>")
-		return fmt.Errorf("task ID is required")
-	}
-	return updateTaskStatus(args[0], model.StatusBlocked)
+	return runStatusAlias("block", model.StatusBlocked, args)
 }
 
-// runAbandon sets a task status to 'abandon'
-// Alias for: task update $id -s abandon
+// runAbandon sets task status to 'abandon'
+// Alias for: task update $id... -s abandon
 func runAbandon(args []string) error {
-	if len(args) < 1 {
-		errorf("Error: task ID is required")
-		fmt.Fprintln(stderr, "Usage: task abandon <id>")
-		return fmt.Errorf("task ID is required")
-	}
-	return updateTaskStatus(args[0], model.StatusAbandon)
+	return runStatusAlias("abandon", model.StatusAbandon, args)
 }
 
-// updateTaskStatus is a helper that updates a task's status
-func updateTaskStatus(taskID string, status model.Status) error {
-	s := getStore()
+// runStatusAlias parses the arguments shared by the status aliases and
+// applies status to every selected task
+func runStatusAlias(name string, status model.Status, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
 
-	task, err := s.FindByID(taskID)
-	if err != nil {
-		errorf("Error: %v", err)
-		return err
-	}
+	var bulk bulkFlags
+	bulk.register(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, `Set task status to '%s'.
+
+Usage:
+  task %s <id>... [flags]
+  task %s --where <filter> [flags]
+
+Without an ID, the task for the current git branch is used (see 'task current').
 
-	if task == nil {
-		errorf("Error: task not found: %s", taskID)
-		return fmt.Errorf("task not found: %s", taskID)
+Flags:
+%s
+
+Examples:
+  task %s abc
+  task %s abc def ghi
+  task %s --where "label=sprint-3" --dry-run
+`, status, name, name, bulkFlagsUsage, name, name, name)
 	}
 
-	if err := task.SetStatus(status); err != nil {
-		errorf("Error: %v", err)
+	if err := fs.Parse(splitArgs(fs, args)); err != nil {
 		return err
 	}
 
-	if err := s.Update(task); err != nil {
-		errorf("Error: %v", err)
+	// Without IDs, use the task for the current git branch
+	taskIDs := currentTaskIDs(fs.Args(), bulk)
+	if len(taskIDs) < 1 && bulk.where == "" {
+		errorf("Error: task ID is required")
+		fmt.Fprintf(stderr, "Usage: task %s <id>...\n", name)
+		return fmt.Errorf("task ID is required")
+	}
+
+	return updateTaskStatus(taskIDs, bulk, status)
+}
+
+// updateTaskStatus is a helper that updates the status of the selected tasks
+func updateTaskStatus(taskIDs []string, bulk bulkFlags, status model.Status) error {
+	s := getStore()
+
+	updated, err := applyBulk(s, taskIDs, bulk, "set status to "+status.String()+" for", func(t *model.Task) error {
+		return t.SetStatus(status)
+	})
+	if err != nil {
 		return err
 	}
 
-	fmt.Fprintf(stdout, "Updated task %s to %s\n", task.ID, status)
+	for _, t := range updated {
+		fmt.Fprintf(stdout, "Updated task %s to %s\n", t.ID, status)
+	}
 	return nil
 }
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+func runArchive(args []string) error {
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var olderThan int
+
+	fs.IntVar(&olderThan, "older-than", 0, "Only archive tasks last updated more than N days ago")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Move closed tasks into the archive.
+
+Closed tasks are those with status 'done' or 'abandon'. Archived tasks are
+kept in .task/archive.jsonl and can be listed with 'task list --archived'
+or brought back with 'task restore'.
+
+Usage:
+  task archive [flags]
+
+Flags:
+  --older-than int  Only archive tasks last updated more than N days ago
+
+Examples:
+  task archive
+  task archive --older-than 30`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if olderThan < 0 {
+		errorf("Error: --older-than must not be negative")
+		return fmt.Errorf("--older-than must not be negative")
+	}
+
+	var before time.Time
+	if olderThan > 0 {
+		before = time.Now().UTC().AddDate(0, 0, -olderThan)
+	}
+
+	s := getStore()
+
+	archived, err := s.Archive(before)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	if archived == 0 {
+		fmt.Fprintln(stdout, "No closed tasks to archive")
+	} else {
+		fmt.Fprintf(stdout, "Archived %d closed task(s)\n", archived)
+	}
+
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Restore an archived task to the live task list.
+
+Usage:
+  task restore <id>
+
+Examples:
+  task restore abc`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 1 {
+		errorf("Error: task ID is required")
+		fs.Usage()
+		return fmt.Errorf("task ID is required")
+	}
+
+	taskID := fs.Arg(0)
+
+	s := getStore()
+
+	task, err := s.Restore(taskID)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Restored task %s: %s\n", task.ID, task.Title)
+	return nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+func runBoard(args []string) error {
+	fs := flag.NewFlagSet("board", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var width, limit int
+	var filters filterFlags
+
+	fs.IntVar(&width, "width", 0, "Board width in columns (default: terminal width)")
+	fs.IntVar(&limit, "limit", 0, "Maximum lines per column, including the \"+N more\" line (default: all)")
+	filters.register(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Print tasks as a kanban board with a column per status.
+
+Usage:
+  task board [flags]
+
+Flags:
+  --width int         Board width (default: terminal width)
+  --limit int         Maximum lines per column, including "+N more" (default: all)
+  -l, --label string  Filter by label
+  -t, --type string   Filter by type: task, bug, feature
+  -s, --status string Only show the column for this status
+  --archived          Show archived tasks instead of live tasks
+  --all               Show both live and archived tasks
+
+Examples:
+  task board
+  task board -l frontend
+  task board --limit 5 --width 120`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter, err := filters.filter()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	tasks, err := getStore().ListFiltered(filter)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	statuses := boardStatuses
+	if filter.Status != nil {
+		statuses = []model.Status{*filter.Status}
+	}
+	columns := groupByStatus(tasks, statuses)
+
+	if width <= 0 {
+		width, _ = terminalSize()
+	}
+
+	height := limit
+	if height <= 0 {
+		for _, col := range columns {
+			if len(col.Tasks) > height {
+				height = len(col.Tasks)
+			}
+		}
+	}
+
+	fmt.Fprintln(stdout, strings.Join(renderBoard(columns, width, height, nil), "\n"))
+	return nil
+}
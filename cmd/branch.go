@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/jackreid/task/internal/git"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+func runBranch(args []string) error {
+	fs := flag.NewFlagSet("branch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, `Create and check out a git branch for a task.
+
+The branch is named from the branch_pattern in .task/config (default %q),
+where {slug} is the title in lowercase with dashes. The branch is recorded on
+the task, so running this again switches back to it and 'task current' knows
+which task you're working on.
+
+Usage:
+  task branch <id>
+
+Examples:
+  task branch abc`, projectConfig.BranchNamePattern())
+		fmt.Fprintln(stderr)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		errorf("Error: task ID is required")
+		fs.Usage()
+		return fmt.Errorf("task ID is required")
+	}
+
+	s := getStore()
+	taskID := fs.Arg(0)
+	task, err := s.FindByID(taskID)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	if task == nil {
+		errorf("Error: task not found: %s", taskID)
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+
+	branch := task.Branch
+	if branch == "" {
+		branch = git.BranchName(projectConfig.BranchNamePattern(), task)
+	}
+	if err := git.Checkout(workDir, branch); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	if task.Branch != branch {
+		task.SetBranch(branch)
+		if err := s.Update(task); err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+	}
+
+	fmt.Fprintf(stdout, "Switched to branch %s for task %s\n", branch, task.ID)
+	return nil
+}
+
+func runCurrent(args []string) error {
+	fs := flag.NewFlagSet("current", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var jsonOutput, idOnly bool
+	fs.BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	fs.BoolVar(&idOnly, "id", false, "Print only the task ID")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Show the task for the current git branch.
+
+The task is the one whose branch was created by 'task branch', or else the
+one whose ID appears where {id} is in the branch_pattern. Commands such as
+'task note' and 'task complete' use it when no task ID is given.
+
+Usage:
+  task current [flags]
+
+Flags:
+  --json  Output as JSON
+  --id    Print only the task ID`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	task, err := currentTask(getStore())
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	switch {
+	case idOnly:
+		fmt.Fprintln(stdout, task.ID)
+		return nil
+	case jsonOutput:
+		return printTaskJSON(task)
+	}
+	return printTaskDetail(task)
+}
+
+// currentTask returns the task being worked on in the checked out git
+// branch: the task the branch is recorded on, or else the task whose ID the
+// branch name holds
+func currentTask(s *store.Store) (*model.Task, error) {
+	branch, err := git.CurrentBranch(workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		if tasks[i].Branch == branch {
+			return &tasks[i], nil
+		}
+	}
+
+	if id, ok := git.TaskIDFromBranch(projectConfig.BranchNamePattern(), branch); ok {
+		for i := range tasks {
+			if tasks[i].ID == id {
+				return &tasks[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no task found for branch %s", branch)
+}
+
+// currentTaskIDs returns the ID of the current branch's task when no IDs
+// were given, for commands that default to it
+func currentTaskIDs(ids []string, bulk bulkFlags) []string {
+	if len(ids) > 0 || bulk.where != "" {
+		return ids
+	}
+	if task, err := currentTask(getStore()); err == nil {
+		return []string{task.ID}
+	}
+	return ids
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+// bulkFlags holds the selection and safety flags shared by commands that
+// accept multiple task IDs
+type bulkFlags struct {
+	where  string
+	dryRun bool
+	yes    bool
+}
+
+// register adds the bulk flags to fs
+func (b *bulkFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&b.where, "where", "", "Select tasks by filter instead of IDs")
+	fs.BoolVar(&b.dryRun, "dry-run", false, "Show the affected tasks without changing anything")
+	fs.BoolVar(&b.yes, "y", false, "Skip the confirmation prompt")
+	fs.BoolVar(&b.yes, "yes", false, "Skip the confirmation prompt")
+}
+
+// bulkFlagsUsage is the help text for the flags added by bulkFlags.register
+const bulkFlagsUsage = `  --where string           Select tasks by filter instead of IDs, e.g. "status=todo,label=ui"
+  --dry-run                Show the affected tasks without changing anything
+  -y, --yes                Skip the confirmation prompt for large selections`
+
+// splitArgs reorders args so that all flags (with their values) come before
+// positional arguments, letting flags appear anywhere on the command line.
+// fs is used to tell boolean flags from flags that take a value. Positional
+// arguments are placed after a "--" terminator so they are never parsed as flags
+func splitArgs(fs *flag.FlagSet, args []string) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			positional = append(positional, arg)
+			continue
+		}
+
+		flags = append(flags, arg)
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			// Unknown flag, let fs.Parse report it
+			continue
+		}
+		if isBoolFlag(f) {
+			continue
+		}
+		if i+1 < len(args) {
+			flags = append(flags, args[i+1])
+			i++
+		}
+	}
+
+	result := make([]string, 0, len(flags)+len(positional)+1)
+	result = append(result, flags...)
+	result = append(result, "--")
+	result = append(result, positional...)
+	return result
+}
+
+// isBoolFlag reports whether f is a boolean flag that takes no value
+func isBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
+
+// parseWhere parses a --where expression into a store filter.
+// Terms are key=value pairs separated by commas or spaces; supported keys are
+// status (s), type (t), label (l) and text (q) for a free-text search
+func parseWhere(expr string) (store.Filter, error) {
+	filter := store.Filter{}
+
+	terms := strings.FieldsFunc(expr, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if len(terms) == 0 {
+		return filter, fmt.Errorf("empty --where filter")
+	}
+
+	for _, term := range terms {
+		parts := strings.SplitN(term, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return filter, fmt.Errorf("invalid --where term: %s (expected key=value)", term)
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+		switch key {
+		case "status", "s":
+			status, err := model.ParseStatus(value)
+			if err != nil {
+				return filter, err
+			}
+			filter.Status = &status
+		case "type", "t":
+			tt, err := model.ParseTaskType(value)
+			if err != nil {
+				return filter, err
+			}
+			filter.Type = &tt
+		case "label", "l":
+			filter.Label = &value
+		case "text", "q":
+			filter.Query = &value
+		default:
+			return filter, fmt.Errorf("unknown --where key: %s (valid: status, type, label, text)", key)
+		}
+	}
+
+	return filter, nil
+}
+
+// selectTasks returns the tasks named by ids, or matching the where filter.
+// Exactly one of ids or where must be provided. IDs are deduplicated and every
+// ID must exist
+func selectTasks(s *store.Store, ids []string, where string) ([]model.Task, error) {
+	if where != "" && len(ids) > 0 {
+		return nil, fmt.Errorf("task IDs and --where cannot be combined")
+	}
+
+	if where != "" {
+		filter, err := parseWhere(where)
+		if err != nil {
+			return nil, err
+		}
+		return s.ListFiltered(filter)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("task ID is required")
+	}
+
+	tasks, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]model.Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+
+	seen := make(map[string]bool, len(ids))
+	selected := make([]model.Task, 0, len(ids))
+	for _, taskID := range ids {
+		if seen[taskID] {
+			continue
+		}
+		seen[taskID] = true
+		t, ok := byID[taskID]
+		if !ok {
+			return nil, fmt.Errorf("task not found: %s", taskID)
+		}
+		selected = append(selected, t)
+	}
+	return selected, nil
+}
+
+// printBulkPreview lists the tasks an action would affect, where action
+// completes the sentence "Would <action> N task(s)"
+func printBulkPreview(action string, tasks []model.Task) {
+	fmt.Fprintf(stdout, "Would %s %d task(s):\n", action, len(tasks))
+	for _, t := range tasks {
+		printTaskLine(t)
+	}
+}
+
+// confirmBulk asks for confirmation when more tasks than the configured
+// threshold are selected. Returns true when the action may proceed
+func confirmBulk(action string, tasks []model.Task, yes bool) bool {
+	if yes || len(tasks) <= projectConfig.ConfirmThreshold() {
+		return true
+	}
+
+	printBulkPreview(action, tasks)
+	fmt.Fprint(stdout, "Proceed? [y/N] ")
+
+	answer, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(stdout)
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// selectBulk selects tasks by ids or --where and handles --dry-run and the
+// confirmation threshold. It returns the tasks to act on, or nil when there is
+// nothing to do (empty selection or dry run)
+func selectBulk(s *store.Store, ids []string, b bulkFlags, action string) ([]model.Task, error) {
+	tasks, err := selectTasks(s, ids, b.where)
+	if err != nil {
+		errorf("Error: %v", err)
+		return nil, err
+	}
+
+	if len(tasks) == 0 {
+		fmt.Fprintln(stdout, "No tasks matched.")
+		return nil, nil
+	}
+
+	if b.dryRun {
+		printBulkPreview(action, tasks)
+		return nil, nil
+	}
+
+	if !confirmBulk(action, tasks, b.yes) {
+		errorf("Aborted")
+		return nil, fmt.Errorf("aborted")
+	}
+
+	return tasks, nil
+}
+
+// applyBulk selects tasks with selectBulk, applies fn to each of them and saves
+// them in a single write. It returns the updated tasks
+func applyBulk(s *store.Store, ids []string, b bulkFlags, action string, fn func(t *model.Task) error) ([]model.Task, error) {
+	tasks, err := selectBulk(s, ids, b, action)
+	if err != nil || tasks == nil {
+		return nil, err
+	}
+
+	for i := range tasks {
+		if err := fn(&tasks[i]); err != nil {
+			errorf("Error: %v", err)
+			return nil, err
+		}
+	}
+
+	if err := s.UpdateMany(tasks); err != nil {
+		errorf("Error: %v", err)
+		return nil, err
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jackreid/task/internal/changelog"
+	"github.com/jackreid/task/internal/git"
+)
+
+// defaultChangelogFile is the changelog --write prepends to
+const defaultChangelogFile = "CHANGELOG.md"
+
+func runChangelog(args []string) error {
+	fs := flag.NewFlagSet("changelog", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var since, until, version, templateText, file string
+	var write bool
+
+	fs.StringVar(&since, "since", "", "Tag, commit or date to list tasks completed after (default: the latest tag)")
+	fs.StringVar(&until, "until", "", "Tag, commit or date to list tasks completed up to (default: now)")
+	fs.StringVar(&version, "version", changelog.Unreleased, "Version for the release heading")
+	fs.StringVar(&templateText, "template", "", "Go template for the release, or the name of a template in .task/templates/")
+	fs.BoolVar(&write, "w", false, "Prepend the release to the changelog file")
+	fs.BoolVar(&write, "write", false, "Prepend the release to the changelog file")
+	fs.StringVar(&file, "file", defaultChangelogFile, "Changelog file to write")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Print release notes for the tasks completed since a tag or date.
+
+Done tasks are grouped into Features, Bug fixes and Other by type, using the
+time their status last changed (or their last update for older tasks).
+Archived tasks are included.
+
+Usage:
+  task changelog [flags]
+
+Flags:
+  --since string     Tag, commit or date (YYYY-MM-DD) to list tasks completed
+                     after (default: the latest tag, or all time)
+  --until string     Tag, commit or date to list tasks completed up to. A
+                     date includes the whole day (default: now)
+  --version string   Version for the release heading (default "Unreleased")
+  --template string  Go template for the release, or the name of a template
+                     in .task/templates/. It is given .Version, .Date and
+                     .Sections, each with a .Title and .Tasks
+  -w, --write        Prepend the release to the changelog file, replacing an
+                     entry with the same heading
+  --file string      Changelog file to write (default "CHANGELOG.md")
+
+Examples:
+  task changelog
+  task changelog --since v1.2.0 --version 1.3.0 --write
+  task changelog --since 2024-05-01 --until 2024-06-01
+  task changelog --template '{{range .Sections}}{{range .Tasks}}* {{.Title}}{{"\n"}}{{end}}{{end}}'`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		errorf("Error: unexpected argument: %s", fs.Arg(0))
+		fs.Usage()
+		return fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+	if write && templateText != "" {
+		errorf("Error: --write and --template cannot be combined")
+		return fmt.Errorf("--write and --template cannot be combined")
+	}
+
+	now := time.Now()
+	if since == "" {
+		tag, err := git.LatestTag(workDir)
+		if err != nil && !errors.Is(err, git.ErrNotRepository) {
+			errorf("Error: %v", err)
+			return err
+		}
+		since = tag
+	}
+	from, err := parseChangelogBound(since, time.Time{}, false)
+	if err != nil {
+		errorf("Error: --since: %v", err)
+		return err
+	}
+	to, err := parseChangelogBound(until, now, true)
+	if err != nil {
+		errorf("Error: --until: %v", err)
+		return err
+	}
+
+	s := getStore()
+	tasks, err := s.Load()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	archived, err := s.LoadArchive()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	date := now
+	if until != "" {
+		date = to
+	}
+	release := changelog.New(version, date, append(tasks, archived...), from, to)
+
+	if templateText != "" {
+		tmpl, err := loadTemplate(templateText)
+		if err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+		if err := tmpl.Execute(stdout, release); err != nil {
+			errorf("Error: executing template: %v", err)
+			return err
+		}
+		return nil
+	}
+
+	if !write {
+		fmt.Fprint(stdout, release.Markdown())
+		return nil
+	}
+
+	if release.Empty() {
+		fmt.Fprintln(stdout, "No completed tasks in the range, changelog not changed")
+		return nil
+	}
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		errorf("Error: %v", err)
+		return err
+	}
+	if err := os.WriteFile(path, []byte(changelog.Prepend(string(existing), release.Markdown())), 0644); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	fmt.Fprintf(stdout, "Added %s to %s\n", release.Heading(), file)
+	return nil
+}
+
+// parseChangelogBound turns a --since or --until value into a time: a date,
+// an RFC 3339 time, or a git tag or commit. An empty value returns def. A
+// date is the start of that day, or the end of it when endOfDay is set, so
+// that --until includes the tasks completed on the day
+func parseChangelogBound(value string, def time.Time, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := git.CommitTime(workDir, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("not a date, tag or commit: %s", value)
+	}
+	return t, nil
+}
@@ -2,12 +2,27 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/jackreid/task/internal/git"
 	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/remote"
+	"github.com/jackreid/task/internal/server"
+	"github.com/jackreid/task/internal/watch"
 )
 
 // testEnv sets up a test environment with isolated stdout/stderr and temp directory
@@ -17,6 +32,7 @@ type testEnv struct {
 	origWorkDir string
 	origEditor  string
 	origVisual  string
+	origXDG     string
 	stdout      *bytes.Buffer
 	stderr      *bytes.Buffer
 	cleanup     func()
@@ -34,6 +50,7 @@ func setupTestEnv(t *testing.T) *testEnv {
 	env.origWorkDir = workDir
 	env.origEditor = os.Getenv("EDITOR")
 	env.origVisual = os.Getenv("VISUAL")
+	env.origXDG = os.Getenv("XDG_CONFIG_HOME")
 
 	// Redirect output
 	stdout = env.stdout
@@ -44,6 +61,8 @@ func setupTestEnv(t *testing.T) *testEnv {
 	workDir = tmpDir
 	os.Setenv("EDITOR", "true")
 	os.Unsetenv("VISUAL")
+	// Keep the project registry out of the real user config
+	os.Setenv("XDG_CONFIG_HOME", t.TempDir())
 
 	env.cleanup = func() {
 		stdout = os.Stdout
@@ -59,6 +78,11 @@ func setupTestEnv(t *testing.T) *testEnv {
 		} else {
 			os.Setenv("VISUAL", env.origVisual)
 		}
+		if env.origXDG == "" {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		} else {
+			os.Setenv("XDG_CONFIG_HOME", env.origXDG)
+		}
 	}
 
 	return env
@@ -132,6 +156,26 @@ func TestRunInitAlreadyInitialized(t *testing.T) {
 	if err == nil {
 		t.Error("second init should return error")
 	}
+
+	// A project initialized before it could be registered is registered by
+	// running init again
+	os.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	run([]string{"new", "Unregistered"})
+	env.stdout.Reset()
+	if err := run([]string{"init"}); err != nil {
+		t.Fatalf("init of an unregistered project error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), "Registered existing project") {
+		t.Errorf("init output = %q", env.stdout.String())
+	}
+	env.stdout.Reset()
+	run([]string{"global", "list"})
+	if !strings.Contains(env.stdout.String(), "Unregistered") {
+		t.Errorf("global list after registering = %q", env.stdout.String())
+	}
+	if err := run([]string{"init"}); err == nil {
+		t.Error("init of a registered project should return error")
+	}
 }
 
 func TestRunNew(t *testing.T) {
@@ -377,6 +421,23 @@ func TestRunShowJSON(t *testing.T) {
 	if result["title"] != "Test Task" {
 		t.Errorf("show --json returned title %q, want %q", result["title"], "Test Task")
 	}
+	if _, ok := result["status_changed_at"]; ok {
+		t.Errorf("show --json of an unchanged task has status_changed_at = %v", result["status_changed_at"])
+	}
+
+	// show --json and list --json give a task the same status_changed_at
+	run([]string{"complete", taskID})
+	env.stdout.Reset()
+	run([]string{"show", "--json", taskID})
+	result = nil
+	json.Unmarshal(env.stdout.Bytes(), &result)
+	env.stdout.Reset()
+	run([]string{"list", "--json", "-s", "done"})
+	var listed []map[string]interface{}
+	json.Unmarshal(env.stdout.Bytes(), &listed)
+	if len(listed) != 1 || result["status_changed_at"] == nil || result["status_changed_at"] != listed[0]["status_changed_at"] {
+		t.Errorf("status_changed_at: show --json = %v, list --json = %v", result["status_changed_at"], listed)
+	}
 }
 
 func TestRunShowNotFound(t *testing.T) {
@@ -913,6 +974,51 @@ func TestRunEditWithFlagsEditsImmediately(t *testing.T) {
 	}
 }
 
+func TestRunDue(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	if err := run([]string{"new", "Release", "--due", "2024-07-01"}); err != nil {
+		t.Fatalf("new --due error = %v", err)
+	}
+	taskID := extractTaskID(env.stdout.String())
+	due := func() string {
+		task, _ := getStore().FindByID(taskID)
+		if task.Due.IsZero() {
+			return ""
+		}
+		return task.Due.Format(model.DateLayout)
+	}
+	if got := due(); got != "2024-07-01" {
+		t.Errorf("due after new = %q", got)
+	}
+
+	if err := run([]string{"update", taskID, "--due", "2024-08-01"}); err != nil {
+		t.Fatalf("update --due error = %v", err)
+	}
+	if got := due(); got != "2024-08-01" {
+		t.Errorf("due after update = %q", got)
+	}
+
+	if err := run([]string{"edit", taskID, "--due", "none"}); err != nil {
+		t.Fatalf("edit --due none error = %v", err)
+	}
+	if got := due(); got != "" {
+		t.Errorf("due after clearing = %q", got)
+	}
+
+	for _, args := range [][]string{
+		{"new", "Bad", "--due", "next week"},
+		{"update", taskID, "--due", "2024-13-01"},
+		{"edit", taskID, "--due", "tomorrow"},
+	} {
+		if err := run(args); err == nil {
+			t.Errorf("%v should return error", args)
+		}
+	}
+}
+
 // createTempEditorScript creates a temporary executable script file
 func createTempEditorScript(t *testing.T, content string) string {
 	t.Helper()
@@ -1082,6 +1188,2068 @@ func TestRunCleanEmpty(t *testing.T) {
 	}
 }
 
+func TestRunArchive(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	run([]string{"new", "Done Task"})
+	doneTaskID := extractTaskID(env.stdout.String())
+	run([]string{"new", "Todo Task"})
+	run([]string{"complete", doneTaskID})
+
+	env.stdout.Reset()
+	err := run([]string{"archive"})
+	if err != nil {
+		t.Errorf("run(archive) error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), "Archived 1 closed task(s)") {
+		t.Errorf("archive should report 1 archived task, got: %s", env.stdout.String())
+	}
+
+	env.stdout.Reset()
+	run([]string{"list"})
+	if strings.Contains(env.stdout.String(), "Done Task") {
+		t.Error("list should not show archived tasks by default")
+	}
+
+	env.stdout.Reset()
+	run([]string{"list", "--archived"})
+	output := env.stdout.String()
+	if !strings.Contains(output, "Done Task") || strings.Contains(output, "Todo Task") {
+		t.Errorf("list --archived should show only archived tasks, got: %s", output)
+	}
+
+	env.stdout.Reset()
+	run([]string{"list", "--all"})
+	output = env.stdout.String()
+	if !strings.Contains(output, "Done Task") || !strings.Contains(output, "Todo Task") {
+		t.Errorf("list --all should show live and archived tasks, got: %s", output)
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"show", doneTaskID}); err != nil {
+		t.Errorf("show should find archived tasks, error = %v", err)
+	}
+}
+
+func TestRunArchiveNoClosedTasks(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	run([]string{"new", "Todo Task"})
+
+	env.stdout.Reset()
+	if err := run([]string{"archive", "--older-than", "7"}); err != nil {
+		t.Errorf("run(archive --older-than) error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), "No closed tasks to archive") {
+		t.Errorf("archive should report nothing archived, got: %s", env.stdout.String())
+	}
+}
+
+func TestRunRestore(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	run([]string{"new", "Done Task"})
+	taskID := extractTaskID(env.stdout.String())
+	run([]string{"complete", taskID})
+	run([]string{"archive"})
+
+	env.stdout.Reset()
+	if err := run([]string{"restore", taskID}); err != nil {
+		t.Errorf("run(restore) error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), "Restored task") {
+		t.Errorf("restore should print restore message, got: %s", env.stdout.String())
+	}
+
+	env.stdout.Reset()
+	run([]string{"list", "-s", "done"})
+	if !strings.Contains(env.stdout.String(), "Done Task") {
+		t.Error("restored task should be back in the live list")
+	}
+
+	if err := run([]string{"restore", "xxx"}); err == nil {
+		t.Error("restore with unknown ID should return error")
+	}
+	if err := run([]string{"restore"}); err == nil {
+		t.Error("restore without ID should return error")
+	}
+}
+
+func TestRunCollections(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+
+	env.stdout.Reset()
+	if err := run([]string{"init", "--collection", "api"}); err != nil {
+		t.Fatalf("run(init --collection api) error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), "Initialized collection api") {
+		t.Errorf("init --collection should report the collection, got: %s", env.stdout.String())
+	}
+
+	run([]string{"new", "Default Task"})
+	run([]string{"--collection", "api", "new", "API Task"})
+
+	env.stdout.Reset()
+	run([]string{"list"})
+	output := env.stdout.String()
+	if !strings.Contains(output, "Default Task") || strings.Contains(output, "API Task") {
+		t.Errorf("list should only show the default collection, got: %s", output)
+	}
+
+	env.stdout.Reset()
+	run([]string{"list", "--collection=api"})
+	output = env.stdout.String()
+	if !strings.Contains(output, "API Task") || strings.Contains(output, "Default Task") {
+		t.Errorf("list --collection api should only show the api collection, got: %s", output)
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"list", "--all-collections"}); err != nil {
+		t.Fatalf("run(list --all-collections) error = %v", err)
+	}
+	output = env.stdout.String()
+	if !strings.Contains(output, "API Task") || !strings.Contains(output, "Default Task") || !strings.Contains(output, "api") {
+		t.Errorf("list --all-collections should show every collection, got: %s", output)
+	}
+
+	env.stdout.Reset()
+	run([]string{"list", "--all-collections", "--json"})
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(env.stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("list --all-collections --json output is not valid JSON: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("list --all-collections --json returned %d tasks, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if e["collection"] == nil {
+			t.Errorf("list --all-collections --json entry missing collection: %v", e)
+		}
+	}
+
+	if err := run([]string{"--collection", "missing", "list"}); err == nil {
+		t.Error("list with an uninitialized collection should return error")
+	}
+	if err := run([]string{"--collection", "Bad/Name", "list"}); err == nil {
+		t.Error("list with an invalid collection name should return error")
+	}
+}
+
+func TestRunCollectionConfigDefault(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	run([]string{"init", "--collection", "web"})
+	os.WriteFile(workDir+"/.task/config", []byte(`{"default_collection": "web"}`), 0644)
+
+	run([]string{"new", "Web Task"})
+
+	env.stdout.Reset()
+	run([]string{"list", "--collection", "web"})
+	if !strings.Contains(env.stdout.String(), "Web Task") {
+		t.Errorf("config default collection should receive new tasks, got: %s", env.stdout.String())
+	}
+}
+
+func TestRunMove(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	run([]string{"init", "--collection", "api"})
+	run([]string{"new", "Movable Task"})
+	taskID := extractTaskID(env.stdout.String())
+
+	env.stdout.Reset()
+	if err := run([]string{"move", taskID, "--to", "api"}); err != nil {
+		t.Fatalf("run(move) error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), "Moved task "+taskID+" to api") {
+		t.Errorf("move should report the move, got: %s", env.stdout.String())
+	}
+
+	env.stdout.Reset()
+	run([]string{"list", "--collection", "api"})
+	if !strings.Contains(env.stdout.String(), "Movable Task") {
+		t.Error("moved task should be in the target collection")
+	}
+
+	if err := run([]string{"move", taskID}); err == nil {
+		t.Error("move without --to should return error")
+	}
+	if err := run([]string{"move", "--to", "api"}); err == nil {
+		t.Error("move without ID should return error")
+	}
+}
+
+func TestRunGlobal(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	projectA := workDir
+	run([]string{"init"})
+	run([]string{"new", "Login page"})
+	run([]string{"new", "-t", "bug", "Fix crash"})
+
+	projectB := t.TempDir()
+	workDir = projectB
+	run([]string{"init"})
+	run([]string{"new", "-d", "Support login via SSO", "Auth service"})
+	workDir = projectA
+
+	env.stdout.Reset()
+	if err := run([]string{"global", "list"}); err != nil {
+		t.Fatalf("run(global list) error = %v", err)
+	}
+	output := env.stdout.String()
+	for _, want := range []string{"Login page", "Fix crash", "Auth service"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("global list should contain %q, got: %s", want, output)
+		}
+	}
+
+	env.stdout.Reset()
+	run([]string{"global", "ready", "-t", "bug"})
+	output = env.stdout.String()
+	if !strings.Contains(output, "Fix crash") || strings.Contains(output, "Login page") {
+		t.Errorf("global ready -t bug should only show bugs, got: %s", output)
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"global", "search", "login", "--json"}); err != nil {
+		t.Fatalf("run(global search) error = %v", err)
+	}
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(env.stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("global search --json output is not valid JSON: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("global search login returned %d tasks, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if e["project"] == nil {
+			t.Errorf("global search --json entry missing project: %v", e)
+		}
+	}
+
+	if err := run([]string{"global", "search"}); err == nil {
+		t.Error("global search without a query should return error")
+	}
+	if err := run([]string{"global"}); err == nil {
+		t.Error("global without a subcommand should return error")
+	}
+}
+
+// createTasks creates tasks with the given titles and returns their IDs
+func createTasks(t *testing.T, env *testEnv, titles ...string) []string {
+	t.Helper()
+
+	ids := make([]string, 0, len(titles))
+	for _, title := range titles {
+		env.stdout.Reset()
+		if err := run([]string{"new", title}); err != nil {
+			t.Fatalf("run(new %q) error = %v", title, err)
+		}
+		ids = append(ids, extractTaskID(env.stdout.String()))
+	}
+	env.stdout.Reset()
+	return ids
+}
+
+func TestSplitArgs(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("s", "", "")
+	fs.Bool("dry-run", false, "")
+
+	got := splitArgs(fs, []string{"abc", "--dry-run", "def", "-s", "done", "ghi"})
+	want := []string{"--dry-run", "-s", "done", "--", "abc", "def", "ghi"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("splitArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestParseWhere(t *testing.T) {
+	filter, err := parseWhere("status=todo, type=bug label=ui,text=login")
+	if err != nil {
+		t.Fatalf("parseWhere() error = %v", err)
+	}
+	if filter.Status == nil || *filter.Status != model.StatusTodo {
+		t.Errorf("parseWhere() status = %v, want todo", filter.Status)
+	}
+	if filter.Type == nil || *filter.Type != model.TypeBug {
+		t.Errorf("parseWhere() type = %v, want bug", filter.Type)
+	}
+	if filter.Label == nil || *filter.Label != "ui" {
+		t.Errorf("parseWhere() label = %v, want ui", filter.Label)
+	}
+	if filter.Query == nil || *filter.Query != "login" {
+		t.Errorf("parseWhere() text = %v, want login", filter.Query)
+	}
+
+	for _, expr := range []string{"", "status", "status=nope", "color=red"} {
+		if _, err := parseWhere(expr); err == nil {
+			t.Errorf("parseWhere(%q) should return error", expr)
+		}
+	}
+}
+
+func TestRunBulkComplete(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Task A", "Task B", "Task C")
+
+	if err := run([]string{"complete", ids[0], ids[1]}); err != nil {
+		t.Fatalf("run(complete with two IDs) error = %v", err)
+	}
+	if strings.Count(env.stdout.String(), "Updated task") != 2 {
+		t.Errorf("complete should report each task, got: %s", env.stdout.String())
+	}
+
+	env.stdout.Reset()
+	run([]string{"list", "-s", "done"})
+	output := env.stdout.String()
+	if !strings.Contains(output, "Task A") || !strings.Contains(output, "Task B") || strings.Contains(output, "Task C") {
+		t.Errorf("complete should only close the given tasks, got: %s", output)
+	}
+
+	if err := run([]string{"complete", ids[2], "xxx"}); err == nil {
+		t.Error("complete with an unknown ID should return error")
+	}
+	env.stdout.Reset()
+	run([]string{"list", "-s", "done"})
+	if strings.Contains(env.stdout.String(), "Task C") {
+		t.Error("complete should not change any task when an ID is unknown")
+	}
+}
+
+func TestRunBulkWhereAndDryRun(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	run([]string{"new", "-l", "sprint", "Sprint A"})
+	run([]string{"new", "-l", "sprint", "Sprint B"})
+	run([]string{"new", "Backlog"})
+
+	env.stdout.Reset()
+	if err := run([]string{"update", "--where", "label=sprint", "-s", "blocked", "--dry-run"}); err != nil {
+		t.Fatalf("run(update --where --dry-run) error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), "Would update 2 task(s)") {
+		t.Errorf("dry run should preview the selection, got: %s", env.stdout.String())
+	}
+
+	env.stdout.Reset()
+	run([]string{"list", "-s", "blocked"})
+	if !strings.Contains(env.stdout.String(), "No tasks found") {
+		t.Error("dry run should not change tasks")
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"block", "--where", "label=sprint"}); err != nil {
+		t.Fatalf("run(block --where) error = %v", err)
+	}
+	env.stdout.Reset()
+	run([]string{"list", "-s", "blocked"})
+	output := env.stdout.String()
+	if !strings.Contains(output, "Sprint A") || !strings.Contains(output, "Sprint B") || strings.Contains(output, "Backlog") {
+		t.Errorf("block --where should block matching tasks only, got: %s", output)
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"note", "--where", "status=blocked", "Waiting", "on", "review"}); err != nil {
+		t.Fatalf("run(note --where) error = %v", err)
+	}
+	if strings.Count(env.stdout.String(), "Added note") != 2 {
+		t.Errorf("note --where should note each task, got: %s", env.stdout.String())
+	}
+
+	if err := run([]string{"update", "abc", "--where", "status=todo", "-s", "done"}); err == nil {
+		t.Error("combining IDs and --where should return error")
+	}
+}
+
+func TestRunBulkConfirmation(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+	defer func() { stdin = os.Stdin }()
+
+	run([]string{"init"})
+	os.WriteFile(workDir+"/.task/config", []byte(`{"bulk_confirm_threshold": 1}`), 0644)
+	ids := createTasks(t, env, "Task A", "Task B")
+
+	stdin = strings.NewReader("n\n")
+	if err := run([]string{"delete", ids[0], ids[1]}); err == nil {
+		t.Error("declined confirmation should return error")
+	}
+	if !strings.Contains(env.stdout.String(), "Proceed? [y/N]") {
+		t.Errorf("delete above threshold should prompt, got: %s", env.stdout.String())
+	}
+
+	env.stdout.Reset()
+	stdin = strings.NewReader("y\n")
+	if err := run([]string{"delete", ids[0], ids[1]}); err != nil {
+		t.Fatalf("confirmed delete error = %v", err)
+	}
+	if strings.Count(env.stdout.String(), "Deleted task") != 2 {
+		t.Errorf("delete should report each task, got: %s", env.stdout.String())
+	}
+
+	ids = createTasks(t, env, "Task C", "Task D")
+	if err := run([]string{"take", "-y", ids[0], ids[1]}); err != nil {
+		t.Errorf("take --yes should skip confirmation, error = %v", err)
+	}
+}
+
+func TestRunNoteMultipleIDs(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Task A", "Task B")
+
+	if err := run([]string{"note", ids[0], ids[1], "-m", "Shared context"}); err != nil {
+		t.Fatalf("run(note -m) error = %v", err)
+	}
+
+	for _, taskID := range ids {
+		env.stdout.Reset()
+		run([]string{"show", taskID})
+		if !strings.Contains(env.stdout.String(), "Shared context") {
+			t.Errorf("task %s should have the shared note", taskID)
+		}
+	}
+}
+
+func TestRunLabels(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	run([]string{"new", "-l", "frontend", "UI Task"})
+	env.stdout.Reset()
+	run([]string{"new", "-l", "front-end", "-l", "urgent", "Other UI Task"})
+	doneID := extractTaskID(env.stdout.String())
+	run([]string{"complete", doneID})
+
+	env.stdout.Reset()
+	if err := run([]string{"labels", "--json"}); err != nil {
+		t.Fatalf("run(labels --json) error = %v", err)
+	}
+	var stats []labelStat
+	if err := json.Unmarshal(env.stdout.Bytes(), &stats); err != nil {
+		t.Fatalf("labels --json output is not valid JSON: %v", err)
+	}
+	counts := make(map[string]labelStat)
+	for _, stat := range stats {
+		counts[stat.Name] = stat
+	}
+	if counts["frontend"].Open != 1 || counts["front-end"].Closed != 1 || counts["urgent"].Closed != 1 {
+		t.Errorf("labels counts = %+v", stats)
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"labels", "rename", "front-end", "frontend"}); err != nil {
+		t.Fatalf("run(labels rename) error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), "on 1 task(s)") {
+		t.Errorf("labels rename should report changed tasks, got: %s", env.stdout.String())
+	}
+
+	env.stdout.Reset()
+	run([]string{"list", "-l", "frontend"})
+	if !strings.Contains(env.stdout.String(), "Other UI Task") {
+		t.Error("renamed label should be applied to the task")
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"labels", "merge", "urgent", "frontend", "--into", "ui"}); err != nil {
+		t.Fatalf("run(labels merge) error = %v", err)
+	}
+	env.stdout.Reset()
+	run([]string{"labels"})
+	output := env.stdout.String()
+	if !strings.Contains(output, "ui") || strings.Contains(output, "urgent") || strings.Contains(output, "frontend") {
+		t.Errorf("labels merge should leave only the target label, got: %s", output)
+	}
+
+	if err := run([]string{"labels", "rename", "missing", "other"}); err == nil {
+		t.Error("renaming an unused label should return error")
+	}
+	if err := run([]string{"labels", "merge", "ui"}); err == nil {
+		t.Error("labels merge without --into should return error")
+	}
+
+	run([]string{"new", "-l", "n", "-l", "ui", "Flag-like label"})
+	env.stdout.Reset()
+	if err := run([]string{"labels", "remove", "n", "ui"}); err != nil {
+		t.Fatalf("run(labels remove) error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), `Removed label(s) "n", "ui" from 3 task(s)`) {
+		t.Errorf("labels remove output = %s", env.stdout.String())
+	}
+	env.stdout.Reset()
+	run([]string{"labels"})
+	if output := env.stdout.String(); !strings.Contains(output, "No labels found") {
+		t.Errorf("labels after remove = %s", output)
+	}
+	if err := run([]string{"labels", "remove", "missing"}); err == nil {
+		t.Error("removing an unused label should return error")
+	}
+	if err := run([]string{"labels", "remove"}); err == nil {
+		t.Error("labels remove without a label should return error")
+	}
+}
+
+func TestRunUpdateIncrementalLabels(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	run([]string{"new", "-l", "front-end", "-l", "urgent", "Task"})
+	taskID := extractTaskID(env.stdout.String())
+
+	if err := run([]string{"update", taskID, "+frontend", "-n", "-renamed-", "--", "-front-end"}); err != nil {
+		t.Fatalf("run(update +label -- -label) error = %v", err)
+	}
+
+	env.stdout.Reset()
+	run([]string{"show", taskID, "--json"})
+	var task map[string]interface{}
+	json.Unmarshal(env.stdout.Bytes(), &task)
+	labels := task["labels"].([]interface{})
+	if len(labels) != 2 || labels[0] != "urgent" || labels[1] != "frontend" {
+		t.Errorf("labels after incremental update = %v, want [urgent frontend]", labels)
+	}
+	if task["title"] != "-renamed-" {
+		t.Errorf("title = %v, flag values starting with - should not be label edits", task["title"])
+	}
+
+	// Before --, help and unknown flags are not label removals
+	run([]string{"update", taskID, "+h"})
+	if err := run([]string{"update", taskID, "-h"}); err != flag.ErrHelp {
+		t.Errorf("update -h error = %v, want flag.ErrHelp", err)
+	}
+	if err := run([]string{"update", taskID, "-stauts", "done"}); err == nil {
+		t.Error("update with an unknown flag should return error")
+	}
+	env.stdout.Reset()
+	run([]string{"show", taskID, "--json"})
+	json.Unmarshal(env.stdout.Bytes(), &task)
+	if labels := task["labels"].([]interface{}); len(labels) != 3 || labels[2] != "h" {
+		t.Errorf("labels after update -h = %v, want h kept", labels)
+	}
+}
+
+func TestRunLabelAllowlist(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	os.WriteFile(workDir+"/.task/config", []byte(`{
+  "restrict_labels": true,
+  "labels": [
+    {"name": "frontend", "description": "UI work", "color": "blue"},
+    {"name": "backend"}
+  ]
+}`), 0644)
+
+	if err := run([]string{"new", "-l", "front-end", "Task"}); err == nil {
+		t.Error("new with a label outside the allowlist should return error")
+	}
+	if err := run([]string{"new", "-l", "frontend", "Task"}); err != nil {
+		t.Fatalf("new with an allowed label error = %v", err)
+	}
+	taskID := extractTaskID(env.stdout.String())
+
+	if err := run([]string{"update", taskID, "+typo"}); err == nil {
+		t.Error("update adding a label outside the allowlist should return error")
+	}
+
+	env.stdout.Reset()
+	run([]string{"labels"})
+	output := env.stdout.String()
+	if !strings.Contains(output, "UI work") || !strings.Contains(output, "backend") {
+		t.Errorf("labels should list configured labels with descriptions, got: %s", output)
+	}
+	if !strings.Contains(output, colorBlue+"frontend") {
+		t.Errorf("labels should color configured labels, got: %q", output)
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	got := decodeKeys([]byte("j\x1b[A\x1b[1;5Cq\r\x7f\x1b\x03é"))
+	want := []string{"j", "up", "right", "q", "enter", "backspace", "esc", "ctrl+c", "é"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("decodeKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	if got := truncateText("short", 10); got != "short" {
+		t.Errorf("truncateText(short, 10) = %q", got)
+	}
+	if got := truncateText("a long title", 6); got != "a lon…" {
+		t.Errorf("truncateText(a long title, 6) = %q, want %q", got, "a lon…")
+	}
+	if got := visibleWidth(colorRed + "✨ab" + colorReset); got != 4 {
+		t.Errorf("visibleWidth() = %d, want 4", got)
+	}
+	if got := truncateVisible(colorRed+"abcdef"+colorReset, 3); visibleWidth(got) != 3 {
+		t.Errorf("truncateVisible() = %q, want 3 visible cells", got)
+	}
+}
+
+func TestRenderBoard(t *testing.T) {
+	tasks := []model.Task{
+		*model.NewTask("aaa", "First todo", model.TypeTask),
+		*model.NewTask("bbb", "A very long task title that will not fit", model.TypeBug),
+	}
+	tasks[1].SetStatus(model.StatusProgress)
+
+	lines := renderBoard(groupByStatus(tasks, boardStatuses), 100, 5, &boardCursor{Column: 1, Row: 0})
+	output := strings.Join(lines, "\n")
+
+	if !strings.Contains(output, "todo (1)") || !strings.Contains(output, "progress (1)") {
+		t.Errorf("renderBoard() should show column counts, got:\n%s", output)
+	}
+	if !strings.Contains(output, "First todo") {
+		t.Errorf("renderBoard() should show task titles, got:\n%s", output)
+	}
+	if !strings.Contains(output, "…") {
+		t.Errorf("renderBoard() should truncate long titles, got:\n%s", output)
+	}
+	if !strings.Contains(output, ansiReverse+"bbb") {
+		t.Errorf("renderBoard() should highlight the selected card, got:\n%q", output)
+	}
+	for _, line := range lines {
+		if visibleWidth(line) > 100 {
+			t.Errorf("renderBoard() line wider than 100 cells: %q", line)
+		}
+	}
+}
+
+func TestUIStateNavigationAndMoves(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Board Task")
+
+	ui := newUIState(getStore())
+	if err := ui.reload(); err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+	if sel := ui.selected(); sel == nil || sel.ID != ids[0] {
+		t.Fatalf("selected() = %v, want %s", sel, ids[0])
+	}
+
+	// Move the card to progress and check it was saved
+	ui.handleKey("L")
+	task, _ := getStore().FindByID(ids[0])
+	if task.Status != model.StatusProgress {
+		t.Errorf("status after L = %s, want progress", task.Status)
+	}
+	if ui.cursor.Column != 1 {
+		t.Errorf("cursor column after L = %d, want 1 (follows the card)", ui.cursor.Column)
+	}
+
+	// Add a note inline
+	for _, key := range []string{"n", "h", "i", "enter"} {
+		ui.handleKey(key)
+	}
+	task, _ = getStore().FindByID(ids[0])
+	if len(task.Notes) != 1 || task.Notes[0].Content != "hi" {
+		t.Errorf("notes after inline note = %v, want [hi]", task.Notes)
+	}
+
+	// Detail view shows the task and returns to the board on esc
+	ui.handleKey("enter")
+	if ui.mode != uiModeDetail {
+		t.Errorf("mode after enter = %d, want detail", ui.mode)
+	}
+	if out := strings.Join(ui.render(80, 24), "\n"); !strings.Contains(out, "Notes (1)") {
+		t.Errorf("detail view should show notes, got:\n%s", out)
+	}
+	ui.handleKey("esc")
+
+	// Filter hides tasks without the label
+	for _, key := range []string{"/", "l", "a", "b", "e", "l", "=", "x", "enter"} {
+		ui.handleKey(key)
+	}
+	if ui.filterText != "label=x" || ui.selected() != nil {
+		t.Errorf("filter = %q, selected = %v, want label=x with no tasks", ui.filterText, ui.selected())
+	}
+
+	ui.handleKey("q")
+	if !ui.quit {
+		t.Error("q should quit the board")
+	}
+}
+
+func TestUIStateReloadsOnChange(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ui := newUIState(getStore())
+	ui.reload()
+
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(workDir+"/.task/task.json", past, past)
+	ui.reload()
+	if ui.changedOnDisk() {
+		t.Error("changedOnDisk() = true right after reload")
+	}
+
+	createTasks(t, env, "External Task")
+	if !ui.changedOnDisk() {
+		t.Error("changedOnDisk() = false after the task file changed")
+	}
+	ui.reload()
+	if out := strings.Join(ui.render(120, 24), "\n"); !strings.Contains(out, "External Task") {
+		t.Errorf("board should show reloaded tasks, got:\n%s", out)
+	}
+}
+
+func TestRunBoard(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Todo Task", "Active Task", "Other Task")
+	run([]string{"take", ids[1]})
+	run([]string{"update", ids[2], "-l", "other"})
+
+	env.stdout.Reset()
+	if err := run([]string{"board", "--width", "120"}); err != nil {
+		t.Fatalf("board error = %v", err)
+	}
+	output := env.stdout.String()
+
+	for _, want := range []string{"todo (2)", "progress (1)", "blocked (0)", "Todo Task", "Active Task"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("board output should contain %q, got:\n%s", want, output)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if visibleWidth(line) > 120 {
+			t.Errorf("board line wider than 120 cells: %q", line)
+		}
+	}
+
+	// Filters match task list
+	env.stdout.Reset()
+	run([]string{"board", "--width", "120", "-l", "other"})
+	output = env.stdout.String()
+	if !strings.Contains(output, "todo (1)") || strings.Contains(output, "Todo Task") {
+		t.Errorf("board -l other should only show labelled tasks, got:\n%s", output)
+	}
+
+	// A status filter shows a single column
+	env.stdout.Reset()
+	run([]string{"board", "--width", "120", "-s", "progress"})
+	output = env.stdout.String()
+	if !strings.Contains(output, "progress (1)") || strings.Contains(output, "todo (") {
+		t.Errorf("board -s progress should only show the progress column, got:\n%s", output)
+	}
+
+	// Limit keeps the count but hides extra cards
+	run([]string{"new", "Third Todo"})
+	env.stdout.Reset()
+	run([]string{"board", "--width", "120", "--limit", "2", "-s", "todo"})
+	output = env.stdout.String()
+	if !strings.Contains(output, "todo (3)") || !strings.Contains(output, "+2 more") {
+		t.Errorf("board --limit 2 should summarise hidden tasks, got:\n%s", output)
+	}
+}
+
+func TestFormatRelative(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3 * time.Hour, "3h ago"},
+		{3 * 24 * time.Hour, "3d ago"},
+		{21 * 24 * time.Hour, "3w ago"},
+		{90 * 24 * time.Hour, "3mo ago"},
+		{800 * 24 * time.Hour, "2y ago"},
+	}
+
+	for _, tt := range tests {
+		if got := formatRelative(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("formatRelative(-%v) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+	if got := formatRelative(time.Time{}, now); got != "" {
+		t.Errorf("formatRelative(zero) = %q, want empty", got)
+	}
+}
+
+func TestRunListTable(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+	t.Setenv("COLUMNS", "60")
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "A task with a rather long title that needs to be cut short")
+	run([]string{"update", ids[0], "-l", "frontend"})
+
+	env.stdout.Reset()
+	if err := run([]string{"list", "--format", "table"}); err != nil {
+		t.Fatalf("list --format table error = %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(env.stdout.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("table should have a header and one row, got:\n%s", env.stdout.String())
+	}
+	for _, header := range []string{"ID", "STATUS", "TYPE", "TITLE", "LABELS", "UPDATED"} {
+		if !strings.Contains(lines[0], header) {
+			t.Errorf("header should contain %s, got %q", header, lines[0])
+		}
+	}
+	for _, want := range []string{ids[0], "todo", "task", "frontend", "just now", "…"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("row should contain %q, got %q", want, lines[1])
+		}
+	}
+	for _, line := range lines {
+		if visibleWidth(line) > 60 {
+			t.Errorf("table line wider than 60 cells: %q", line)
+		}
+	}
+
+	// --columns implies table output with only those columns
+	env.stdout.Reset()
+	if err := run([]string{"list", "--columns", "id,created"}); err != nil {
+		t.Fatalf("list --columns error = %v", err)
+	}
+	output := env.stdout.String()
+	if !strings.Contains(output, "CREATED") || strings.Contains(output, "TITLE") {
+		t.Errorf("list --columns id,created should only show those columns, got:\n%s", output)
+	}
+
+	if err := run([]string{"list", "--columns", "id,bogus"}); err == nil {
+		t.Error("list with an unknown column should return error")
+	}
+	if err := run([]string{"list", "--format", "yaml"}); err == nil {
+		t.Error("list with an unknown format should return error")
+	}
+}
+
+func TestRunListTableConfigColumns(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	createTasks(t, env, "Configured Task")
+	os.WriteFile(workDir+"/.task/config", []byte(`{"table_columns": ["title", "notes"]}`), 0644)
+
+	env.stdout.Reset()
+	run([]string{"list", "--format", "table"})
+	output := env.stdout.String()
+	if !strings.Contains(output, "NOTES") || strings.Contains(output, "STATUS") {
+		t.Errorf("table should use the configured columns, got:\n%s", output)
+	}
+}
+
+func TestRunListTemplate(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "First Task", "Second Task")
+	run([]string{"update", ids[0], "+a", "+b"})
+
+	env.stdout.Reset()
+	if err := run([]string{"list", "--template", `{{.ID}}|{{.Title}}|{{join ";" .Labels}}|{{.Collection}}`}); err != nil {
+		t.Fatalf("list --template error = %v", err)
+	}
+	output := env.stdout.String()
+	for _, want := range []string{ids[0] + "|First Task|a;b|default\n", ids[1] + "|Second Task||default\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("list --template output should contain %q, got:\n%s", want, output)
+		}
+	}
+
+	env.stdout.Reset()
+	run([]string{"list", "-l", "a", "--template", `{{upper .Title | truncate 5}} {{ago .CreatedAt}} {{date "2006" .CreatedAt}}`})
+	want := fmt.Sprintf("FIRS… just now %d\n", time.Now().Year())
+	if got := env.stdout.String(); got != want {
+		t.Errorf("list --template helpers = %q, want %q", got, want)
+	}
+
+	// The example from the help text, as a shell passes it
+	env.stdout.Reset()
+	run([]string{"list", "-l", "a", "--template", `{{.ID}}\t{{.Title}}`})
+	if got, want := env.stdout.String(), ids[0]+"\tFirst Task\n"; got != want {
+		t.Errorf("list --template with \\t = %q, want %q", got, want)
+	}
+	env.stdout.Reset()
+	run([]string{"list", "-l", "a", "--template", `{{.ID}}{{"\t"}}{{.Title}}\n\\n`})
+	if got, want := env.stdout.String(), ids[0]+"\tFirst Task\n\\n\n"; got != want {
+		t.Errorf("list --template with escapes = %q, want %q", got, want)
+	}
+
+	if err := run([]string{"list", "--template", "{{.Nope"}); err == nil {
+		t.Error("list with an invalid template should return error")
+	}
+	if err := run([]string{"list", "--template", "{{.ID}}", "--json"}); err == nil {
+		t.Error("list --template --json should return error")
+	}
+}
+
+func TestRunNamedTemplate(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Named Task")
+
+	os.MkdirAll(workDir+"/.task/templates", 0755)
+	os.WriteFile(workDir+"/.task/templates/oneline.tmpl", []byte("{{.ID}} {{.Status}} {{.Title}}\n"), 0644)
+
+	env.stdout.Reset()
+	if err := run([]string{"list", "--template", "oneline"}); err != nil {
+		t.Fatalf("list --template oneline error = %v", err)
+	}
+	if got, want := env.stdout.String(), ids[0]+" todo Named Task\n"; got != want {
+		t.Errorf("named template output = %q, want %q", got, want)
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"show", "--template", "oneline", ids[0]}); err != nil {
+		t.Fatalf("show --template error = %v", err)
+	}
+	if got, want := env.stdout.String(), ids[0]+" todo Named Task\n"; got != want {
+		t.Errorf("show --template output = %q, want %q", got, want)
+	}
+
+	if err := run([]string{"list", "--template", "missing"}); err == nil {
+		t.Error("list with a missing named template should return error")
+	}
+}
+
+func TestRunExportImportCSV(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Exported Task", "Other Task")
+	run([]string{"update", ids[0], "+frontend", "-t", "bug"})
+	run([]string{"note", ids[0], "First note"})
+	run([]string{"complete", ids[1]})
+
+	env.stdout.Reset()
+	if err := run([]string{"export", "--notes", "-s", "todo"}); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+	exported := env.stdout.String()
+	if !strings.HasPrefix(exported, "id,title,type,status,labels,description,notes,created_at,updated_at,note_created_at,note\n") {
+		t.Errorf("export should start with the header, got:\n%s", exported)
+	}
+	if !strings.Contains(exported, ids[0]+",Exported Task,bug,todo,frontend,,1,") || strings.Contains(exported, "Other Task") {
+		t.Errorf("export should contain only the filtered task, got:\n%s", exported)
+	}
+
+	// Export to a TSV file, inferring the format from the extension
+	path := workDir + "/tasks.tsv"
+	env.stdout.Reset()
+	if err := run([]string{"export", "-o", path}); err != nil {
+		t.Fatalf("export -o error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "id\ttitle\t") {
+		t.Errorf("export -o tasks.tsv should write TSV, got:\n%s", data)
+	}
+
+	// Import the CSV back as new tasks
+	defer func() { stdin = os.Stdin }()
+	stdin = strings.NewReader(exported)
+	env.stdout.Reset()
+	if err := run([]string{"import"}); err != nil {
+		t.Fatalf("import error = %v\n%s", err, env.stderr.String())
+	}
+	if !strings.Contains(env.stdout.String(), "Imported 1 task(s)") {
+		t.Errorf("import output = %q", env.stdout.String())
+	}
+
+	tasks, _ := getStore().Load()
+	if len(tasks) != 3 {
+		t.Fatalf("tasks after import = %d, want 3", len(tasks))
+	}
+	imported := tasks[2]
+	if imported.ID == ids[0] || imported.Title != "Exported Task" || imported.Type != model.TypeBug {
+		t.Errorf("imported task = %+v, want a copy with a new ID", imported)
+	}
+	if len(imported.Notes) != 1 || imported.Notes[0].Content != "First note" || !strings.HasPrefix(imported.Notes[0].ID, imported.ID+"-") {
+		t.Errorf("imported notes = %+v", imported.Notes)
+	}
+}
+
+func TestRunExportImportTodoTxt(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Buy milk", "Fix the sink")
+	run([]string{"update", ids[1], "+home", "-t", "bug"})
+	run([]string{"note", ids[1], "Keep this note"})
+
+	path := workDir + "/todo.txt"
+	if err := run([]string{"export", "-o", path}); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "Fix the sink +home type:bug id:"+ids[1]) {
+		t.Fatalf("export -o todo.txt should write todo.txt, got:\n%s", data)
+	}
+
+	// Edit the file as a todo.txt app would: complete one task, reprioritise
+	// another and add a new one
+	edited := strings.Replace(string(data), "Fix the sink", "Fix the kitchen sink", 1)
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(edited), "\n") {
+		switch {
+		case strings.HasSuffix(line, "id:"+ids[0]):
+			line = "x 2024-06-01 " + line
+		case strings.HasSuffix(line, "id:"+ids[1]):
+			line = "(A) " + line + " due:2024-07-01"
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "Water plants @garden")
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+
+	env.stdout.Reset()
+	if err := run([]string{"import", path, "--dry-run"}); err != nil {
+		t.Fatalf("import --dry-run error = %v\n%s", err, env.stderr.String())
+	}
+	if out := env.stdout.String(); !strings.Contains(out, "Would import 1 task(s)") || !strings.Contains(out, "Would update 2 task(s)") {
+		t.Errorf("import --dry-run output = %q", out)
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"import", path}); err != nil {
+		t.Fatalf("import error = %v\n%s", err, env.stderr.String())
+	}
+	if out := env.stdout.String(); !strings.Contains(out, "Imported 1 task(s)") || !strings.Contains(out, "Updated 2 existing task(s)") {
+		t.Errorf("import output = %q", out)
+	}
+
+	tasks, _ := getStore().Load()
+	if len(tasks) != 3 {
+		t.Fatalf("tasks after import = %d, want 3", len(tasks))
+	}
+	milk, _ := getStore().FindByID(ids[0])
+	if milk.Status != model.StatusDone {
+		t.Errorf("completed task status = %s, want done", milk.Status)
+	}
+	sink, _ := getStore().FindByID(ids[1])
+	if sink.Title != "Fix the kitchen sink" || sink.Type != model.TypeBug || sink.Due.Format(model.DateLayout) != "2024-07-01" {
+		t.Errorf("updated task = %+v", sink)
+	}
+	if strings.Join(sink.Labels, ",") != "priority:a,home" {
+		t.Errorf("updated labels = %v", sink.Labels)
+	}
+	if len(sink.Notes) != 1 {
+		t.Errorf("import should keep the notes of updated tasks, got %v", sink.Notes)
+	}
+
+	// Importing the same file again changes nothing
+	env.stdout.Reset()
+	run([]string{"import", path})
+	if tasks, _ := getStore().Load(); len(tasks) != 4 {
+		t.Errorf("tasks after second import = %d, want 4 (only the line without an id is added again)", len(tasks))
+	}
+	if strings.Contains(env.stdout.String(), "Updated") {
+		t.Errorf("unchanged tasks should not be updated, got %q", env.stdout.String())
+	}
+}
+
+func TestRunTodoTxtRoundTripKeepsLabels(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Plan the trip")
+	run([]string{"update", ids[0], "-l", "two words", "-l", "priority:a", "-l", "home"})
+
+	path := workDir + "/todo.txt"
+	run([]string{"export", "-o", path})
+	env.stdout.Reset()
+	if err := run([]string{"import", path}); err != nil {
+		t.Fatalf("import error = %v\n%s", err, env.stderr.String())
+	}
+	if strings.Contains(env.stdout.String(), "Updated") {
+		t.Errorf("re-importing an unedited export should change nothing, got %q", env.stdout.String())
+	}
+	task, _ := getStore().FindByID(ids[0])
+	if strings.Join(task.Labels, ",") != "two words,priority:a,home" {
+		t.Errorf("labels after round trip = %v", task.Labels)
+	}
+}
+
+func TestRunImportExportTaskwarrior(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	twExport := `[
+{"id":1,"description":"Pay rent","entry":"20240301T093000Z","status":"pending","uuid":"11111111-1111-4111-8111-111111111111","project":"home","tags":["money"],"annotations":[{"entry":"20240301T100000Z","description":"Ask about the deposit"}]},
+{"id":0,"description":"Old thing","entry":"20240101T000000Z","status":"deleted","uuid":"22222222-2222-4222-8222-222222222222"}
+]`
+	path := workDir + "/tasks.json"
+	os.WriteFile(path, []byte(twExport), 0644)
+
+	// .json could be any JSON, so it needs --format
+	if err := run([]string{"import", path}); err == nil {
+		t.Fatal("import of a .json file without --format should return error")
+	}
+	if err := run([]string{"import", "-f", "taskwarrior", path}); err != nil {
+		t.Fatalf("import error = %v\n%s", err, env.stderr.String())
+	}
+	tasks, _ := getStore().Load()
+	if len(tasks) != 2 {
+		t.Fatalf("tasks after import = %d, want 2", len(tasks))
+	}
+	rent := tasks[0]
+	if rent.Title != "Pay rent" || strings.Join(rent.Labels, ",") != "project:home,money" || rent.Status != model.StatusTodo {
+		t.Errorf("imported task = %+v", rent)
+	}
+	if len(rent.Notes) != 1 || !strings.HasPrefix(rent.Notes[0].ID, rent.ID+"-") {
+		t.Errorf("imported notes = %+v", rent.Notes)
+	}
+	if tasks[1].Status != model.StatusAbandon {
+		t.Errorf("deleted task status = %s, want abandon", tasks[1].Status)
+	}
+
+	// Importing a later export of the same tasks updates them in place and
+	// adds new annotations as notes
+	later := strings.Replace(twExport, `"status":"pending"`, `"status":"completed","end":"20240305T000000Z"`, 1)
+	later = strings.Replace(later, `"description":"Ask about the deposit"}`, `"description":"Ask about the deposit"},{"entry":"20240305T000000Z","description":"Paid"}`, 1)
+	os.WriteFile(path, []byte(later), 0644)
+	env.stdout.Reset()
+	if err := run([]string{"import", "--format", "taskwarrior", path}); err != nil {
+		t.Fatalf("second import error = %v\n%s", err, env.stderr.String())
+	}
+	if out := env.stdout.String(); !strings.Contains(out, "Imported 0 task(s)") || !strings.Contains(out, "Updated 1 existing task(s)") {
+		t.Errorf("second import output = %q", out)
+	}
+	updated, _ := getStore().FindByID(rent.ID)
+	if updated.Status != model.StatusDone || len(updated.Notes) != 2 || updated.Notes[1].Content != "Paid" {
+		t.Errorf("updated task = %+v", updated)
+	}
+
+	// Exporting keeps the UUIDs, and importing the export changes nothing
+	env.stdout.Reset()
+	if err := run([]string{"export", "--format", "taskwarrior"}); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+	exported := env.stdout.String()
+	if !strings.Contains(exported, `"uuid":"11111111-1111-4111-8111-111111111111"`) || !strings.Contains(exported, `"taskid":"`+rent.ID+`"`) {
+		t.Errorf("export = %s", exported)
+	}
+	defer func() { stdin = os.Stdin }()
+	stdin = strings.NewReader(exported)
+	env.stdout.Reset()
+	if err := run([]string{"import", "--format", "taskwarrior"}); err != nil {
+		t.Fatalf("import of export error = %v\n%s", err, env.stderr.String())
+	}
+	if out := env.stdout.String(); out != "Imported 0 task(s)\n" {
+		t.Errorf("importing an unchanged export output = %q", out)
+	}
+}
+
+func TestRunImportRowErrors(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	path := workDir + "/in.csv"
+	os.WriteFile(path, []byte("title,type\nGood,bug\nBad,epic\n"), 0644)
+
+	if err := run([]string{"import", path}); err == nil {
+		t.Error("import with invalid rows should return error")
+	}
+	if !strings.Contains(env.stderr.String(), "line 3: invalid type: epic") {
+		t.Errorf("import should report the bad row, got: %s", env.stderr.String())
+	}
+	if tasks, _ := getStore().Load(); len(tasks) != 0 {
+		t.Errorf("import with invalid rows imported %d task(s), want 0", len(tasks))
+	}
+
+	env.stdout.Reset()
+	run([]string{"import", path, "--dry-run", "--skip-invalid"})
+	if !strings.Contains(env.stdout.String(), "Would import 1 task(s)") {
+		t.Errorf("import --dry-run output = %q", env.stdout.String())
+	}
+	if tasks, _ := getStore().Load(); len(tasks) != 0 {
+		t.Error("import --dry-run should not import")
+	}
+
+	if err := run([]string{"import", path, "--skip-invalid"}); err != nil {
+		t.Fatalf("import --skip-invalid error = %v", err)
+	}
+	if tasks, _ := getStore().Load(); len(tasks) != 1 || tasks[0].Title != "Good" {
+		t.Errorf("import --skip-invalid tasks = %v, want Good", tasks)
+	}
+}
+
+func TestRunExportMarkdown(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Ship feature", "Write docs")
+	run([]string{"update", ids[0], "+release", "-d", "The big one"})
+	run([]string{"note", ids[0], "Halfway there"})
+	run([]string{"complete", ids[1]})
+
+	env.stdout.Reset()
+	if err := run([]string{"export", "--format", "markdown", "--title", "Status"}); err != nil {
+		t.Fatalf("export --format markdown error = %v", err)
+	}
+	output := env.stdout.String()
+	for _, want := range []string{
+		"# Status\n",
+		"## Todo (1)\n\n- [ ] Ship feature `" + ids[0] + "` _(task; release)_\n\n  The big one\n",
+		"Halfway there\n",
+		"## Done (1)\n\n- [x] Write docs `" + ids[1] + "`",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("markdown export should contain %q, got:\n%s", want, output)
+		}
+	}
+
+	env.stdout.Reset()
+	run([]string{"export", "-f", "md", "--group-by", "label", "--brief"})
+	output = env.stdout.String()
+	if !strings.Contains(output, "# release (1)") || !strings.Contains(output, "# Unlabelled (1)") || strings.Contains(output, "Halfway") {
+		t.Errorf("markdown export by label should section by label without notes, got:\n%s", output)
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"export", "-f", "markdown", "--id", ids[0]}); err != nil {
+		t.Fatalf("export --id error = %v", err)
+	}
+	output = env.stdout.String()
+	if !strings.HasPrefix(output, "# Ship feature\n") || !strings.Contains(output, "## Description\n\nThe big one\n") || !strings.Contains(output, "## Notes (1)") {
+		t.Errorf("single task markdown export = \n%s", output)
+	}
+
+	if err := run([]string{"export", "--id", ids[0]}); err == nil {
+		t.Error("export --id without markdown should return error")
+	}
+	if err := run([]string{"export", "-f", "markdown", "--group-by", "type"}); err == nil {
+		t.Error("export with an invalid --group-by should return error")
+	}
+	if err := run([]string{"import", "-f", "markdown"}); err == nil {
+		t.Error("import of markdown should return error")
+	}
+}
+
+func TestRunExportICS(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Ship feature", "Write docs")
+	run([]string{"update", ids[0], "+release"})
+	run([]string{"take", ids[0]})
+	run([]string{"complete", ids[1]})
+	s := getStore()
+	task, _ := s.FindByID(ids[0])
+	task.Due = time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	s.UpdateMany([]model.Task{*task})
+
+	path := workDir + "/tasks.ics"
+	if err := run([]string{"export", "-o", path, "--events"}); err != nil {
+		t.Fatalf("export -o tasks.ics error = %v", err)
+	}
+	if err := run([]string{"export", "-o", workDir + "/tasks.json"}); err == nil {
+		t.Error("export -o tasks.json without --format should return error")
+	}
+	data, _ := os.ReadFile(path)
+	output := string(data)
+	domain := git.Slug(projectName())
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:" + projectName() + "\r\n",
+		"UID:" + ids[0] + "@" + domain + "\r\n",
+		"STATUS:IN-PROCESS\r\n",
+		"CATEGORIES:release\r\n",
+		"DUE;VALUE=DATE:20240701\r\n",
+		"UID:" + ids[0] + "-due@" + domain + "\r\n",
+		"UID:" + ids[1] + "@" + domain + "\r\n",
+		"STATUS:COMPLETED\r\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("ics export should contain %q, got:\n%s", want, output)
+		}
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"export", "-f", "ics", "--title", "Release"}); err != nil {
+		t.Fatalf("export -f ics error = %v", err)
+	}
+	output = env.stdout.String()
+	if !strings.Contains(output, "X-WR-CALNAME:Release\r\n") || strings.Contains(output, "BEGIN:VEVENT") {
+		t.Errorf("ics export with --title and no --events = \n%s", output)
+	}
+
+	if err := run([]string{"import", "-f", "ics"}); err == nil {
+		t.Error("import of ics should return error")
+	}
+}
+
+func TestRunSite(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Site Task", "Hidden Task")
+	run([]string{"update", ids[0], "+docs"})
+
+	env.stdout.Reset()
+	if err := run([]string{"site", "--out", "public", "-l", "docs"}); err != nil {
+		t.Fatalf("site error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), "for 1 task(s) in public") {
+		t.Errorf("site output = %q", env.stdout.String())
+	}
+
+	index, err := os.ReadFile(workDir + "/public/index.html")
+	if err != nil {
+		t.Fatalf("site should write index.html: %v", err)
+	}
+	if !strings.Contains(string(index), "Site Task") || strings.Contains(string(index), "Hidden Task") {
+		t.Error("site index should only include filtered tasks")
+	}
+	for _, name := range []string{"tasks/" + ids[0] + ".html", "labels/docs.html", "tasks.json"} {
+		if _, err := os.Stat(workDir + "/public/" + name); err != nil {
+			t.Errorf("site should write %s: %v", name, err)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	if err := run([]string{"serve"}); err == nil {
+		t.Error("serve should fail before init")
+	}
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Served Task")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, listener, server.New(getStore(), projectConfig))
+	}()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/api/tasks/" + ids[0])
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "Served Task") {
+		t.Errorf("GET task = %d %s", resp.StatusCode, body)
+	}
+
+	// An open event stream must not hold up the shutdown
+	events, err := http.Get("http://" + listener.Addr().String() + "/api/events")
+	if err != nil {
+		t.Fatalf("GET /api/events error = %v", err)
+	}
+	defer events.Body.Close()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serve should shut down cleanly, got %v", err)
+		}
+	case <-time.After(shutdownTimeout / 2):
+		t.Fatal("serve did not shut down with an event stream open")
+	}
+	if !strings.Contains(env.stdout.String(), "Listening on http://127.0.0.1:") {
+		t.Errorf("serve output = %q", env.stdout.String())
+	}
+}
+
+func TestRunMCP(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Agent Task")
+
+	defer func() { stdin = os.Stdin }()
+	stdin = strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"claim_next","params":{}}` + "\n")
+
+	env.stdout.Reset()
+	if err := run([]string{"mcp"}); err != nil {
+		t.Fatalf("mcp error = %v", err)
+	}
+
+	var resp struct {
+		Result struct {
+			Task model.Task `json:"task"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(env.stdout.Bytes(), &resp); err != nil {
+		t.Fatalf("mcp output %q is not JSON: %v", env.stdout.String(), err)
+	}
+	if resp.Result.Task.ID != ids[0] || resp.Result.Task.Status != model.StatusProgress {
+		t.Errorf("claim_next = %+v", resp.Result.Task)
+	}
+}
+
+func TestRunMCPSendsWebhooks(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	received := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-Task-Event")
+	}))
+	defer ts.Close()
+
+	run([]string{"init"})
+	os.WriteFile(workDir+"/.task/config", []byte(`{"webhooks": [{"url": "`+ts.URL+`"}]}`), 0644)
+
+	// Keep the session open until the webhook arrives, as an agent would
+	requests, send := io.Pipe()
+	defer func() { stdin = os.Stdin }()
+	stdin = requests
+	done := make(chan error, 1)
+	go func() { done <- run([]string{"mcp"}) }()
+
+	fmt.Fprintln(send, `{"jsonrpc":"2.0","id":1,"method":"create_task","params":{"title":"Agent Task"}}`)
+	select {
+	case event := <-received:
+		if event != "create" {
+			t.Errorf("webhook event = %q, want create", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("mcp did not send the webhook while the session was open")
+	}
+
+	send.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("mcp error = %v", err)
+	}
+}
+
+func TestPrintEvent(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	task := model.NewTask("abc", "Watched Task", model.TypeTask)
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.Local)
+	events := []watch.Event{
+		{Type: watch.EventUpdated, ID: "abc", Time: at, Fields: []string{"title", "labels"}, Task: *task},
+		{Type: watch.EventStatusChanged, ID: "abc", Time: at, From: model.StatusTodo, To: model.StatusDone, Task: *task},
+		{Type: watch.EventNoteAdded, ID: "abc", Time: at, Note: &model.Note{Content: "First line\nsecond"}, Task: *task},
+	}
+	for _, e := range events {
+		if err := printEvent(e, false); err != nil {
+			t.Fatalf("printEvent() error = %v", err)
+		}
+	}
+
+	out := env.stdout.String()
+	for _, want := range []string{"12:30:00", "updated", "(title, labels)", "status_changed", "todo", "done", "note_added", "First line"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "second") {
+		t.Error("note events should only show the first line")
+	}
+
+	env.stdout.Reset()
+	printEvent(events[1], true)
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(env.stdout.Bytes(), &decoded); err != nil {
+		t.Fatalf("--json output is not JSON: %v", err)
+	}
+	if decoded["type"] != "status_changed" || decoded["from"] != "todo" || decoded["to"] != "done" {
+		t.Errorf("JSON event = %v", decoded)
+	}
+}
+
+func TestRunWatchErrors(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	if err := run([]string{"watch"}); err == nil {
+		t.Error("watch should fail before init")
+	}
+	run([]string{"init"})
+	if err := run([]string{"watch", "--interval", "0s"}); err == nil {
+		t.Error("watch should reject a zero interval")
+	}
+	if err := run([]string{"watch", "-t", "epic"}); err == nil {
+		t.Error("watch should reject an invalid type")
+	}
+}
+
+func TestHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need a POSIX shell")
+	}
+	env := setupTestEnv(t)
+	defer env.cleanup()
+	t.Setenv("TASK_HOOK", "")
+
+	run([]string{"init"})
+	ids := createTasks(t, env, "Protected Task")
+
+	hookDir := filepath.Join(workDir, ".task", "hooks")
+	os.MkdirAll(hookDir, 0755)
+	os.WriteFile(filepath.Join(hookDir, "pre-delete"), []byte("#!/bin/sh\necho \"deleting $TASK_ID is not allowed\" >&2\nexit 1\n"), 0755)
+	os.WriteFile(filepath.Join(hookDir, "post-status-change"), []byte("#!/bin/sh\necho \"$TASK_ID: $TASK_OLD_STATUS -> $TASK_NEW_STATUS\"\n"), 0755)
+
+	env.stderr.Reset()
+	if err := run([]string{"delete", ids[0]}); err == nil {
+		t.Fatal("delete should fail when the pre-delete hook rejects it")
+	}
+	if !strings.Contains(env.stderr.String(), "pre-delete hook failed: deleting "+ids[0]+" is not allowed") {
+		t.Errorf("stderr = %q", env.stderr.String())
+	}
+
+	env.stderr.Reset()
+	if err := run([]string{"take", ids[0]}); err != nil {
+		t.Fatalf("take error = %v", err)
+	}
+	if !strings.Contains(env.stderr.String(), ids[0]+": todo -> progress") {
+		t.Errorf("post-status-change output = %q", env.stderr.String())
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	var mu sync.Mutex
+	fail := false
+	var events []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		events = append(events, r.Header.Get("X-Task-Event"))
+	}))
+	defer ts.Close()
+	setFail := func(v bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		fail = v
+	}
+
+	run([]string{"init"})
+	os.WriteFile(workDir+"/.task/config", []byte(`{"webhooks": [{"url": "`+ts.URL+`", "secret": "s"}]}`), 0644)
+
+	ids := createTasks(t, env, "Webhook Task")
+	if strings.Join(events, ",") != "create" {
+		t.Errorf("events after new = %v", events)
+	}
+
+	setFail(true)
+	env.stderr.Reset()
+	run([]string{"take", ids[0]})
+	if !strings.Contains(env.stderr.String(), "1 webhook delivery(s) failed") {
+		t.Errorf("failed delivery should warn, got %q", env.stderr.String())
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"webhooks"}); err != nil {
+		t.Fatalf("webhooks error = %v", err)
+	}
+	out := env.stdout.String()
+	if !strings.Contains(out, ts.URL) || !strings.Contains(out, "signed") || !strings.Contains(out, "1 pending delivery(s)") || !strings.Contains(out, "500 Internal Server Error") {
+		t.Errorf("webhooks output = %q", out)
+	}
+
+	setFail(false)
+	env.stdout.Reset()
+	if err := run([]string{"webhooks", "flush"}); err != nil {
+		t.Fatalf("webhooks flush error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), "Delivered 1, failed 0") {
+		t.Errorf("flush output = %q", env.stdout.String())
+	}
+	if strings.Join(events, ",") != "create,update" {
+		t.Errorf("events after flush = %v", events)
+	}
+	if _, err := os.Stat(workDir + "/.task/webhooks.outbox"); !os.IsNotExist(err) {
+		t.Error("the outbox should be removed once empty")
+	}
+
+	run([]string{"complete", ids[0]})
+	run([]string{"archive"})
+	run([]string{"restore", ids[0]})
+	if strings.Join(events, ",") != "create,update,update,archive,restore" {
+		t.Errorf("events after archive and restore = %v", events)
+	}
+}
+
+// initGitRepo makes the test working directory a git repository
+func initGitRepo(t *testing.T) {
+	t.Helper()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+	} {
+		if _, err := git.Run(workDir, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+}
+
+func TestGit(t *testing.T) {
+	if _, err := git.Run("", "--version"); err != nil {
+		t.Skip("git is not installed")
+	}
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	initGitRepo(t)
+	run([]string{"init"})
+	ids := createTasks(t, env, "Referenced", "Fixed")
+
+	// A hook the user wrote is only replaced with --force
+	hooksDir := filepath.Join(workDir, ".git", "hooks")
+	os.WriteFile(filepath.Join(hooksDir, "commit-msg"), []byte("#!/bin/sh\nexit 0\n"), 0644)
+	if err := run([]string{"git", "install-hooks"}); err == nil || !strings.Contains(env.stderr.String(), "use --force") {
+		t.Errorf("install-hooks over a foreign hook: err = %v, stderr = %q", err, env.stderr.String())
+	}
+	if err := run([]string{"git", "install-hooks", "--force"}); err != nil {
+		t.Fatalf("install-hooks --force error = %v", err)
+	}
+	if err := run([]string{"git", "install-hooks"}); err != nil {
+		t.Errorf("reinstalling our own hooks should not need --force, got %v", err)
+	}
+	for _, name := range []string{"commit-msg", "post-commit"} {
+		info, err := os.Stat(filepath.Join(hooksDir, name))
+		if err != nil || info.Mode()&0111 == 0 {
+			t.Errorf("%s hook not installed as an executable: %v", name, err)
+		}
+	}
+
+	msg := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	os.WriteFile(msg, []byte("Subject\n\nTask: "+ids[0]+"\nFixes: zzz\n# comment\n"), 0644)
+	env.stderr.Reset()
+	if err := run([]string{"git", "commit-msg", msg}); err == nil || !strings.Contains(env.stderr.String(), "task zzz not found") {
+		t.Errorf("commit-msg with an unknown task: err = %v, stderr = %q", err, env.stderr.String())
+	}
+	os.WriteFile(msg, []byte("Subject\n\nFixes: #123\nFixes: 1a2b3c4d5e6f (\"net: fix null deref\")\nRefs: https://example.com/issues/1\n"), 0644)
+	if err := run([]string{"git", "commit-msg", msg}); err != nil {
+		t.Errorf("commit-msg with issue trailers should pass, got %v", err)
+	}
+	os.WriteFile(msg, []byte("Subject\n\nTask: "+ids[0]+"\nFixes: "+ids[1]+"\n"), 0644)
+	if err := run([]string{"git", "commit-msg", msg}); err != nil {
+		t.Errorf("commit-msg with known tasks error = %v", err)
+	}
+
+	// Commit without running the installed hooks, which call the task binary
+	os.WriteFile(filepath.Join(workDir, "file"), []byte("x"), 0644)
+	git.Run(workDir, "add", "file")
+	if _, err := git.Run(workDir, "-c", "core.hooksPath=/dev/null", "commit", "-q", "-F", msg); err != nil {
+		t.Fatal(err)
+	}
+	head, _ := git.HeadCommit(workDir)
+
+	env.stdout.Reset()
+	if err := run([]string{"git", "post-commit"}); err != nil {
+		t.Fatalf("post-commit error = %v", err)
+	}
+	for _, want := range []string{"Linked commit " + head.ShortHash() + " to task " + ids[0], "Marked task " + ids[1] + " as done"} {
+		if !strings.Contains(env.stdout.String(), want) {
+			t.Errorf("post-commit output missing %q:\n%s", want, env.stdout.String())
+		}
+	}
+
+	run([]string{"git", "post-commit"})
+	s := getStore()
+	referenced, _ := s.FindByID(ids[0])
+	fixed, _ := s.FindByID(ids[1])
+	if len(referenced.Notes) != 1 || referenced.Notes[0].Content != "Commit "+head.ShortHash()+": Subject" {
+		t.Errorf("referenced task notes = %+v, want one commit note", referenced.Notes)
+	}
+	if referenced.Status != model.StatusTodo || fixed.Status != model.StatusDone {
+		t.Errorf("statuses = %s, %s, want todo, done", referenced.Status, fixed.Status)
+	}
+}
+
+func TestBranch(t *testing.T) {
+	if _, err := git.Run("", "--version"); err != nil {
+		t.Skip("git is not installed")
+	}
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	initGitRepo(t)
+	git.Run(workDir, "commit", "-q", "--allow-empty", "-m", "Initial")
+	start, _ := git.CurrentBranch(workDir)
+	run([]string{"init"})
+	ids := createTasks(t, env, "Fix the login page", "Other")
+	run([]string{"update", ids[0], "-t", "bug"})
+
+	if err := run([]string{"current"}); err == nil {
+		t.Error("current on a branch without a task should fail")
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"branch", ids[0]}); err != nil {
+		t.Fatalf("branch error = %v: %s", err, env.stderr.String())
+	}
+	want := "bug/" + ids[0] + "-fix-the-login-page"
+	if branch, _ := git.CurrentBranch(workDir); branch != want {
+		t.Errorf("checked out branch = %q, want %q", branch, want)
+	}
+	task, _ := getStore().FindByID(ids[0])
+	if task.Branch != want {
+		t.Errorf("recorded branch = %q, want %q", task.Branch, want)
+	}
+
+	env.stdout.Reset()
+	run([]string{"current", "--id"})
+	if strings.TrimSpace(env.stdout.String()) != ids[0] {
+		t.Errorf("current --id = %q, want %s", env.stdout.String(), ids[0])
+	}
+
+	// The ID is omitted from note and the status aliases
+	if err := run([]string{"note", "Working on the form"}); err != nil {
+		t.Fatalf("note without ID error = %v", err)
+	}
+	if err := run([]string{"complete"}); err != nil {
+		t.Fatalf("complete without ID error = %v", err)
+	}
+	task, _ = getStore().FindByID(ids[0])
+	if len(task.Notes) != 1 || task.Notes[0].Content != "Working on the form" || task.Status != model.StatusDone {
+		t.Errorf("task after note and complete = %+v", task)
+	}
+
+	// With content piped in, a lone argument is a task ID even when it is
+	// mistyped
+	stdin = strings.NewReader("Long piped note\n")
+	err := run([]string{"note", "zzz"})
+	stdin = os.Stdin
+	if err == nil {
+		t.Error("note with piped content and an unknown ID should return error")
+	}
+	if task, _ = getStore().FindByID(ids[0]); len(task.Notes) != 1 {
+		t.Errorf("piped note went to the branch task: %+v", task.Notes)
+	}
+
+	// A note naming another task still goes to that task
+	run([]string{"note", ids[1], "Elsewhere"})
+	if other, _ := getStore().FindByID(ids[1]); len(other.Notes) != 1 {
+		t.Errorf("note with an ID went to the wrong task")
+	}
+
+	// Branches named by the pattern are recognised without being recorded
+	git.Run(workDir, "checkout", "-q", start)
+	git.Run(workDir, "checkout", "-q", "-b", "task/"+ids[1]+"-by-hand")
+	env.stdout.Reset()
+	run([]string{"current", "--id"})
+	if strings.TrimSpace(env.stdout.String()) != ids[1] {
+		t.Errorf("current --id on a hand-made branch = %q, want %s", env.stdout.String(), ids[1])
+	}
+
+	// Running branch again switches back to the recorded branch
+	run([]string{"update", ids[0], "-n", "Renamed"})
+	run([]string{"branch", ids[0]})
+	if branch, _ := git.CurrentBranch(workDir); branch != want {
+		t.Errorf("branch again checked out %q, want %q", branch, want)
+	}
+}
+
+func TestChangelog(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	run([]string{"new", "Dark mode", "-t", "feature"})
+	run([]string{"new", "Crash on save", "-t", "bug"})
+	run([]string{"new", "Tidy the docs"})
+	run([]string{"new", "Not done yet", "-t", "feature"})
+	ids := make(map[string]string)
+	tasks, _ := getStore().Load()
+	for _, task := range tasks {
+		ids[task.Title] = task.ID
+	}
+	run([]string{"complete", ids["Dark mode"], ids["Crash on save"], ids["Tidy the docs"]})
+	run([]string{"archive"})
+
+	env.stdout.Reset()
+	if err := run([]string{"changelog"}); err != nil {
+		t.Fatalf("changelog error = %v", err)
+	}
+	want := "## [Unreleased]\n\n### Features\n\n- Dark mode (" + ids["Dark mode"] + ")\n\n### Bug fixes\n\n- Crash on save (" +
+		ids["Crash on save"] + ")\n\n### Other\n\n- Tidy the docs (" + ids["Tidy the docs"] + ")\n"
+	if env.stdout.String() != want {
+		t.Errorf("changelog output =\n%s\nwant\n%s", env.stdout.String(), want)
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	env.stdout.Reset()
+	run([]string{"changelog", "--since", tomorrow})
+	if env.stdout.String() != "## [Unreleased]\n" {
+		t.Errorf("changelog --since tomorrow = %q", env.stdout.String())
+	}
+	today := time.Now().Format("2006-01-02")
+	env.stdout.Reset()
+	run([]string{"changelog", "--since", "2000-01-01", "--until", today, "--template", `{{.Date.Format "2006-01-02"}}:{{range .Sections}} {{len .Tasks}}{{end}}`})
+	if env.stdout.String() != today+": 1 1 1" {
+		t.Errorf("changelog --until today = %q, want the tasks completed today", env.stdout.String())
+	}
+	if err := run([]string{"changelog", "--since", "not-a-tag"}); err == nil {
+		t.Error("changelog with an unknown --since should fail")
+	}
+
+	env.stdout.Reset()
+	run([]string{"changelog", "--template", `{{range .Sections}}{{.Title}}:{{range .Tasks}} {{.Title}}{{end}};{{end}}`})
+	if env.stdout.String() != "Features: Dark mode;Bug fixes: Crash on save;Other: Tidy the docs;" {
+		t.Errorf("changelog --template = %q", env.stdout.String())
+	}
+
+	if err := run([]string{"changelog", "--version", "1.0.0", "--write"}); err != nil {
+		t.Fatalf("changelog --write error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(workDir, "CHANGELOG.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	heading := "## [1.0.0] - " + time.Now().Format("2006-01-02")
+	if !strings.HasPrefix(string(data), "# Changelog\n") || !strings.Contains(string(data), heading+"\n\n### Features\n") {
+		t.Errorf("CHANGELOG.md =\n%s", data)
+	}
+
+	// --since defaults to the latest tag
+	if _, err := git.Run("", "--version"); err == nil {
+		initGitRepo(t)
+		t.Setenv("GIT_COMMITTER_DATE", "2000-01-01T00:00:00Z")
+		git.Run(workDir, "commit", "-q", "--allow-empty", "-m", "Old release")
+		git.Run(workDir, "tag", "v0.1.0")
+		env.stdout.Reset()
+		run([]string{"changelog", "--since", "v0.1.0"})
+		if !strings.Contains(env.stdout.String(), "Dark mode") {
+			t.Errorf("changelog --since an old tag = %q", env.stdout.String())
+		}
+
+		t.Setenv("GIT_COMMITTER_DATE", time.Now().Add(time.Hour).Format(time.RFC3339))
+		git.Run(workDir, "commit", "-q", "--allow-empty", "-m", "New release")
+		git.Run(workDir, "tag", "v0.2.0")
+		env.stdout.Reset()
+		run([]string{"changelog"})
+		if env.stdout.String() != "## [Unreleased]\n" {
+			t.Errorf("changelog since the latest tag = %q", env.stdout.String())
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	os.MkdirAll(filepath.Join(workDir, "pkg"), 0755)
+	writeSource := func(name, src string) {
+		if err := os.WriteFile(filepath.Join(workDir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeSource("main.go", "package main\n\n// TODO: parse flags\n// FIXME: crashes on empty input\n")
+	writeSource("pkg/lib.go", "package pkg\n\n// HACK: global state\n")
+
+	env.stdout.Reset()
+	if err := run([]string{"scan", "--dry-run"}); err != nil {
+		t.Fatalf("scan --dry-run error = %v", err)
+	}
+	if !strings.Contains(env.stdout.String(), "would create 3") {
+		t.Errorf("scan --dry-run output = %q", env.stdout.String())
+	}
+	if tasks, _ := getStore().Load(); len(tasks) != 0 {
+		t.Fatal("scan --dry-run should not create tasks")
+	}
+
+	env.stdout.Reset()
+	if err := run([]string{"scan", "-l", "debt"}); err != nil {
+		t.Fatalf("scan error = %v: %s", err, env.stderr.String())
+	}
+	if !strings.Contains(env.stdout.String(), "3 created, 0 moved, 0 completed") {
+		t.Errorf("scan output = %q", env.stdout.String())
+	}
+	bySource := func() map[string]model.Task {
+		tasks, _ := getStore().Load()
+		m := make(map[string]model.Task)
+		for _, task := range tasks {
+			m[task.Title] = task
+		}
+		return m
+	}
+	tasks := bySource()
+	fixme := tasks["crashes on empty input"]
+	if fixme.Type != model.TypeBug || fixme.Source == nil || fixme.Source.String() != "main.go:4" || !fixme.HasLabel("debt") {
+		t.Errorf("FIXME task = %+v", fixme)
+	}
+
+	// Rerunning doesn't duplicate, and follows moved comments
+	writeSource("main.go", "package main\n\nimport \"fmt\"\n\n// TODO: parse flags\n// FIXME: crashes on empty input\n")
+	env.stdout.Reset()
+	run([]string{"scan"})
+	if !strings.Contains(env.stdout.String(), "0 created, 2 moved, 0 completed") {
+		t.Errorf("rescan output = %q", env.stdout.String())
+	}
+	if tasks := bySource(); len(tasks) != 3 || tasks["parse flags"].Source.Line != 5 {
+		t.Errorf("tasks after rescan = %+v", tasks)
+	}
+
+	// Scanning a path leaves tasks for other files alone
+	writeSource("main.go", "package main\n")
+	env.stdout.Reset()
+	run([]string{"scan", "pkg"})
+	if !strings.Contains(env.stdout.String(), "0 created, 0 moved, 0 completed") {
+		t.Errorf("scan pkg output = %q", env.stdout.String())
+	}
+
+	env.stdout.Reset()
+	run([]string{"scan"})
+	if !strings.Contains(env.stdout.String(), "0 created, 0 moved, 2 completed") {
+		t.Errorf("scan after removing comments = %q", env.stdout.String())
+	}
+	tasks = bySource()
+	done := tasks["parse flags"]
+	if done.Status != model.StatusDone || len(done.Notes) != 1 || done.Notes[0].Content != "Comment removed from main.go" {
+		t.Errorf("task for removed comment = %+v", done)
+	}
+	if tasks["global state"].Status != model.StatusTodo {
+		t.Error("task for a remaining comment should stay open")
+	}
+
+	if err := run([]string{"scan", "../elsewhere"}); err == nil {
+		t.Error("scan outside the project should fail")
+	}
+}
+
+func TestRemote(t *testing.T) {
+	env := setupTestEnv(t)
+	defer env.cleanup()
+
+	run([]string{"init"})
+	if err := run([]string{"remote", "push"}); err == nil || !strings.Contains(env.stderr.String(), "no remote is configured") {
+		t.Errorf("push without a remote should fail, got %v: %s", err, env.stderr.String())
+	}
+	os.WriteFile(workDir+"/.task/config", []byte(`{"remote": {"type": "file", "path": "issues.json"}}`), 0644)
+
+	ids := createTasks(t, env, "Public task", "Private task")
+	run([]string{"update", ids[0], "+public"})
+	run([]string{"note", ids[0], "Some progress"})
+
+	env.stdout.Reset()
+	if err := run([]string{"remote", "push", "-l", "public"}); err != nil {
+		t.Fatalf("push error = %v\n%s", err, env.stderr.String())
+	}
+	ref := workDir + "/issues.json#1"
+	if out := env.stdout.String(); !strings.Contains(out, "Created issue "+ref+" for task "+ids[0]) || !strings.Contains(out, "1 created") {
+		t.Errorf("push output = %q", out)
+	}
+	task, _ := getStore().FindByID(ids[0])
+	if task.Refs["file"] != ref {
+		t.Errorf("refs after push = %v, want %s", task.Refs, ref)
+	}
+	if _, err := os.Stat(workDir + "/.task/remote.sync"); err != nil {
+		t.Errorf("push should save the sync state: %v", err)
+	}
+
+	// Pushing again without changes does nothing
+	env.stdout.Reset()
+	run([]string{"remote", "push"})
+	if out := env.stdout.String(); !strings.Contains(out, "Pushed 1 task(s): 0 created, 0 updated, 1 unchanged") {
+		t.Errorf("second push output = %q", out)
+	}
+
+	// Close the issue and comment on it on the tracker
+	r := remote.NewFile(workDir + "/issues.json")
+	issue, _ := r.Get(1)
+	issue.State = remote.StateClosed
+	time.Sleep(1100 * time.Millisecond)
+	r.Update(*issue)
+	r.AddComment(1, "Shipped")
+	r.Create(remote.Issue{Title: "Reported by a user", State: remote.StateOpen, Labels: []string{"bug"}})
+
+	env.stdout.Reset()
+	if err := run([]string{"remote", "pull", "--new"}); err != nil {
+		t.Fatalf("pull error = %v\n%s", err, env.stderr.String())
+	}
+	if out := env.stdout.String(); !strings.Contains(out, "Pulled "+ref+" into task "+ids[0]) || !strings.Contains(out, "1 updated, 0 unchanged, 1 created") {
+		t.Errorf("pull output = %q", out)
+	}
+	task, _ = getStore().FindByID(ids[0])
+	if task.Status != model.StatusDone || len(task.Notes) != 2 || task.Notes[1].Content != "Shipped" {
+		t.Errorf("pulled task = %+v", task)
+	}
+	tasks, _ := getStore().Load()
+	if len(tasks) != 3 || tasks[2].Title != "Reported by a user" || tasks[2].Refs["file"] != workDir+"/issues.json#2" {
+		t.Errorf("tasks after pull --new = %+v", tasks)
+	}
+
+	// Edits on both sides are a conflict
+	time.Sleep(1100 * time.Millisecond)
+	run([]string{"update", ids[0], "--name", "Public task, renamed"})
+	issue, _ = r.Get(1)
+	issue.Title = "Renamed on the tracker"
+	r.Update(*issue)
+
+	env.stdout.Reset()
+	env.stderr.Reset()
+	if err := run([]string{"remote", "pull"}); err == nil {
+		t.Error("pull with a conflict should return an error")
+	}
+	if !strings.Contains(env.stderr.String(), "Conflict: task "+ids[0]) {
+		t.Errorf("pull should report the conflict, got %q", env.stderr.String())
+	}
+	if task, _ := getStore().FindByID(ids[0]); task.Title != "Public task, renamed" {
+		t.Error("a conflict should leave the task alone")
+	}
+	if err := run([]string{"remote", "pull", "--force"}); err != nil {
+		t.Fatalf("pull --force error = %v\n%s", err, env.stderr.String())
+	}
+	if task, _ := getStore().FindByID(ids[0]); task.Title != "Renamed on the tracker" {
+		t.Errorf("pull --force title = %q", task.Title)
+	}
+}
+
 // extractTaskID extracts a task ID from output like "Created task abc: Title"
 func extractTaskID(output string) string {
 	// Look for "task xxx:" or "task xxx " pattern
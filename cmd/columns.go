@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// boardStatuses is the left-to-right column order of the kanban board
+var boardStatuses = []model.Status{
+	model.StatusTodo,
+	model.StatusProgress,
+	model.StatusBlocked,
+	model.StatusDone,
+	model.StatusAbandon,
+}
+
+// columnSeparator is drawn between board columns
+const columnSeparator = " │ "
+
+// boardColumn is one status column of the kanban board
+type boardColumn struct {
+	Status model.Status
+	Tasks  []model.Task
+}
+
+// boardCursor marks the selected card on an interactive board
+type boardCursor struct {
+	Column int
+	Row    int
+}
+
+// groupByStatus splits tasks into one column per status, keeping their order
+func groupByStatus(tasks []model.Task, statuses []model.Status) []boardColumn {
+	columns := make([]boardColumn, len(statuses))
+	index := make(map[model.Status]int, len(statuses))
+	for i, status := range statuses {
+		columns[i] = boardColumn{Status: status, Tasks: []model.Task{}}
+		index[status] = i
+	}
+
+	for _, t := range tasks {
+		if i, ok := index[t.Status]; ok {
+			columns[i].Tasks = append(columns[i].Tasks, t)
+		}
+	}
+	return columns
+}
+
+// renderBoard lays columns out side by side within width cells, showing at
+// most height card rows per column. When cursor is non-nil the selected card
+// is highlighted and its column is scrolled to keep it visible
+func renderBoard(columns []boardColumn, width, height int, cursor *boardCursor) []string {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	sepWidth := visibleWidth(columnSeparator)
+	colWidth := (width - sepWidth*(len(columns)-1)) / len(columns)
+	if colWidth < 8 {
+		colWidth = 8
+	}
+
+	cells := make([][]string, len(columns))
+	for i, col := range columns {
+		var selected = -1
+		if cursor != nil && cursor.Column == i {
+			selected = cursor.Row
+		}
+		cells[i] = renderColumn(col, colWidth, height, selected)
+	}
+
+	rows := 0
+	for _, c := range cells {
+		if len(c) > rows {
+			rows = len(c)
+		}
+	}
+
+	lines := make([]string, rows)
+	for r := 0; r < rows; r++ {
+		parts := make([]string, len(cells))
+		for i, c := range cells {
+			cell := ""
+			if r < len(c) {
+				cell = c[r]
+			}
+			parts[i] = padRight(cell, colWidth)
+		}
+		lines[r] = strings.TrimRight(strings.Join(parts, colorGray+columnSeparator+colorReset), " ")
+	}
+	return lines
+}
+
+// renderColumn renders a column header, rule and up to height cards
+// selected is the index of the highlighted card, or -1
+func renderColumn(col boardColumn, width, height, selected int) []string {
+	header := fmt.Sprintf("%s %s (%d)", statusSymbol(col.Status), col.Status, len(col.Tasks))
+	lines := []string{
+		getStatusColor(col.Status) + ansiBold + truncateText(header, width) + colorReset,
+		colorGray + strings.Repeat("─", width) + colorReset,
+	}
+
+	if height < 1 {
+		height = 1
+	}
+
+	// Scroll so the selected card is visible, keeping a line for "+N more"
+	visible := height
+	if len(col.Tasks) > height {
+		visible = height - 1
+	}
+	offset := 0
+	if selected >= visible {
+		offset = selected - visible + 1
+	}
+
+	end := offset + visible
+	if end > len(col.Tasks) {
+		end = len(col.Tasks)
+	}
+	for i := offset; i < end; i++ {
+		lines = append(lines, renderCard(col.Tasks[i], width, i == selected))
+	}
+
+	if hidden := len(col.Tasks) - (end - offset); hidden > 0 {
+		lines = append(lines, fmt.Sprintf("%s… +%d more%s", colorGray, hidden, colorReset))
+	}
+	return lines
+}
+
+// renderCard renders a single task as one board line of width cells
+func renderCard(t model.Task, width int, selected bool) string {
+	icon := getTypeIcon(t.Type)
+	prefix := t.ID + " " + icon + " "
+	title := truncateText(t.Title, width-visibleWidth(prefix))
+
+	if selected {
+		return ansiReverse + padRight(prefix+title, width) + colorReset
+	}
+	return colorCyan + t.ID + colorReset + " " + icon + " " + title
+}
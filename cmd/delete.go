@@ -3,54 +3,66 @@ package cmd
 import (
 	"flag"
 	"fmt"
+
+	"github.com/jackreid/task/internal/model"
 )
 
 func runDelete(args []string) error {
 	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 
+	var bulk bulkFlags
+	bulk.register(fs)
+
 	fs.Usage = func() {
-		fmt.Fprintln(stderr, `Delete a task completely from the store.
+		fmt.Fprintln(stderr, `Delete one or more tasks completely from the store.
 
 Usage:
-  task delete <id>
+  task delete <id>... [flags]
+  task delete --where <filter> [flags]
+
+Flags:
+`+bulkFlagsUsage+`
 
 Examples:
-  task delete abc`)
+  task delete abc
+  task delete abc def
+  task delete --where "status=abandon,label=spike" --dry-run`)
 	}
 
-	// Reorder args to allow positional arguments before flags
-	reorderedArgs := reorderArgsForFlexibleFlags(args)
-	if err := fs.Parse(reorderedArgs); err != nil {
+	if err := fs.Parse(splitArgs(fs, args)); err != nil {
 		return err
 	}
 
-	if fs.NArg() < 1 {
+	if fs.NArg() < 1 && bulk.where == "" {
 		errorf("Error: task ID is required")
 		fs.Usage()
 		return fmt.Errorf("task ID is required")
 	}
 
-	taskID := fs.Arg(0)
-
 	s := getStore()
 
-	task, err := s.FindByID(taskID)
-	if err != nil {
-		errorf("Error: %v", err)
+	tasks, err := selectBulk(s, fs.Args(), bulk, "delete")
+	if err != nil || tasks == nil {
 		return err
 	}
 
-	if task == nil {
-		errorf("Error: task not found: %s", taskID)
-		return fmt.Errorf("task not found: %s", taskID)
-	}
-
-	if err := s.Delete(taskID); err != nil {
+	if err := s.DeleteMany(taskIDs(tasks)); err != nil {
 		errorf("Error: %v", err)
 		return err
 	}
 
-	fmt.Fprintf(stdout, "Deleted task %s\n", taskID)
+	for _, t := range tasks {
+		fmt.Fprintf(stdout, "Deleted task %s\n", t.ID)
+	}
 	return nil
 }
+
+// taskIDs returns the IDs of tasks in order
+func taskIDs(tasks []model.Task) []string {
+	ids := make([]string, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	return ids
+}
@@ -18,6 +18,7 @@ func runEdit(args []string) error {
 	var labels labelList
 	var taskType string
 	var status string
+	var due string
 
 	fs.StringVar(&name, "n", "", "New task name")
 	fs.StringVar(&name, "name", "", "New task name")
@@ -29,6 +30,7 @@ func runEdit(args []string) error {
 	fs.StringVar(&taskType, "type", "", "Task type (task, bug, feature)")
 	fs.StringVar(&status, "s", "", "Task status (todo, progress, blocked, abandon, done)")
 	fs.StringVar(&status, "status", "", "Task status (todo, progress, blocked, abandon, done)")
+	fs.StringVar(&due, "due", "", "Due date (YYYY-MM-DD, or none to clear it)")
 
 	fs.Usage = func() {
 		fmt.Fprintln(stderr, `Edit a task in $EDITOR or update directly with flags.
@@ -43,6 +45,7 @@ Flags:
   -l, --label string       Label to add (can be specified multiple times)
   -t, --type string        Task type: task, bug, feature
   -s, --status string      Task status: todo, progress, blocked, abandon, done
+  --due string             Due date (YYYY-MM-DD, or none to clear it)
 
 Examples:
   task edit abc
@@ -87,6 +90,10 @@ Examples:
 		}
 
 		if len(labels) > 0 {
+			if err := projectConfig.ValidateLabels(labels); err != nil {
+				errorf("Error: %v", err)
+				return err
+			}
 			task.SetLabels(labels)
 		}
 
@@ -108,6 +115,15 @@ Examples:
 			task.SetStatus(st)
 		}
 
+		if due != "" {
+			dueDate, err := parseDue(due)
+			if err != nil {
+				errorf("Error: %v", err)
+				return err
+			}
+			task.SetDue(dueDate)
+		}
+
 		if err := s.Update(task); err != nil {
 			errorf("Error: %v", err)
 			return err
@@ -168,6 +184,10 @@ Examples:
 	parsedLabels := task.Labels
 	if fm.HasLabels {
 		parsedLabels = normalizeLabels(fm.Labels)
+		if err := projectConfig.ValidateLabels(parsedLabels); err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
 	}
 
 	descriptionValue := normalizeDescription(body)
@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackreid/task/internal/exchange"
+	"github.com/jackreid/task/internal/git"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+// Exchange formats accepted by export and import
+const (
+	exchangeCSV         = "csv"
+	exchangeTSV         = "tsv"
+	exchangeMarkdown    = "markdown"
+	exchangeTodoTxt     = "todotxt"
+	exchangeTaskwarrior = "taskwarrior"
+	exchangeICS         = "ics"
+)
+
+// exportFormats and importFormats list the formats each command accepts
+var (
+	exportFormats = []string{exchangeCSV, exchangeTSV, exchangeMarkdown, exchangeTodoTxt, exchangeTaskwarrior, exchangeICS}
+	importFormats = []string{exchangeCSV, exchangeTSV, exchangeTodoTxt, exchangeTaskwarrior}
+)
+
+// formatAliases maps alternative --format names and file extensions to formats
+var formatAliases = map[string]string{
+	"md":  exchangeMarkdown,
+	"txt": exchangeTodoTxt,
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var format, output, groupBy, title, taskID string
+	var notes, brief, events bool
+	var filters filterFlags
+
+	fs.StringVar(&format, "format", "", "Export format: csv, tsv, markdown, todotxt, taskwarrior, ics")
+	fs.StringVar(&format, "f", "", "Export format: csv, tsv, markdown, todotxt, taskwarrior, ics")
+	fs.StringVar(&output, "o", "", "Write to a file instead of stdout")
+	fs.StringVar(&output, "output", "", "Write to a file instead of stdout")
+	fs.BoolVar(&notes, "notes", false, "Include notes as extra rows")
+	fs.StringVar(&groupBy, "group-by", exchange.GroupByStatus, "Markdown sections: status, label, none")
+	fs.StringVar(&title, "title", "", "Markdown report heading or calendar name")
+	fs.BoolVar(&brief, "brief", false, "Leave descriptions and notes out of Markdown reports")
+	fs.StringVar(&taskID, "id", "", "Export a single task as a Markdown document")
+	fs.BoolVar(&events, "events", false, "Add a calendar event on each due date to iCalendar exports")
+	filters.register(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Export tasks for use in other tools.
+
+Usage:
+  task export [flags]
+
+Flags:
+  -f, --format string  Export format: csv, tsv, markdown, todotxt,
+                       taskwarrior, ics (default: from the output file
+                       extension, or csv for stdout and files without one)
+  -o, --output string  Write to a file instead of stdout
+  --notes              CSV/TSV: include each note as an extra row after its task
+  --group-by string    Markdown: section tasks by status, label or none
+                       (default status)
+  --title string       Markdown: heading for the report; ics: calendar name
+  --brief              Markdown: leave out descriptions and notes
+  --id string          Markdown: export a single task as a full document
+  --events             ics: add an all-day event on each task's due date
+  -l, --label string   Filter by label
+  -t, --type string    Filter by type: task, bug, feature
+  -s, --status string  Filter by status: todo, progress, blocked, abandon, done
+  --archived           Export archived tasks instead of live tasks
+  --all                Export both live and archived tasks
+
+Examples:
+  task export > tasks.csv
+  task export -o tasks.tsv --notes
+  task export --format csv -s done --all
+  task export --format markdown --title "Sprint 4" -l sprint-4
+  task export --format markdown --id abc > abc.md
+  task export -o todo.txt
+  task export --format taskwarrior --all > tasks.json
+  task export -o tasks.ics --events`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	format, err := exchangeFormat(format, output, exportFormats)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	if err := exchange.ValidGroupBy(groupBy); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	if taskID != "" && format != exchangeMarkdown {
+		errorf("Error: --id is only supported with --format markdown")
+		return fmt.Errorf("--id is only supported with --format markdown")
+	}
+
+	var tasks []model.Task
+	var single *model.Task
+	if taskID != "" {
+		single, err = findTask(getStore(), taskID)
+		if err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+		tasks = []model.Task{*single}
+	} else {
+		filter, err := filters.filter()
+		if err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+
+		tasks, err = getStore().ListFiltered(filter)
+		if err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+	}
+
+	w := stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if single != nil {
+		err = exchange.WriteMarkdownTask(w, single)
+	} else {
+		ics := calendarOptions(getStore(), events)
+		if title != "" {
+			ics.Name = title
+		}
+		err = writeExport(w, format, tasks, exportOptions{
+			notes:    notes,
+			markdown: exchange.MarkdownOptions{Title: title, GroupBy: groupBy, Brief: brief},
+			ics:      ics,
+		})
+	}
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	if output != "" {
+		fmt.Fprintf(stdout, "Exported %d task(s) to %s\n", len(tasks), output)
+	}
+	return nil
+}
+
+// exportOptions holds the format specific export flags
+type exportOptions struct {
+	notes    bool
+	markdown exchange.MarkdownOptions
+	ics      exchange.ICSOptions
+}
+
+// writeExport writes tasks to w in the given format
+func writeExport(w io.Writer, format string, tasks []model.Task, opts exportOptions) error {
+	switch format {
+	case exchangeCSV:
+		return exchange.WriteCSV(w, tasks, exchange.CSVOptions{Notes: opts.notes})
+	case exchangeTSV:
+		return exchange.WriteCSV(w, tasks, exchange.CSVOptions{Comma: '\t', Notes: opts.notes})
+	case exchangeMarkdown:
+		return exchange.WriteMarkdown(w, tasks, opts.markdown)
+	case exchangeTodoTxt:
+		return exchange.WriteTodoTxt(w, tasks)
+	case exchangeTaskwarrior:
+		return exchange.WriteTaskwarrior(w, tasks)
+	case exchangeICS:
+		return exchange.WriteICS(w, tasks, opts.ics)
+	default:
+		return fmt.Errorf("invalid format: %s", format)
+	}
+}
+
+// calendarOptions names iCalendar output after the project and collection,
+// which also keep task UIDs unique across the calendars a user subscribes to
+func calendarOptions(s *store.Store, events bool) exchange.ICSOptions {
+	name := projectName()
+	domain := git.Slug(name)
+	if c := s.Collection(); c != "" && c != store.DefaultCollection {
+		name += " (" + c + ")"
+		domain = git.Slug(c) + "." + domain
+	}
+	return exchange.ICSOptions{Name: name, Domain: domain, Events: events}
+}
+
+// exchangeFormat resolves the --format flag against the allowed formats,
+// falling back to the file extension of path, and to csv when there is no
+// extension. An extension that names no format is an error rather than a
+// guess, since .json could mean any JSON
+func exchangeFormat(format, path string, allowed []string) (string, error) {
+	explicit := format != ""
+	if !explicit {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if format == "" {
+			return exchangeCSV, nil
+		}
+	}
+	if alias, ok := formatAliases[format]; ok {
+		format = alias
+	}
+
+	for _, f := range allowed {
+		if f == format {
+			return format, nil
+		}
+	}
+	if !explicit {
+		return "", fmt.Errorf("unknown format for %s, set one with --format (%s)", filepath.Base(path), strings.Join(allowed, ", "))
+	}
+	return "", fmt.Errorf("invalid format: %s (must be %s)", format, strings.Join(allowed, ", "))
+}
+
+// findTask looks a task up by ID in the live tasks, then the archive
+func findTask(s *store.Store, taskID string) (*model.Task, error) {
+	task, err := s.FindByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		task, err = s.FindArchivedByID(taskID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	return task, nil
+}
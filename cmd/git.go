@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackreid/task/internal/git"
+	"github.com/jackreid/task/internal/id"
+	"github.com/jackreid/task/internal/model"
+)
+
+// gitHookMarker identifies the git hooks written by install-hooks so they can
+// be replaced without --force
+const gitHookMarker = "# Installed by 'task git install-hooks'"
+
+// gitHooks are the scripts install-hooks writes into the git hooks directory.
+// They do nothing when task is not on the PATH, so clones without it can
+// still commit
+var gitHooks = []struct {
+	name   string
+	script string
+}{
+	{"commit-msg", `exec task git commit-msg "$1"`},
+	{"post-commit", `exec task git post-commit`},
+}
+
+func runGit(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "install-hooks":
+			return runGitInstallHooks(args[1:])
+		case "commit-msg":
+			return runGitCommitMsg(args[1:])
+		case "post-commit":
+			return runGitPostCommit(args[1:])
+		}
+	}
+
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		printGitUsage()
+		if len(args) == 0 {
+			return fmt.Errorf("git subcommand is required")
+		}
+		return nil
+	}
+	errorf("Error: unknown git subcommand: %s", args[0])
+	printGitUsage()
+	return fmt.Errorf("unknown git subcommand: %s", args[0])
+}
+
+// printGitUsage prints the help for task git
+func printGitUsage() {
+	fmt.Fprintln(stderr, `Link commits to tasks.
+
+Commits name tasks with trailers in the last paragraph of their message:
+
+  Task: abc        Add a note about the commit to task abc
+  Fixes: abc       Add a note and mark task abc as done
+
+Other trailers are left for other tools, and a value is only read up to
+the first word that isn't a task ID, such as "#123" or a commit hash.
+
+Usage:
+  task git install-hooks [--force]
+  task git commit-msg <file>
+  task git post-commit
+
+Subcommands:
+  install-hooks  Install git hooks that run commit-msg and post-commit
+  commit-msg     Check that the tasks a commit message names exist
+  post-commit    Note the last commit on the tasks it names`)
+}
+
+func runGitInstallHooks(args []string) error {
+	fs := flag.NewFlagSet("git install-hooks", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var force bool
+	fs.BoolVar(&force, "f", false, "Replace existing hooks")
+	fs.BoolVar(&force, "force", false, "Replace existing hooks")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Install the commit-msg and post-commit git hooks.
+
+The commit-msg hook rejects commits whose trailers name tasks that don't
+exist. The post-commit hook adds a note with the commit hash and subject to
+every task the commit names, and marks Fixes tasks as done.
+
+Usage:
+  task git install-hooks [--force]
+
+Flags:
+  -f, --force  Replace existing hooks that were not installed by task`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	dir, err := git.HooksDir(workDir)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	for _, hook := range gitHooks {
+		path := filepath.Join(dir, hook.name)
+		if existing, err := os.ReadFile(path); err == nil && !force && !strings.Contains(string(existing), gitHookMarker) {
+			err := fmt.Errorf("%s already exists, use --force to replace it", path)
+			errorf("Error: %v", err)
+			return err
+		}
+
+		script := "#!/bin/sh\n" + gitHookMarker + "\ncommand -v task >/dev/null 2>&1 || exit 0\n" + hook.script + "\n"
+		if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+		// WriteFile keeps the mode of a file it overwrites
+		if err := os.Chmod(path, 0755); err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+		fmt.Fprintf(stdout, "Installed %s hook at %s\n", hook.name, path)
+	}
+	return nil
+}
+
+func runGitCommitMsg(args []string) error {
+	fs := flag.NewFlagSet("git commit-msg", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Check that the tasks named by a commit message's trailers exist.
+Run by the commit-msg git hook.
+
+Usage:
+  task git commit-msg <file>`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		errorf("Error: commit message file is required")
+		fs.Usage()
+		return fmt.Errorf("commit message file is required")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	s := getStore()
+	refs := git.References(string(data))
+	if len(refs) == 0 || !s.IsInitialized() {
+		return nil
+	}
+
+	var problems []string
+	for _, ref := range refs {
+		task, err := s.FindByID(ref.ID)
+		if err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+		if task != nil {
+			continue
+		}
+		if archived, _ := s.FindArchivedByID(ref.ID); archived != nil {
+			problems = append(problems, fmt.Sprintf("task %s is archived", ref.ID))
+		} else {
+			problems = append(problems, fmt.Sprintf("task %s not found", ref.ID))
+		}
+	}
+	if len(problems) > 0 {
+		err := errors.New(strings.Join(problems, "; "))
+		errorf("Error: %v (commit aborted)", err)
+		return err
+	}
+	return nil
+}
+
+func runGitPostCommit(args []string) error {
+	fs := flag.NewFlagSet("git post-commit", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Add a note about the last commit to the tasks its trailers name, and mark
+Fixes tasks as done. Run by the post-commit git hook.
+
+Usage:
+  task git post-commit`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s := getStore()
+	if !s.IsInitialized() {
+		return nil
+	}
+
+	commit, err := git.HeadCommit(workDir)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	refs := git.References(commit.Message)
+	if len(refs) == 0 {
+		return nil
+	}
+
+	note := fmt.Sprintf("Commit %s: %s", commit.ShortHash(), commit.Subject)
+	var updated []model.Task
+	var messages []string
+	for _, ref := range refs {
+		task, err := s.FindByID(ref.ID)
+		if err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+		if task == nil {
+			errorf("Warning: task %s not found", ref.ID)
+			continue
+		}
+
+		changed := false
+		if !hasNote(task, note) {
+			noteID, err := id.GenerateNoteID(task.ID)
+			if err != nil {
+				errorf("Error: generating note ID: %v", err)
+				return err
+			}
+			task.AddNote(noteID, note)
+			changed = true
+			messages = append(messages, fmt.Sprintf("Linked commit %s to task %s", commit.ShortHash(), task.ID))
+		}
+		if ref.Closes && task.Status != model.StatusDone {
+			task.SetStatus(model.StatusDone)
+			changed = true
+			messages = append(messages, fmt.Sprintf("Marked task %s as done", task.ID))
+		}
+		if changed {
+			updated = append(updated, *task)
+		}
+	}
+
+	if len(updated) == 0 {
+		return nil
+	}
+	if err := s.UpdateMany(updated); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	for _, msg := range messages {
+		fmt.Fprintln(stdout, msg)
+	}
+	return nil
+}
+
+// hasNote reports whether a task already has a note with the content, so
+// running post-commit twice for a commit doesn't add it twice
+func hasNote(task *model.Task, content string) bool {
+	for _, n := range task.Notes {
+		if n.Content == content {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/jackreid/task/internal/config"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/registry"
+	"github.com/jackreid/task/internal/store"
+)
+
+func runGlobal(args []string) error {
+	usage := func() {
+		fmt.Fprintln(stderr, `Work with tasks across every registered project.
+
+Projects are registered by 'task init' in $XDG_CONFIG_HOME/task/projects.json
+(~/.config/task/projects.json when XDG_CONFIG_HOME is unset).
+
+Usage:
+  task global list [flags]
+  task global ready [flags]
+  task global search <query> [flags]
+
+Flags:
+  --json              Output as JSON
+  -l, --label string  Filter by label
+  -t, --type string   Filter by type: task, bug, feature
+  -s, --status string Filter by status: todo, progress, blocked, abandon, done
+  --archived          List archived tasks instead of live tasks
+  --all               List both live and archived tasks
+
+Examples:
+  task global list -s progress
+  task global ready -t bug
+  task global search "login"`)
+	}
+
+	if len(args) < 1 {
+		errorf("Error: subcommand is required")
+		usage()
+		return fmt.Errorf("subcommand is required")
+	}
+
+	switch args[0] {
+	case "list":
+		return runGlobalList(args[1:], false, usage)
+	case "ready":
+		return runGlobalList(append([]string{"-s", "todo"}, args[1:]...), false, usage)
+	case "search":
+		return runGlobalList(args[1:], true, usage)
+	case "-h", "--help", "help":
+		usage()
+		return nil
+	default:
+		errorf("Error: unknown global subcommand: %s", args[0])
+		usage()
+		return fmt.Errorf("unknown global subcommand: %s", args[0])
+	}
+}
+
+// runGlobalList lists matching tasks from every registered project, optionally
+// requiring a search query as the positional argument
+func runGlobalList(args []string, search bool, usage func()) error {
+	fs := flag.NewFlagSet("global", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = usage
+
+	var jsonOutput bool
+	var filters filterFlags
+
+	fs.BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	filters.register(fs)
+
+	if search {
+		args = reorderArgsForFlexibleFlags(args)
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter, err := filters.filter()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	if search {
+		query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+		if query == "" {
+			errorf("Error: search query is required")
+			usage()
+			return fmt.Errorf("search query is required")
+		}
+		filter.Query = &query
+	}
+
+	reg, err := registry.Load()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	var entries []sourcedTask
+	for _, p := range reg.Projects {
+		tasks, err := listProject(p, filter)
+		if err != nil {
+			errorf("Warning: skipping project %s: %v", p.Name, err)
+			continue
+		}
+		for _, t := range tasks {
+			entries = append(entries, sourcedTask{Source: p.Name, Task: t})
+		}
+	}
+	sortSourcedTasks(entries)
+
+	if jsonOutput {
+		return printSourcedTasksJSON(entries, "project")
+	}
+	return printSourcedTasksPretty(entries)
+}
+
+// listProject returns tasks matching filter from a registered project's default collection
+func listProject(p registry.Project, filter store.Filter) ([]model.Task, error) {
+	cfg, err := config.Load(store.New(p.Path).Dir())
+	if err != nil {
+		return nil, err
+	}
+	return store.NewCollection(p.Path, cfg.DefaultCollection).ListFiltered(filter)
+}
+
+// registerProject records the current project in the user-level registry,
+// reporting whether it wasn't registered before
+func registerProject() (bool, error) {
+	reg, err := registry.Load()
+	if err != nil {
+		return false, err
+	}
+
+	dir := workDir
+	if dir == "" {
+		dir = "."
+	}
+
+	if _, added, err := reg.Register(dir); err != nil || !added {
+		return false, err
+	}
+	return true, reg.Save()
+}
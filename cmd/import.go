@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jackreid/task/internal/exchange"
+	"github.com/jackreid/task/internal/id"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var format string
+	var dryRun, skipInvalid bool
+
+	fs.StringVar(&format, "format", "", "Import format: csv, tsv, todotxt, taskwarrior")
+	fs.StringVar(&format, "f", "", "Import format: csv, tsv, todotxt, taskwarrior")
+	fs.BoolVar(&dryRun, "dry-run", false, "Show what would be imported without importing")
+	fs.BoolVar(&skipInvalid, "skip-invalid", false, "Import valid rows even if some rows are invalid")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Import tasks from a file, or stdin when no file (or "-") is given.
+
+Every imported task gets a new ID. Header columns are matched to task fields
+by name: title (required), type, status, labels, description, created_at and
+updated_at. Rows with no title but a note column are added as notes to the
+task above them, as written by "task export --notes".
+
+todo.txt lines map (A) priorities to a priority:a label, +project and
+@context tags to labels, due: to the due date and "x" to done. A line with
+the id: of an existing task updates that task instead of adding a new one.
+
+Taskwarrior JSON, as written by its "task export", maps descriptions to
+titles, tags to labels, projects to project:x labels, priorities to
+priority:h, m or l labels and annotations to notes. Waiting tasks are
+blocked and deleted tasks abandoned. Each task keeps its Taskwarrior UUID,
+and a task whose UUID (or ID, when it was exported from here) matches an
+existing task updates it, adding any new annotations as notes.
+
+Usage:
+  task import [file] [flags]
+
+Flags:
+  -f, --format string  Import format: csv, tsv, todotxt, taskwarrior
+                       (default: from the file extension, or csv for stdin
+                       and files without one)
+  --dry-run            Show what would be imported without importing
+  --skip-invalid       Import valid rows even if some rows are invalid
+
+Examples:
+  task import tasks.csv
+  task import --format tsv < tasks.tsv
+  task import tasks.csv --dry-run
+  task import todo.txt
+  task import --format taskwarrior tasks.json`)
+	}
+
+	if err := fs.Parse(splitArgs(fs, args)); err != nil {
+		return err
+	}
+
+	path := fs.Arg(0)
+	format, err := exchangeFormat(format, path, importFormats)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	r := stdin
+	if path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	tasks, rowErrors, err := readImport(r, format)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	for _, rowErr := range rowErrors {
+		errorf("Error: %v", rowErr)
+	}
+	if len(rowErrors) > 0 && !skipInvalid {
+		errorf("No tasks imported: %d invalid row(s). Fix them or use --skip-invalid", len(rowErrors))
+		return fmt.Errorf("%d invalid row(s)", len(rowErrors))
+	}
+
+	s := getStore()
+	var updates []model.Task
+	if format == exchangeTodoTxt || format == exchangeTaskwarrior {
+		tasks, updates, err = matchExisting(s, tasks)
+		if err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+	}
+
+	if dryRun {
+		fmt.Fprintf(stdout, "Would import %d task(s):\n", len(tasks))
+		for _, t := range tasks {
+			fmt.Fprintf(stdout, "  %s %s %s\n", statusSymbol(t.Status), getTypeIcon(t.Type), t.Title)
+		}
+		if len(updates) > 0 {
+			fmt.Fprintf(stdout, "Would update %d task(s):\n", len(updates))
+			for _, t := range updates {
+				fmt.Fprintf(stdout, "  %s %s %s %s\n", t.ID, statusSymbol(t.Status), getTypeIcon(t.Type), t.Title)
+			}
+		}
+		return nil
+	}
+
+	if err := importTasks(s, tasks); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	if len(updates) > 0 {
+		for _, t := range updates {
+			if err := projectConfig.ValidateLabels(t.Labels); err != nil {
+				errorf("Error: %s: %v", t.Title, err)
+				return err
+			}
+		}
+		if err := s.UpdateMany(updates); err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+	}
+
+	fmt.Fprintf(stdout, "Imported %d task(s)\n", len(tasks))
+	if len(updates) > 0 {
+		fmt.Fprintf(stdout, "Updated %d existing task(s)\n", len(updates))
+	}
+	return nil
+}
+
+// readImport parses tasks from r in the given format
+func readImport(r io.Reader, format string) ([]model.Task, []*exchange.RowError, error) {
+	switch format {
+	case exchangeCSV:
+		return exchange.ReadCSV(r, ',')
+	case exchangeTSV:
+		return exchange.ReadCSV(r, '\t')
+	case exchangeTodoTxt:
+		return exchange.ReadTodoTxt(r)
+	case exchangeTaskwarrior:
+		return exchange.ReadTaskwarrior(r)
+	default:
+		return nil, nil, fmt.Errorf("invalid format: %s", format)
+	}
+}
+
+// importTasks gives tasks and their notes fresh IDs and adds them to the
+// store in a single save. Labels are checked against the project allowlist
+func importTasks(s *store.Store, tasks []model.Task) error {
+	for _, t := range tasks {
+		if err := projectConfig.ValidateLabels(t.Labels); err != nil {
+			return fmt.Errorf("%s: %w", t.Title, err)
+		}
+	}
+
+	existingIDs, err := s.GetExistingIDs()
+	if err != nil {
+		return err
+	}
+
+	for i := range tasks {
+		taskID, err := id.GenerateUnique(existingIDs)
+		if err != nil {
+			return fmt.Errorf("generating ID: %w", err)
+		}
+		existingIDs[taskID] = true
+		tasks[i].ID = taskID
+
+		for j := range tasks[i].Notes {
+			noteID, err := id.GenerateNoteID(taskID)
+			if err != nil {
+				return fmt.Errorf("generating note ID: %w", err)
+			}
+			tasks[i].Notes[j].ID = noteID
+		}
+	}
+
+	return s.AddMany(tasks)
+}
+
+// matchExisting splits imported tasks into new tasks and changes to the live
+// tasks they carry the ID or an external reference of, so a file exported
+// with IDs can be edited elsewhere and imported again. Tasks that match but
+// haven't changed are dropped
+func matchExisting(s *store.Store, imported []model.Task) ([]model.Task, []model.Task, error) {
+	existing, err := s.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[string]*model.Task, len(existing))
+	byRef := make(map[string]*model.Task)
+	for i := range existing {
+		byID[existing[i].ID] = &existing[i]
+		for tool, ref := range existing[i].Refs {
+			byRef[tool+"\x00"+ref] = &existing[i]
+		}
+		byRef[exchange.TaskwarriorRef+"\x00"+exchange.TaskwarriorUUID(existing[i])] = &existing[i]
+	}
+
+	var created, updated []model.Task
+	for _, in := range imported {
+		t := byID[in.ID]
+		for tool, ref := range in.Refs {
+			if t == nil {
+				t = byRef[tool+"\x00"+ref]
+			}
+		}
+		if in.ID == "" && len(in.Refs) == 0 || t == nil {
+			created = append(created, in)
+			continue
+		}
+		changed, err := mergeImported(t, in)
+		if err != nil {
+			return nil, nil, err
+		}
+		if changed {
+			updated = append(updated, *t)
+		}
+	}
+	return created, updated, nil
+}
+
+// importedLabels returns the labels an import gives a task. An imported
+// label that is an existing label as written to a format that can't hold
+// spaces keeps the existing label, so re-importing an export renames nothing
+func importedLabels(existing, imported []string) []string {
+	kept := make(map[string]bool, len(existing))
+	for _, e := range existing {
+		kept[e] = true
+	}
+	labels := make([]string, 0, len(imported))
+	for _, label := range imported {
+		if !kept[label] {
+			for _, e := range existing {
+				if exchange.TagLabel(e) == label {
+					label = e
+					break
+				}
+			}
+		}
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+// sameLabelSet reports whether a and b hold the same labels in any order
+func sameLabelSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int, len(a))
+	for _, label := range a {
+		counts[label]++
+	}
+	for _, label := range b {
+		if counts[label] == 0 {
+			return false
+		}
+		counts[label]--
+	}
+	return true
+}
+
+// mergeImported copies the fields an import format carries onto an existing
+// task. A description is only replaced when the import has one, and notes
+// are added rather than replaced. Returns whether anything changed
+func mergeImported(t *model.Task, in model.Task) (bool, error) {
+	changed := false
+	if t.Title != in.Title {
+		t.SetTitle(in.Title)
+		changed = true
+	}
+	if in.Description != nil && (t.Description == nil || *t.Description != *in.Description) {
+		t.SetDescription(*in.Description)
+		changed = true
+	}
+	if t.Type != in.Type {
+		t.SetType(in.Type)
+		changed = true
+	}
+	if t.Status != in.Status {
+		t.SetStatus(in.Status)
+		changed = true
+	}
+	if labels := importedLabels(t.Labels, in.Labels); !sameLabelSet(t.Labels, labels) {
+		t.SetLabels(labels)
+		changed = true
+	}
+	if !t.Due.Equal(in.Due) {
+		t.SetDue(in.Due)
+		changed = true
+	}
+	for tool, ref := range in.Refs {
+		current := t.Refs[tool]
+		if tool == exchange.TaskwarriorRef {
+			// Tasks exported without a UUID were given one from their ID
+			current = exchange.TaskwarriorUUID(*t)
+		}
+		if current != ref {
+			t.SetRef(tool, ref)
+			changed = true
+		}
+	}
+	for _, note := range in.Notes {
+		if hasNote(t, note.Content) {
+			continue
+		}
+		noteID, err := id.GenerateNoteID(t.ID)
+		if err != nil {
+			return false, fmt.Errorf("generating note ID: %w", err)
+		}
+		note.ID = noteID
+		t.Notes = append(t.Notes, note)
+		changed = true
+	}
+	return changed, nil
+}
@@ -3,6 +3,8 @@ package cmd
 import (
 	"flag"
 	"fmt"
+
+	"github.com/jackreid/task/internal/store"
 )
 
 func runInit(args []string) error {
@@ -13,8 +15,12 @@ func runInit(args []string) error {
 
 Usage:
   task init
+  task init --collection <name>
 
-This creates a .task/ directory and an empty task.json file.`)
+This creates a .task/ directory and an empty task.json file. With
+--collection, an empty .task/<name>.jsonl collection is created instead.
+The project is also registered for 'task global'; running init again in a
+project that isn't registered yet registers it.`)
 	}
 
 	if err := fs.Parse(args); err != nil {
@@ -23,10 +29,28 @@ This creates a .task/ directory and an empty task.json file.`)
 
 	s := getStore()
 	if err := s.Init(); err != nil {
+		// Projects initialized before registration existed can still be
+		// registered
+		if s.IsInitialized() {
+			if added, regErr := registerProject(); regErr == nil && added {
+				fmt.Fprintln(stdout, "Registered existing project for 'task global'")
+				return nil
+			}
+		}
 		errorf("Error: %v", err)
 		return err
 	}
 
+	// Registration is best-effort so init still works without a writable config dir
+	if _, err := registerProject(); err != nil {
+		errorf("Warning: could not register project for 'task global': %v", err)
+	}
+
+	if s.Collection() != store.DefaultCollection {
+		fmt.Fprintf(stdout, "Initialized collection %s in .task/\n", s.Collection())
+		return nil
+	}
+
 	fmt.Fprintln(stdout, "Initialized task management in .task/")
 	return nil
 }
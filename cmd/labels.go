@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackreid/task/internal/store"
+)
+
+// labelStat holds usage counts for a label
+type labelStat struct {
+	Name        string `json:"name"`
+	Open        int    `json:"open"`
+	Closed      int    `json:"closed"`
+	Description string `json:"description,omitempty"`
+	Color       string `json:"color,omitempty"`
+}
+
+func runLabels(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "rename":
+			return runLabelsRename(args[1:])
+		case "merge":
+			return runLabelsMerge(args[1:])
+		case "remove":
+			return runLabelsRemove(args[1:])
+		}
+	}
+	return runLabelsList(args)
+}
+
+func runLabelsList(args []string) error {
+	fs := flag.NewFlagSet("labels", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var jsonOutput bool
+	var all bool
+
+	fs.BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	fs.BoolVar(&all, "all", false, "Include archived tasks in the counts")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `List labels with open and closed task counts, or manage labels.
+
+Usage:
+  task labels [flags]
+  task labels rename <old> <new>
+  task labels merge <label>... --into <label>
+  task labels remove <label>...
+
+Labels defined in .task/config are listed even when unused, with their
+descriptions. Rename, merge and remove also update archived tasks.
+
+Flags:
+  --json  Output as JSON
+  --all   Include archived tasks in the counts
+
+Examples:
+  task labels
+  task labels rename front-end frontend
+  task labels merge ui frontend-ui --into frontend
+  task labels remove wontfix`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		errorf("Error: unknown labels subcommand: %s", fs.Arg(0))
+		fs.Usage()
+		return fmt.Errorf("unknown labels subcommand: %s", fs.Arg(0))
+	}
+
+	filter := store.Filter{}
+	if all {
+		filter.Scope = store.ScopeAll
+	}
+
+	tasks, err := getStore().ListFiltered(filter)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	stats := make(map[string]*labelStat)
+	for _, def := range projectConfig.Labels {
+		stats[def.Name] = &labelStat{Name: def.Name, Description: def.Description, Color: def.Color}
+	}
+	for _, t := range tasks {
+		for _, label := range t.Labels {
+			stat, ok := stats[label]
+			if !ok {
+				stat = &labelStat{Name: label}
+				stats[label] = stat
+			}
+			if t.Status.IsClosed() {
+				stat.Closed++
+			} else {
+				stat.Open++
+			}
+		}
+	}
+
+	list := make([]labelStat, 0, len(stats))
+	for _, stat := range stats {
+		list = append(list, *stat)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(data))
+		return nil
+	}
+
+	if len(list) == 0 {
+		fmt.Fprintln(stdout, "No labels found.")
+		return nil
+	}
+
+	width := len("LABEL")
+	for _, stat := range list {
+		if len(stat.Name) > width {
+			width = len(stat.Name)
+		}
+	}
+
+	fmt.Fprintf(stdout, "%s%-*s  %5s  %6s  %s%s\n", colorGray, width, "LABEL", "OPEN", "CLOSED", "DESCRIPTION", colorReset)
+	for _, stat := range list {
+		color := colorByName(stat.Color)
+		if color == "" {
+			color = colorReset
+		}
+		fmt.Fprintf(stdout, "%s%-*s%s  %5d  %6d  %s\n", color, width, stat.Name, colorReset, stat.Open, stat.Closed, stat.Description)
+	}
+	return nil
+}
+
+func runLabelsRename(args []string) error {
+	fs := flag.NewFlagSet("labels rename", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Rename a label on every task, including archived tasks.
+
+Usage:
+  task labels rename <old> <new>
+
+Examples:
+  task labels rename front-end frontend`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		errorf("Error: old and new label names are required")
+		fs.Usage()
+		return fmt.Errorf("old and new label names are required")
+	}
+
+	changed, err := replaceLabels([]string{fs.Arg(0)}, fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Renamed label %q to %q on %d task(s)\n", fs.Arg(0), fs.Arg(1), changed)
+	return nil
+}
+
+func runLabelsMerge(args []string) error {
+	fs := flag.NewFlagSet("labels merge", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var into string
+
+	fs.StringVar(&into, "into", "", "Label to merge into")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Merge several labels into one on every task, including archived tasks.
+
+Usage:
+  task labels merge <label>... --into <label>
+
+Flags:
+  --into string  Label to merge into
+
+Examples:
+  task labels merge ui frontend-ui --into frontend`)
+	}
+
+	if err := fs.Parse(splitArgs(fs, args)); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 1 {
+		errorf("Error: at least one label to merge is required")
+		fs.Usage()
+		return fmt.Errorf("at least one label to merge is required")
+	}
+
+	if into == "" {
+		errorf("Error: target label is required")
+		fs.Usage()
+		return fmt.Errorf("target label is required")
+	}
+
+	changed, err := replaceLabels(fs.Args(), into)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Merged label(s) %s into %q on %d task(s)\n", joinQuoted(fs.Args()), into, changed)
+	return nil
+}
+
+func runLabelsRemove(args []string) error {
+	fs := flag.NewFlagSet("labels remove", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Remove labels from every task, including archived tasks.
+
+Usage:
+  task labels remove <label>...
+
+Examples:
+  task labels remove wontfix
+  task labels remove old-sprint older-sprint`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 1 {
+		errorf("Error: at least one label to remove is required")
+		fs.Usage()
+		return fmt.Errorf("at least one label to remove is required")
+	}
+
+	changed, err := getStore().ReplaceLabels(fs.Args(), "")
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	if changed == 0 {
+		errorf("Error: no tasks have label(s): %s", joinQuoted(fs.Args()))
+		return fmt.Errorf("no tasks have label(s): %s", joinQuoted(fs.Args()))
+	}
+
+	fmt.Fprintf(stdout, "Removed label(s) %s from %d task(s)\n", joinQuoted(fs.Args()), changed)
+	return nil
+}
+
+// replaceLabels replaces the from labels with to across the store, returning
+// the number of tasks changed
+func replaceLabels(from []string, to string) (int, error) {
+	if err := projectConfig.ValidateLabels([]string{to}); err != nil {
+		errorf("Error: %v", err)
+		return 0, err
+	}
+
+	changed, err := getStore().ReplaceLabels(from, to)
+	if err != nil {
+		errorf("Error: %v", err)
+		return 0, err
+	}
+
+	if changed == 0 {
+		errorf("Error: no tasks have label(s): %s", joinQuoted(from))
+		return 0, fmt.Errorf("no tasks have label(s): %s", joinQuoted(from))
+	}
+
+	return changed, nil
+}
+
+// joinQuoted formats values as a comma separated list of quoted strings
+func joinQuoted(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/jackreid/task/internal/model"
@@ -22,22 +23,80 @@ const (
 	colorGray    = "\033[90m"
 )
 
+// filterFlags holds the task filter flags shared by list-style commands
+type filterFlags struct {
+	label    string
+	taskType string
+	status   string
+	archived bool
+	all      bool
+}
+
+// register adds the filter flags to fs
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.archived, "archived", false, "List archived tasks instead of live tasks")
+	fs.BoolVar(&f.all, "all", false, "List both live and archived tasks")
+	fs.StringVar(&f.label, "l", "", "Filter by label")
+	fs.StringVar(&f.label, "label", "", "Filter by label")
+	fs.StringVar(&f.taskType, "t", "", "Filter by type (task, bug, feature)")
+	fs.StringVar(&f.taskType, "type", "", "Filter by type (task, bug, feature)")
+	fs.StringVar(&f.status, "s", "", "Filter by status (todo, progress, blocked, abandon, done)")
+	fs.StringVar(&f.status, "status", "", "Filter by status (todo, progress, blocked, abandon, done)")
+}
+
+// filter validates the parsed flag values and builds a store filter
+func (f *filterFlags) filter() (store.Filter, error) {
+	filter := store.Filter{}
+
+	if f.status != "" {
+		status, err := model.ParseStatus(f.status)
+		if err != nil {
+			return filter, err
+		}
+		filter.Status = &status
+	}
+
+	if f.taskType != "" {
+		tt, err := model.ParseTaskType(f.taskType)
+		if err != nil {
+			return filter, err
+		}
+		filter.Type = &tt
+	}
+
+	if f.label != "" {
+		label := f.label
+		filter.Label = &label
+	}
+
+	switch {
+	case f.archived && f.all:
+		return filter, fmt.Errorf("--archived and --all cannot be combined")
+	case f.archived:
+		filter.Scope = store.ScopeArchived
+	case f.all:
+		filter.Scope = store.ScopeAll
+	}
+
+	return filter, nil
+}
+
 func runList(args []string) error {
 	fs := flag.NewFlagSet("list", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 
 	var jsonOutput bool
-	var labelFilter string
-	var typeFilter string
-	var statusFilter string
+	var allCollections bool
+	var filters filterFlags
+
+	var format, columnSpec, templateText string
 
 	fs.BoolVar(&jsonOutput, "json", false, "Output as JSON")
-	fs.StringVar(&labelFilter, "l", "", "Filter by label")
-	fs.StringVar(&labelFilter, "label", "", "Filter by label")
-	fs.StringVar(&typeFilter, "t", "", "Filter by type (task, bug, feature)")
-	fs.StringVar(&typeFilter, "type", "", "Filter by type (task, bug, feature)")
-	fs.StringVar(&statusFilter, "s", "", "Filter by status (todo, progress, blocked, abandon, done)")
-	fs.StringVar(&statusFilter, "status", "", "Filter by status (todo, progress, blocked, abandon, done)")
+	fs.StringVar(&format, "format", "", "Output format: pretty, table, json")
+	fs.StringVar(&columnSpec, "columns", "", "Comma separated table columns")
+	fs.StringVar(&templateText, "template", "", "Go template for each task, or the name of a template in .task/templates/")
+	fs.BoolVar(&allCollections, "all-collections", false, "List tasks from every collection")
+	filters.register(fs)
 
 	fs.Usage = func() {
 		fmt.Fprintln(stderr, `List all tasks.
@@ -47,15 +106,28 @@ Usage:
 
 Flags:
   --json              Output as JSON
+  --format string     Output format: pretty (default), table, json
+  --columns string    Comma separated table columns, implies --format table
+                      (id, status, type, title, labels, notes, created, updated)
+  --template string   Go template printed for each task, or the name of a
+                      template in .task/templates/
   -l, --label string  Filter by label
   -t, --type string   Filter by type: task, bug, feature
   -s, --status string Filter by status: todo, progress, blocked, abandon, done
+  --archived          List archived tasks instead of live tasks
+  --all               List both live and archived tasks
+  --all-collections   List tasks from every collection with a collection column
 
 Examples:
   task list
   task list --json
   task list -s todo
-  task list -t bug -l urgent`)
+  task list -t bug -l urgent
+  task list --format table
+  task list --columns id,status,title,created
+  task list --template '{{.ID}}\t{{.Title}}'
+  task list --all -l release
+  task list --all-collections -s progress`)
 	}
 
 	if err := fs.Parse(args); err != nil {
@@ -64,29 +136,42 @@ Examples:
 
 	s := getStore()
 
-	// Build filter
-	filter := store.Filter{}
+	filter, err := filters.filter()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
 
-	if statusFilter != "" {
-		status, err := model.ParseStatus(statusFilter)
-		if err != nil {
-			errorf("Error: %v", err)
-			return err
+	format, columns, err := outputFormat(format, jsonOutput, columnSpec)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	if templateText != "" {
+		if format != formatPretty {
+			errorf("Error: --template cannot be combined with --format, --json or --columns")
+			return fmt.Errorf("--template cannot be combined with --format, --json or --columns")
 		}
-		filter.Status = &status
+		format = formatTemplate
 	}
 
-	if typeFilter != "" {
-		tt, err := model.ParseTaskType(typeFilter)
+	if allCollections {
+		entries, err := listAllCollections(s, filter)
 		if err != nil {
 			errorf("Error: %v", err)
 			return err
 		}
-		filter.Type = &tt
-	}
-
-	if labelFilter != "" {
-		filter.Label = &labelFilter
+		switch format {
+		case formatTemplate:
+			return printListTemplate(templateText, entries)
+		case formatJSON:
+			return printSourcedTasksJSON(entries, "collection")
+		case formatTable:
+			width, _ := terminalSize()
+			return printTasksTable(entries, columns, width, "COLLECTION")
+		}
+		return printSourcedTasksPretty(entries)
 	}
 
 	tasks, err := s.ListFiltered(filter)
@@ -95,13 +180,156 @@ Examples:
 		return err
 	}
 
-	if jsonOutput {
+	entries := make([]sourcedTask, len(tasks))
+	for i, t := range tasks {
+		entries[i] = sourcedTask{Source: s.Collection(), Task: t}
+	}
+
+	switch format {
+	case formatJSON:
 		return printTasksJSON(tasks)
+	case formatTable:
+		width, _ := terminalSize()
+		return printTasksTable(entries, columns, width, "")
+	case formatTemplate:
+		return printListTemplate(templateText, entries)
 	}
 
 	return printTasksPretty(tasks)
 }
 
+// printListTemplate loads a --template value and prints it for each entry
+func printListTemplate(value string, entries []sourcedTask) error {
+	tmpl, err := loadTemplate(value)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	if err := printTemplate(stdout, tmpl, entries); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// outputFormat resolves the --format, --json and --columns flags into a
+// format and, for table output, its columns. --columns implies table output
+// and the project's default columns are used when it is not given
+func outputFormat(format string, jsonOutput bool, columnSpec string) (string, []tableColumn, error) {
+	switch {
+	case jsonOutput && format != "" && format != formatJSON:
+		return "", nil, fmt.Errorf("--json cannot be combined with --format %s", format)
+	case jsonOutput:
+		format = formatJSON
+	case format == "" && columnSpec != "":
+		format = formatTable
+	case format == "":
+		format = formatPretty
+	}
+
+	switch format {
+	case formatPretty, formatJSON:
+		if columnSpec != "" {
+			return "", nil, fmt.Errorf("--columns requires --format table")
+		}
+		return format, nil, nil
+	case formatTable:
+		spec := projectConfig.Columns()
+		if columnSpec != "" {
+			spec = strings.Split(columnSpec, ",")
+		}
+		columns, err := parseColumns(spec)
+		if err != nil {
+			return "", nil, err
+		}
+		return format, columns, nil
+	default:
+		return "", nil, fmt.Errorf("invalid format: %s (must be pretty, table or json)", format)
+	}
+}
+
+// sourcedTask pairs a task with the collection or project it was loaded from
+type sourcedTask struct {
+	Source string
+	Task   model.Task
+}
+
+// sortSourcedTasks orders entries by UpdatedAt descending
+func sortSourcedTasks(entries []sourcedTask) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Task.UpdatedAt.After(entries[j].Task.UpdatedAt)
+	})
+}
+
+// listAllCollections returns tasks matching filter from every collection,
+// sorted by UpdatedAt descending
+func listAllCollections(s *store.Store, filter store.Filter) ([]sourcedTask, error) {
+	names, err := s.Collections()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []sourcedTask
+	for _, name := range names {
+		tasks, err := store.NewCollection(workDir, name).ListFiltered(filter)
+		if err != nil {
+			return nil, fmt.Errorf("collection %s: %w", name, err)
+		}
+		for _, t := range tasks {
+			entries = append(entries, sourcedTask{Source: name, Task: t})
+		}
+	}
+
+	sortSourcedTasks(entries)
+	return entries, nil
+}
+
+// printSourcedTasksJSON prints tasks as JSON with each task's source added under key
+func printSourcedTasksJSON(entries []sourcedTask, key string) error {
+	out := make([]map[string]json.RawMessage, 0, len(entries))
+	for _, e := range entries {
+		data, err := json.Marshal(e.Task)
+		if err != nil {
+			return err
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		source, _ := json.Marshal(e.Source)
+		fields[key] = source
+		out = append(out, fields)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, string(data))
+	return nil
+}
+
+// printSourcedTasksPretty prints task lines prefixed with an aligned source column
+func printSourcedTasksPretty(entries []sourcedTask) error {
+	if len(entries) == 0 {
+		fmt.Fprintln(stdout, "No tasks found.")
+		return nil
+	}
+
+	width := 0
+	for _, e := range entries {
+		if len(e.Source) > width {
+			width = len(e.Source)
+		}
+	}
+
+	for _, e := range entries {
+		fmt.Fprintf(stdout, "%s%-*s%s ", colorMagenta, width, e.Source, colorReset)
+		printTaskLine(e.Task)
+	}
+	return nil
+}
+
 func printTasksJSON(tasks []model.Task) error {
 	data, err := json.MarshalIndent(tasks, "", "  ")
 	if err != nil {
@@ -136,12 +364,57 @@ func printTaskLine(t model.Task) {
 	)
 
 	if len(t.Labels) > 0 {
-		fmt.Fprintf(stdout, " %s[%s]%s", colorGray, strings.Join(t.Labels, ", "), colorReset)
+		fmt.Fprintf(stdout, " %s[%s]%s", colorGray, formatLabels(t.Labels, colorGray), colorReset)
 	}
 
 	fmt.Fprintln(stdout)
 }
 
+// formatLabels joins labels for display, coloring any label that has a color
+// in the project config and returning to base color after it
+func formatLabels(labels []string, base string) string {
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		if color := labelColor(label); color != "" {
+			parts[i] = color + label + base
+		} else {
+			parts[i] = label
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// labelColor returns the ANSI color configured for a label, or "" if none
+func labelColor(label string) string {
+	def := projectConfig.FindLabel(label)
+	if def == nil {
+		return ""
+	}
+	return colorByName(def.Color)
+}
+
+// colorByName maps a config color name to its ANSI code, or "" if unknown
+func colorByName(name string) string {
+	switch strings.ToLower(name) {
+	case "red":
+		return colorRed
+	case "green":
+		return colorGreen
+	case "yellow":
+		return colorYellow
+	case "blue":
+		return colorBlue
+	case "magenta":
+		return colorMagenta
+	case "cyan":
+		return colorCyan
+	case "gray", "grey":
+		return colorGray
+	default:
+		return ""
+	}
+}
+
 func getStatusColor(s model.Status) string {
 	switch s {
 	case model.StatusTodo:
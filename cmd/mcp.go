@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/jackreid/task/internal/rpc"
+)
+
+func runMCP(args []string) error {
+	fs := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Serve the tasks to coding agents over stdin and stdout.
+
+Speaks line-delimited JSON-RPC 2.0 using the tools of the Model Context
+Protocol, so agents get structured results and errors instead of parsing
+command output. Tools:
+
+  list_tasks     List tasks filtered by status, type, label, query and scope
+  get_task       Get a task with its notes
+  create_task    Create a task
+  update_status  Change a task's status, with an optional note
+  add_note       Add a note to a task
+  claim_next     Move the oldest todo task to progress and return it
+
+Each tool can also be called directly as a JSON-RPC method of the same name.
+
+Usage:
+  task mcp
+
+Example agent configuration:
+  {"mcpServers": {"task": {"command": "task", "args": ["mcp"]}}}`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Send webhooks as agents make changes rather than when the session ends
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sendWebhooksInBackground(ctx)
+
+	srv := rpc.New(getStore(), projectConfig)
+	if err := srv.Serve(stdin, stdout); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/jackreid/task/internal/store"
+)
+
+func runMove(args []string) error {
+	fs := flag.NewFlagSet("move", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var to string
+
+	fs.StringVar(&to, "to", "", "Collection to move the task into")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Move a task to another collection.
+
+The task keeps its notes and history. It is given a new ID only if its
+current ID is already used in the target collection.
+
+Usage:
+  task move <id> --to <collection>
+
+Flags:
+  --to string  Collection to move the task into ("default" for task.json)
+
+Examples:
+  task move abc --to api
+  task --collection api move abc --to default`)
+	}
+
+	// Reorder args to allow positional arguments before flags
+	reorderedArgs := reorderArgsForFlexibleFlags(args)
+	if err := fs.Parse(reorderedArgs); err != nil {
+		return err
+	}
+
+	if fs.NArg() < 1 {
+		errorf("Error: task ID is required")
+		fs.Usage()
+		return fmt.Errorf("task ID is required")
+	}
+
+	if to == "" {
+		errorf("Error: target collection is required")
+		fs.Usage()
+		return fmt.Errorf("target collection is required")
+	}
+
+	if err := store.ValidateCollectionName(to); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	taskID := fs.Arg(0)
+
+	s := getStore()
+	target := openCollection(to)
+
+	task, err := s.Move(taskID, target)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	if task.ID != taskID {
+		fmt.Fprintf(stdout, "Moved task %s to %s as %s (ID already in use)\n", taskID, to, task.ID)
+	} else {
+		fmt.Fprintf(stdout, "Moved task %s to %s\n", task.ID, to)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jackreid/task/internal/id"
 	"github.com/jackreid/task/internal/model"
@@ -21,6 +22,21 @@ func (l *labelList) Set(value string) error {
 	return nil
 }
 
+// dueNone is the --due value that clears a task's due date
+const dueNone = "none"
+
+// parseDue parses a --due value: a YYYY-MM-DD date, or "none" for no date
+func parseDue(value string) (time.Time, error) {
+	if value == dueNone {
+		return time.Time{}, nil
+	}
+	due, err := time.Parse(model.DateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid due date: %s (must be YYYY-MM-DD, or %s to clear it)", value, dueNone)
+	}
+	return due, nil
+}
+
 func runNew(args []string) error {
 	fs := flag.NewFlagSet("new", flag.ContinueOnError)
 	fs.SetOutput(stderr)
@@ -28,6 +44,7 @@ func runNew(args []string) error {
 	var description string
 	var labels labelList
 	var taskType string
+	var due string
 
 	fs.StringVar(&description, "d", "", "Task description")
 	fs.StringVar(&description, "description", "", "Task description")
@@ -35,6 +52,7 @@ func runNew(args []string) error {
 	fs.Var(&labels, "label", "Label to add (can be specified multiple times)")
 	fs.StringVar(&taskType, "t", "task", "Task type (task, bug, feature)")
 	fs.StringVar(&taskType, "type", "task", "Task type (task, bug, feature)")
+	fs.StringVar(&due, "due", "", "Due date (YYYY-MM-DD)")
 
 	fs.Usage = func() {
 		fmt.Fprintln(stderr, `Create a new task.
@@ -47,11 +65,13 @@ Flags:
   -d, --description string   Task description
   -l, --label string         Label to add (can be specified multiple times)
   -t, --type string          Task type: task, bug, feature (default "task")
+  --due string               Due date (YYYY-MM-DD)
 
 Examples:
   task new "Implement login"
   task new
   task new "Fix bug" -t bug -l urgent
+  task new "Release 2.0" --due 2024-07-01
   task new "Add feature" -t feature -d "Detailed description" -l frontend -l priority`)
 	}
 
@@ -133,6 +153,19 @@ Examples:
 		return err
 	}
 
+	if err := projectConfig.ValidateLabels(labels); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	var dueDate time.Time
+	if due != "" {
+		if dueDate, err = parseDue(due); err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+	}
+
 	s := getStore()
 
 	// Get existing IDs to ensure uniqueness
@@ -161,6 +194,10 @@ Examples:
 		task.AddLabel(label)
 	}
 
+	if !dueDate.IsZero() {
+		task.SetDue(dueDate)
+	}
+
 	// Save the task
 	if err := s.Add(task); err != nil {
 		errorf("Error: %v", err)
@@ -231,6 +268,10 @@ func runNewWithEditor(title string) error {
 	if fm.HasLabels {
 		labels = normalizeLabels(fm.Labels)
 	}
+	if err := projectConfig.ValidateLabels(labels); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
 
 	task := model.NewTask(taskID, title, tt)
 	if status != model.StatusTodo {
@@ -9,46 +9,88 @@ import (
 	"strings"
 
 	"github.com/jackreid/task/internal/id"
+	"github.com/jackreid/task/internal/model"
 )
 
 func runNote(args []string) error {
 	fs := flag.NewFlagSet("note", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 
+	var message string
+	var bulk bulkFlags
+
+	fs.StringVar(&message, "m", "", "Note content (all positional arguments are then task IDs)")
+	fs.StringVar(&message, "message", "", "Note content (all positional arguments are then task IDs)")
+	bulk.register(fs)
+
 	fs.Usage = func() {
-		fmt.Fprintln(stderr, `Add a note to a task.
+		fmt.Fprintln(stderr, `Add a note to one or more tasks.
 
 Usage:
   task note <id> <content>
   echo "content" | task note <id>
+  task note <id>... -m <content>
+  task note --where <filter> <content>
+  task note <content>
 
 The note content can be provided as the second positional argument,
-or via stdin for piping longer content.
+via -m (to note several tasks at once), or via stdin for piping longer content.
+Without an ID, the task for the current git branch is noted (see 'task current').
+
+Flags:
+  -m, --message string     Note content; all positional arguments are task IDs
+`+bulkFlagsUsage+`
 
 Examples:
   task note abc "This is a note"
   echo "Multi-line note" | task note abc
-  cat notes.txt | task note abc`)
+  cat notes.txt | task note abc
+  task note abc def -m "Blocked on the API release"
+  task note --where "status=progress" "Code freeze starts Friday"`)
 	}
 
-	if err := fs.Parse(args); err != nil {
+	if err := fs.Parse(splitArgs(fs, args)); err != nil {
 		return err
 	}
 
-	if fs.NArg() < 1 {
+	// Work out which positional arguments are IDs and which are content
+	var taskIDs []string
+	var content string
+	switch {
+	case message != "":
+		taskIDs = fs.Args()
+		content = message
+	case bulk.where != "":
+		content = strings.Join(fs.Args(), " ")
+	default:
+		if fs.NArg() >= 1 {
+			taskIDs = fs.Args()[:1]
+		}
+		if fs.NArg() >= 2 {
+			// Content from positional argument
+			content = fs.Arg(1)
+		}
+	}
+
+	// Without an ID, note the task for the current git branch. A lone
+	// argument that isn't a task ID is then the note content, unless the
+	// content is being piped in, when it must be a mistyped ID
+	if bulk.where == "" && message == "" && fs.NArg() == 1 && !stdinPiped() {
+		if task, _ := getStore().FindByID(fs.Arg(0)); task == nil {
+			if current := currentTaskIDs(nil, bulk); len(current) > 0 {
+				taskIDs, content = current, fs.Arg(0)
+			}
+		}
+	}
+	taskIDs = currentTaskIDs(taskIDs, bulk)
+
+	if len(taskIDs) == 0 && bulk.where == "" {
 		errorf("Error: task ID is required")
 		fs.Usage()
 		return fmt.Errorf("task ID is required")
 	}
 
-	taskID := fs.Arg(0)
-
-	// Get note content from argument or stdin
-	var content string
-	if fs.NArg() >= 2 {
-		// Content from positional argument
-		content = fs.Arg(1)
-	} else {
+	if content == "" {
 		// Try to read from stdin
 		stdinContent, err := readStdin()
 		if err != nil {
@@ -67,45 +109,41 @@ Examples:
 
 	s := getStore()
 
-	task, err := s.FindByID(taskID)
-	if err != nil {
-		errorf("Error: %v", err)
-		return err
-	}
-
-	if task == nil {
-		errorf("Error: task not found: %s", taskID)
-		return fmt.Errorf("task not found: %s", taskID)
-	}
-
-	// Generate note ID
-	noteID, err := id.GenerateNoteID(taskID)
+	updated, err := applyBulk(s, taskIDs, bulk, "add a note to", func(task *model.Task) error {
+		// Generate note ID
+		noteID, err := id.GenerateNoteID(task.ID)
+		if err != nil {
+			return fmt.Errorf("generating note ID: %w", err)
+		}
+		task.AddNote(noteID, content)
+		return nil
+	})
 	if err != nil {
-		errorf("Error generating note ID: %v", err)
 		return err
 	}
 
-	task.AddNote(noteID, content)
-
-	if err := s.Update(task); err != nil {
-		errorf("Error: %v", err)
-		return err
+	for _, task := range updated {
+		fmt.Fprintf(stdout, "Added note to task %s\n", task.ID)
 	}
-
-	fmt.Fprintf(stdout, "Added note to task %s\n", taskID)
 	return nil
 }
 
-// readStdin reads content from stdin if available
-func readStdin() (string, error) {
-	// Check if stdin has data (not a terminal)
+// stdinPiped reports whether stdin has piped data rather than being a
+// terminal. A stdin overridden in tests always counts as piped
+func stdinPiped() bool {
+	if stdin != io.Reader(os.Stdin) {
+		return true
+	}
 	stat, err := os.Stdin.Stat()
 	if err != nil {
-		return "", nil // Can't stat, assume no stdin
+		return false // Can't stat, assume no stdin
 	}
+	return (stat.Mode() & os.ModeCharDevice) == 0
+}
 
-	// Check if stdin is a pipe or has data
-	if (stat.Mode() & os.ModeCharDevice) != 0 {
+// readStdin reads content from stdin if available
+func readStdin() (string, error) {
+	if !stdinPiped() {
 		return "", nil // stdin is a terminal, no pipe data
 	}
 
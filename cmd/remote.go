@@ -0,0 +1,354 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/remote"
+	"github.com/jackreid/task/internal/store"
+)
+
+func runRemote(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "push":
+			return runRemotePush(args[1:])
+		case "pull":
+			return runRemotePull(args[1:])
+		}
+	}
+
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		printRemoteUsage()
+		if len(args) == 0 {
+			return fmt.Errorf("remote subcommand is required")
+		}
+		return nil
+	}
+	errorf("Error: unknown remote subcommand: %s", args[0])
+	printRemoteUsage()
+	return fmt.Errorf("unknown remote subcommand: %s", args[0])
+}
+
+// printRemoteUsage prints the help for task remote
+func printRemoteUsage() {
+	fmt.Fprintln(stderr, `Mirror tasks to issues in an issue tracker.
+
+The tracker is configured as "remote" in .task/config:
+
+  {"remote": {"type": "github", "repo": "owner/name"}}
+
+The API token is read from $GITHUB_TOKEN, or the variable named by
+"token_env". "url" sets the API URL for GitHub Enterprise.
+
+Titles and descriptions map to issue titles and bodies, labels to labels,
+notes to comments, and done or abandoned tasks to closed issues. Each
+task records its issue, and a task and issue that have both been edited
+since they were last synced are reported as a conflict and left alone.
+
+Usage:
+  task remote push [ids] [flags]
+  task remote pull [flags]
+
+Subcommands:
+  push  Send tasks to their issues, creating issues for new tasks
+  pull  Update tasks from their issues`)
+}
+
+func runRemotePush(args []string) error {
+	fs := flag.NewFlagSet("remote push", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var label string
+	var all, force bool
+
+	fs.StringVar(&label, "l", "", "Push the tasks with a label")
+	fs.StringVar(&label, "label", "", "Push the tasks with a label")
+	fs.BoolVar(&all, "all", false, "Push every task")
+	fs.BoolVar(&force, "force", false, "Overwrite issues that were edited since the last sync")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Send tasks to their issues, creating an issue for each task without one.
+
+Without IDs or flags, the tasks that already have issues are pushed. Tasks
+unchanged since their last sync are skipped.
+
+Usage:
+  task remote push [ids] [flags]
+
+Flags:
+  -l, --label string  Push the tasks with a label
+  --all               Push every task
+  --force             Overwrite issues that were edited since the last sync
+
+Examples:
+  task remote push abc
+  task remote push -l public
+  task remote push`)
+	}
+
+	if err := fs.Parse(splitArgs(fs, args)); err != nil {
+		return err
+	}
+
+	s, sync, err := openRemote(force)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	tasks, err := s.Load()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	selected := make(map[string]bool)
+	for _, taskID := range fs.Args() {
+		selected[taskID] = true
+	}
+	var push []model.Task
+	for _, t := range tasks {
+		_, linked := remote.IssueNumber(sync.Remote, t.Refs[sync.Remote.Name()])
+		switch {
+		case selected[t.ID]:
+			delete(selected, t.ID)
+		case all, label != "" && t.HasLabel(label):
+		case len(fs.Args()) == 0 && label == "" && linked:
+		default:
+			continue
+		}
+		push = append(push, t)
+	}
+	for _, taskID := range fs.Args() {
+		if selected[taskID] {
+			err := fmt.Errorf("task not found: %s", taskID)
+			errorf("Error: %v", err)
+			return err
+		}
+	}
+
+	var results []remote.Result
+	var pushErr error
+	for _, t := range push {
+		result, err := sync.Push(t)
+		if result.Action != "" {
+			// Keep the link to an issue created before the error
+			results = append(results, result)
+		}
+		if err != nil {
+			pushErr = fmt.Errorf("pushing task %s: %w", t.ID, err)
+			break
+		}
+	}
+	if err := saveSync(s, sync, results); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	counts := make(map[remote.Action]int)
+	for _, r := range results {
+		counts[r.Action]++
+		switch r.Action {
+		case remote.ActionCreated:
+			fmt.Fprintf(stdout, "Created issue %s for task %s\n", r.Ref, r.Task.ID)
+		case remote.ActionPushed:
+			fmt.Fprintf(stdout, "Pushed task %s to %s\n", r.Task.ID, r.Ref)
+		case remote.ActionConflict:
+			fmt.Fprintf(stderr, "Conflict: task %s and %s have both changed since the last sync (pull, or push --force to overwrite the issue)\n", r.Task.ID, r.Ref)
+		case remote.ActionMissing:
+			fmt.Fprintf(stderr, "Warning: issue %s for task %s no longer exists\n", r.Ref, r.Task.ID)
+		}
+	}
+	if pushErr != nil {
+		errorf("Error: %v", pushErr)
+		return pushErr
+	}
+	fmt.Fprintf(stdout, "Pushed %d task(s): %d created, %d updated, %d unchanged\n",
+		len(results), counts[remote.ActionCreated], counts[remote.ActionPushed], counts[remote.ActionUnchanged])
+	return conflictError(counts[remote.ActionConflict])
+}
+
+func runRemotePull(args []string) error {
+	fs := flag.NewFlagSet("remote pull", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var create, force bool
+
+	fs.BoolVar(&create, "new", false, "Create tasks for open issues without one")
+	fs.BoolVar(&force, "force", false, "Overwrite tasks that were edited since the last sync")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Update tasks from their issues.
+
+A closed issue marks its task done, or abandoned when it was closed as not
+planned, and a reopened issue reopens its task. New comments are added as
+notes. Tasks whose issues are unchanged since their last sync are skipped.
+
+Usage:
+  task remote pull [flags]
+
+Flags:
+  --new    Create tasks for open issues without one
+  --force  Overwrite tasks that were edited since the last sync
+
+Examples:
+  task remote pull
+  task remote pull --new`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		errorf("Error: unexpected argument: %s", fs.Arg(0))
+		fs.Usage()
+		return fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	s, sync, err := openRemote(force)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	tasks, err := s.Load()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	archived, err := s.LoadArchive()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	issues, err := sync.Remote.List()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	byNumber := make(map[int]*remote.Issue, len(issues))
+	for i := range issues {
+		byNumber[issues[i].Number] = &issues[i]
+	}
+
+	linked := make(map[int]bool)
+	for _, t := range archived {
+		if number, ok := remote.IssueNumber(sync.Remote, t.Refs[sync.Remote.Name()]); ok {
+			linked[number] = true
+		}
+	}
+
+	var results []remote.Result
+	for _, t := range tasks {
+		number, ok := remote.IssueNumber(sync.Remote, t.Refs[sync.Remote.Name()])
+		if !ok {
+			continue
+		}
+		linked[number] = true
+		result, err := sync.Pull(t, byNumber[number])
+		if err != nil {
+			errorf("Error: pulling task %s: %v", t.ID, err)
+			return err
+		}
+		results = append(results, result)
+	}
+
+	var created []model.Task
+	if create {
+		for _, issue := range issues {
+			if linked[issue.Number] || issue.State != remote.StateOpen {
+				continue
+			}
+			t, err := sync.Import(issue)
+			if err != nil {
+				errorf("Error: importing %s: %v", remote.Ref(sync.Remote, issue.Number), err)
+				return err
+			}
+			created = append(created, t)
+		}
+	}
+
+	for _, r := range results {
+		if !r.Changed {
+			continue
+		}
+		if err := projectConfig.ValidateLabels(r.Task.Labels); err != nil {
+			errorf("Error: task %s: %v", r.Task.ID, err)
+			return err
+		}
+	}
+	if len(created) > 0 {
+		if err := importTasks(s, created); err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+	}
+	if err := saveSync(s, sync, results); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	counts := make(map[remote.Action]int)
+	for _, r := range results {
+		counts[r.Action]++
+		switch r.Action {
+		case remote.ActionPulled:
+			fmt.Fprintf(stdout, "Pulled %s into task %s\n", r.Ref, r.Task.ID)
+		case remote.ActionConflict:
+			fmt.Fprintf(stderr, "Conflict: task %s and %s have both changed since the last sync (push, or pull --force to overwrite the task)\n", r.Task.ID, r.Ref)
+		case remote.ActionMissing:
+			fmt.Fprintf(stderr, "Warning: issue %s for task %s no longer exists\n", r.Ref, r.Task.ID)
+		}
+	}
+	for _, t := range created {
+		fmt.Fprintf(stdout, "Created task %s from %s\n", t.ID, t.Refs[sync.Remote.Name()])
+	}
+	fmt.Fprintf(stdout, "Pulled %d issue(s): %d updated, %d unchanged, %d created\n",
+		len(results)+len(created), counts[remote.ActionPulled], counts[remote.ActionUnchanged], len(created))
+	return conflictError(counts[remote.ActionConflict])
+}
+
+// openRemote returns the store and a syncer for the configured remote
+func openRemote(force bool) (*store.Store, *remote.Syncer, error) {
+	s := getStore()
+	if !s.IsInitialized() {
+		return nil, nil, errors.New("task is not initialized, run 'task init' first")
+	}
+	if projectConfig.Remote == nil {
+		return nil, nil, errors.New("no remote is configured, add one to .task/config (see 'task remote --help')")
+	}
+	r, err := remote.New(*projectConfig.Remote, workDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	state, err := remote.LoadState(s.Dir())
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, &remote.Syncer{Remote: r, State: state, Force: force}, nil
+}
+
+// saveSync saves the tasks a sync changed and the sync state
+func saveSync(s *store.Store, sync *remote.Syncer, results []remote.Result) error {
+	var changed []model.Task
+	for _, r := range results {
+		if r.Changed {
+			changed = append(changed, r.Task)
+		}
+	}
+	if len(changed) > 0 {
+		if err := s.UpdateMany(changed); err != nil {
+			return err
+		}
+	}
+	return sync.State.Save(s.Dir())
+}
+
+// conflictError reports conflicts left by a sync as the command's error
+func conflictError(conflicts int) error {
+	if conflicts == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d conflict(s)", conflicts)
+}
@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
+	"github.com/jackreid/task/internal/config"
+	"github.com/jackreid/task/internal/hooks"
 	"github.com/jackreid/task/internal/store"
 	"github.com/jackreid/task/internal/version"
+	"github.com/jackreid/task/internal/webhook"
 )
 
 var (
@@ -17,11 +21,81 @@ var (
 	stdin io.Reader = os.Stdin
 	// workDir is the working directory for the store (can be overridden in tests)
 	workDir string = ""
+	// collection is the active collection, set from --collection or the config default
+	collection string = ""
+	// projectConfig is the project config, loaded at the start of each command
+	projectConfig = &config.Config{}
+	// dispatcher queues the command's changes for the webhooks, shared by
+	// every store the command opens so they are sent once it finishes
+	dispatcher *webhook.Dispatcher
 )
 
-// getStore returns a store instance for the current working directory
+// getStore returns a store instance for the active collection in the current working directory
 func getStore() *store.Store {
-	return store.New(workDir)
+	return openCollection(collection)
+}
+
+// openCollection returns a store for a collection in the current working
+// directory with the project's hooks attached
+func openCollection(name string) *store.Store {
+	s := store.NewCollection(workDir, name)
+	s.AddHook(hooks.New(s.Dir(), stderr))
+	if len(projectConfig.Webhooks) > 0 {
+		s.AddHook(commandDispatcher())
+	}
+	return s
+}
+
+// loadConfig reads the project config for the current working directory
+func loadConfig() (*config.Config, error) {
+	return config.Load(store.New(workDir).Dir())
+}
+
+// parseGlobalFlags strips flags that apply to every command (such as --collection)
+// from args and applies them, returning the remaining arguments. The project
+// config is loaded here so every command sees the same settings
+func parseGlobalFlags(args []string) ([]string, error) {
+	collection = ""
+
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if arg == "--collection" {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag needs an argument: --collection")
+			}
+			collection = args[i+1]
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "--collection=") {
+			collection = strings.TrimPrefix(arg, "--collection=")
+			continue
+		}
+		rest = append(rest, arg)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	projectConfig = cfg
+
+	if collection == "" {
+		collection = cfg.DefaultCollection
+	}
+
+	if collection != "" {
+		if err := store.ValidateCollectionName(collection); err != nil {
+			return nil, err
+		}
+	}
+
+	return rest, nil
 }
 
 // Execute runs the task CLI application
@@ -35,12 +109,22 @@ func Execute() error {
 
 // run is the internal entry point that can be tested
 func run(args []string) error {
+	args, err := parseGlobalFlags(args)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
 	if len(args) == 0 {
 		printHelp()
 		return nil
 	}
 
 	command := args[0]
+	dispatcher = nil
+	if command != "webhooks" {
+		defer sendWebhooks()
+	}
 
 	switch command {
 	case "help", "-h", "--help":
@@ -66,6 +150,46 @@ func run(args []string) error {
 		return runDelete(args[1:])
 	case "clean":
 		return runClean(args[1:])
+	case "archive":
+		return runArchive(args[1:])
+	case "restore":
+		return runRestore(args[1:])
+	case "move":
+		return runMove(args[1:])
+	case "global":
+		return runGlobal(args[1:])
+	case "labels":
+		return runLabels(args[1:])
+	case "board":
+		return runBoard(args[1:])
+	case "ui":
+		return runUI(args[1:])
+	case "export":
+		return runExport(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "site":
+		return runSite(args[1:])
+	case "serve":
+		return runServe(args[1:])
+	case "mcp":
+		return runMCP(args[1:])
+	case "watch":
+		return runWatch(args[1:])
+	case "webhooks":
+		return runWebhooks(args[1:])
+	case "git":
+		return runGit(args[1:])
+	case "branch":
+		return runBranch(args[1:])
+	case "current":
+		return runCurrent(args[1:])
+	case "changelog":
+		return runChangelog(args[1:])
+	case "remote":
+		return runRemote(args[1:])
+	case "scan":
+		return runScan(args[1:])
 	case "ready":
 		return runReady(args[1:])
 	case "take":
@@ -99,6 +223,26 @@ Commands:
   note        Add a note to a task
   delete      Delete a task completely
   clean       Delete all closed tasks (done/abandon)
+  archive     Move closed tasks into the archive
+  restore     Restore an archived task
+  move        Move a task to another collection
+  global      List and search tasks across all registered projects
+  labels      List, rename and merge labels
+  board       Print tasks as a kanban board
+  ui          Open an interactive kanban board
+  export      Export tasks as CSV, TSV, todo.txt, Taskwarrior, iCalendar or Markdown
+  import      Import tasks from CSV, TSV, todo.txt or Taskwarrior
+  site        Generate a static HTML site of the tasks
+  serve       Serve the tasks as a JSON API over HTTP
+  mcp         Serve the tasks to coding agents over stdio (JSON-RPC)
+  watch       Print task changes as they happen
+  webhooks    List, retry or clear pending webhook deliveries
+  git         Link commits to tasks with git hooks
+  branch      Create and check out a git branch for a task
+  current     Show the task for the current git branch
+  changelog   Print or write release notes from completed tasks
+  scan        Turn TODO/FIXME/HACK comments in the code into tasks
+  remote      Push tasks to and pull them from GitHub issues
 
 Aliases:
   ready       List tasks with status 'todo'
@@ -107,6 +251,9 @@ Aliases:
   block       Set task status to 'blocked'
   abandon     Set task status to 'abandon'
 
+Global flags:
+  --collection string  Use a named collection (.task/<name>.jsonl) instead of the default
+
 Use "task <command> -h" for more information about a command.`)
 }
 
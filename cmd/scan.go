@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackreid/task/internal/id"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/scan"
+)
+
+func runScan(args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var labels labelList
+	var dryRun bool
+
+	fs.Var(&labels, "l", "Label for created tasks (can be specified multiple times)")
+	fs.Var(&labels, "label", "Label for created tasks (can be specified multiple times)")
+	fs.BoolVar(&dryRun, "dry-run", false, "Show what would change without changing anything")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Turn TODO, FIXME and HACK comments in the code into tasks.
+
+Source files under the given paths (default: the whole project) are searched,
+skipping files ignored by .gitignore. Each new comment becomes a task (a bug
+for FIXME) that records the comment's file and line. Running scan again
+updates the line of comments that moved and marks tasks done when their
+comment has been removed. A comment is recognised by its file and text, so
+editing its text replaces the task with a new one.
+
+Usage:
+  task scan [paths] [flags]
+
+Flags:
+  -l, --label string  Label for created tasks (can be specified multiple times)
+  --dry-run           Show what would change without changing anything
+
+Examples:
+  task scan
+  task scan cmd internal -l tech-debt
+  task scan --dry-run`)
+	}
+
+	if err := fs.Parse(splitArgs(fs, args)); err != nil {
+		return err
+	}
+
+	s := getStore()
+	if !s.IsInitialized() {
+		err := errors.New("task is not initialized, run 'task init' first")
+		errorf("Error: %v", err)
+		return err
+	}
+	if err := projectConfig.ValidateLabels(labels); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	root := workDir
+	if root == "" {
+		root = "."
+	}
+	paths, err := scanPaths(root, fs.Args())
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	comments, scanned, err := scan.Scan(root, paths)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	tasks, err := s.Load()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	archived, err := s.LoadArchive()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	changes := scan.Reconcile(tasks, archived, comments, paths)
+
+	if dryRun {
+		for _, c := range changes.Create {
+			fmt.Fprintf(stdout, "Would create %s %s %s(%s:%d)%s\n", getTypeIcon(c.TaskType()), c.Title(), colorGray, c.File, c.Line, colorReset)
+		}
+		for _, t := range changes.Removed {
+			fmt.Fprintf(stdout, "Would complete task %s: comment removed from %s\n", t.ID, t.Source.File)
+		}
+		fmt.Fprintf(stdout, "Scanned %d file(s): would create %d, move %d, complete %d\n",
+			scanned, len(changes.Create), len(changes.Moved), len(changes.Removed))
+		return nil
+	}
+
+	created := make([]model.Task, len(changes.Create))
+	for i, c := range changes.Create {
+		t := model.NewTask("", c.Title(), c.TaskType())
+		t.Source = c.Source()
+		if len(labels) > 0 {
+			t.SetLabels(labels)
+		}
+		created[i] = *t
+	}
+	if len(created) > 0 {
+		if err := importTasks(s, created); err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+	}
+
+	updated := changes.Moved
+	for _, t := range changes.Removed {
+		noteID, err := id.GenerateNoteID(t.ID)
+		if err != nil {
+			errorf("Error: generating note ID: %v", err)
+			return err
+		}
+		t.AddNote(noteID, fmt.Sprintf("Comment removed from %s", t.Source.File))
+		t.SetStatus(model.StatusDone)
+		updated = append(updated, t)
+	}
+	if len(updated) > 0 {
+		if err := s.UpdateMany(updated); err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+	}
+
+	for _, t := range created {
+		fmt.Fprintf(stdout, "Created task %s: %s %s(%s)%s\n", t.ID, t.Title, colorGray, t.Source, colorReset)
+	}
+	for _, t := range changes.Removed {
+		fmt.Fprintf(stdout, "Completed task %s: comment removed from %s\n", t.ID, t.Source.File)
+	}
+	fmt.Fprintf(stdout, "Scanned %d file(s): %d created, %d moved, %d completed\n",
+		scanned, len(created), len(changes.Moved), len(changes.Removed))
+	return nil
+}
+
+// scanPaths makes the paths given to scan relative to the project root,
+// checking that they exist within it
+func scanPaths(root string, args []string) ([]string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(args))
+	for _, arg := range args {
+		abs := arg
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(absRoot, arg)
+		}
+		rel, err := filepath.Rel(absRoot, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is outside the project", arg)
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return nil, err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	return paths, nil
+}
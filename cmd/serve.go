@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/jackreid/task/internal/server"
+)
+
+// defaultServeAddr keeps the API local unless another address is asked for
+const defaultServeAddr = "127.0.0.1:8080"
+
+// shutdownTimeout is how long in-flight requests get to finish on interrupt
+const shutdownTimeout = 5 * time.Second
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var addr string
+	fs.StringVar(&addr, "addr", defaultServeAddr, "Address to listen on")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Serve the tasks as a JSON API over HTTP.
+
+Endpoints:
+  GET    /api/tasks             List tasks (?status= &type= &label= &q= &scope=live|archived|all)
+  POST   /api/tasks             Create a task
+  GET    /api/tasks/{id}        Get a task
+  PATCH  /api/tasks/{id}        Update a task
+  DELETE /api/tasks/{id}        Delete a task
+  POST   /api/tasks/{id}/notes  Add a note to a task
+  GET    /api/events            Stream task changes as server-sent events
+  GET    /calendar.ics          Subscribe to the tasks as a calendar (same
+                                filters as /api/tasks, plus ?events=true)
+
+Task responses carry an ETag; send it back in If-Match to make a write fail
+with 412 if someone else changed the task first.
+
+Writes must be sent with Content-Type: application/json, and requests must
+be addressed to localhost, an IP address or the host in --addr, so that web
+pages can't reach the API through the browser.
+
+Usage:
+  task serve [flags]
+
+Flags:
+  --addr string  Address to listen on (default "127.0.0.1:8080")
+
+Examples:
+  task serve
+  task serve --addr :9000`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s := getStore()
+	if !s.IsInitialized() {
+		err := errors.New("task not initialized, run 'task init' first")
+		errorf("Error: %v", err)
+		return err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Send webhooks as changes are made rather than when the server stops
+	sendWebhooksInBackground(ctx)
+
+	srv := server.New(s, projectConfig)
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		srv.AllowHost(host)
+	}
+	srv.Handle(server.CalendarPath, server.CalendarHandler(s, calendarOptions(s, false)))
+	return serve(ctx, listener, srv)
+}
+
+// serve handles requests on listener until ctx is cancelled, then shuts down
+// gracefully. Request contexts are cancelled first, so long-lived streams
+// such as /api/events end instead of holding up the shutdown
+func serve(ctx context.Context, listener net.Listener, handler http.Handler) error {
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(listener)
+	}()
+
+	fmt.Fprintf(stdout, "Listening on http://%s\n", listener.Addr())
+
+	select {
+	case err := <-done:
+		errorf("Error: %v", err)
+		return err
+	case <-ctx.Done():
+	}
+
+	cancelRequests()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	if err := <-done; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errorf("Error: %v", err)
+		return err
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 
 	"github.com/jackreid/task/internal/model"
@@ -14,8 +16,10 @@ func runShow(args []string) error {
 	fs.SetOutput(stderr)
 
 	var jsonOutput bool
+	var templateText string
 
 	fs.BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	fs.StringVar(&templateText, "template", "", "Go template for the task, or the name of a template in .task/templates/")
 
 	fs.Usage = func() {
 		fmt.Fprintln(stderr, `Show task details.
@@ -24,16 +28,17 @@ Usage:
   task show <id> [flags]
 
 Flags:
-  --json  Output as JSON
+  --json             Output as JSON
+  --template string  Go template for the task, or the name of a template
+                     in .task/templates/
 
 Examples:
   task show abc
-  task show abc --json`)
+  task show abc --json
+  task show abc --template '{{.Title}} ({{ago .UpdatedAt}})'`)
 	}
 
-	// Reorder args to allow positional arguments before flags
-	reorderedArgs := reorderArgsForFlexibleFlags(args)
-	if err := fs.Parse(reorderedArgs); err != nil {
+	if err := fs.Parse(splitArgs(fs, args)); err != nil {
 		return err
 	}
 
@@ -47,36 +52,46 @@ Examples:
 
 	s := getStore()
 
-	task, err := s.FindByID(taskID)
+	// findTask falls back to the archive so closed history stays inspectable
+	task, err := findTask(s, taskID)
 	if err != nil {
 		errorf("Error: %v", err)
 		return err
 	}
 
-	if task == nil {
-		errorf("Error: task not found: %s", taskID)
-		return fmt.Errorf("task not found: %s", taskID)
+	if jsonOutput && templateText != "" {
+		errorf("Error: --json and --template cannot be combined")
+		return fmt.Errorf("--json and --template cannot be combined")
 	}
 
 	if jsonOutput {
 		return printTaskJSON(task)
 	}
 
+	if templateText != "" {
+		return printListTemplate(templateText, []sourcedTask{{Source: s.Collection(), Task: *task}})
+	}
+
 	return printTaskDetail(task)
 }
 
 func printTaskJSON(task *model.Task) error {
 	// For JSON output, we bypass the custom MarshalJSON to get clean output
 	type TaskJSON struct {
-		ID          string       `json:"id"`
-		CreatedAt   string       `json:"created_at"`
-		UpdatedAt   string       `json:"updated_at"`
-		Title       string       `json:"title"`
-		Description *string      `json:"description"`
-		Type        string       `json:"type"`
-		Status      string       `json:"status"`
-		Labels      []string     `json:"labels"`
-		Notes       []model.Note `json:"notes"`
+		ID              string            `json:"id"`
+		CreatedAt       string            `json:"created_at"`
+		UpdatedAt       string            `json:"updated_at"`
+		Title           string            `json:"title"`
+		Description     *string           `json:"description"`
+		Type            string            `json:"type"`
+		Status          string            `json:"status"`
+		Labels          []string          `json:"labels"`
+		Notes           []model.Note      `json:"notes"`
+		Branch          string            `json:"branch,omitempty"`
+		Source          *model.Source     `json:"source,omitempty"`
+		Refs            map[string]string `json:"refs,omitempty"`
+		StatusChangedAt string            `json:"status_changed_at,omitempty"`
+		Due             string            `json:"due,omitempty"`
 	}
 	t := TaskJSON{
 		ID:          task.ID,
@@ -88,6 +103,15 @@ func printTaskJSON(task *model.Task) error {
 		Status:      string(task.Status),
 		Labels:      task.Labels,
 		Notes:       task.Notes,
+		Branch:      task.Branch,
+		Source:      task.Source,
+		Refs:        task.Refs,
+	}
+	if !task.StatusChangedAt.IsZero() {
+		t.StatusChangedAt = task.StatusChangedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if !task.Due.IsZero() {
+		t.Due = task.Due.Format(model.DateLayout)
 	}
 	data, err := json.MarshalIndent(t, "", "  ")
 	if err != nil {
@@ -98,43 +122,66 @@ func printTaskJSON(task *model.Task) error {
 }
 
 func printTaskDetail(task *model.Task) error {
+	writeTaskDetail(stdout, task)
+	return nil
+}
+
+// writeTaskDetail writes the full human-readable view of a task to w
+func writeTaskDetail(w io.Writer, task *model.Task) {
 	statusColor := getStatusColor(task.Status)
 	typeIcon := getTypeIcon(task.Type)
 
 	// Header
-	fmt.Fprintf(stdout, "%s%s%s %s\n", colorCyan, task.ID, colorReset, task.Title)
-	fmt.Fprintln(stdout, strings.Repeat("─", 40))
+	fmt.Fprintf(w, "%s%s%s %s\n", colorCyan, task.ID, colorReset, task.Title)
+	fmt.Fprintln(w, strings.Repeat("─", 40))
 
 	// Status and Type
-	fmt.Fprintf(stdout, "Status:  %s%s %s%s\n", statusColor, statusSymbol(task.Status), task.Status, colorReset)
-	fmt.Fprintf(stdout, "Type:    %s %s\n", typeIcon, task.Type)
+	fmt.Fprintf(w, "Status:  %s%s %s%s\n", statusColor, statusSymbol(task.Status), task.Status, colorReset)
+	fmt.Fprintf(w, "Type:    %s %s\n", typeIcon, task.Type)
 
 	// Labels
 	if len(task.Labels) > 0 {
-		fmt.Fprintf(stdout, "Labels:  %s\n", strings.Join(task.Labels, ", "))
+		fmt.Fprintf(w, "Labels:  %s\n", formatLabels(task.Labels, colorReset))
 	} else {
-		fmt.Fprintf(stdout, "Labels:  %s(none)%s\n", colorGray, colorReset)
+		fmt.Fprintf(w, "Labels:  %s(none)%s\n", colorGray, colorReset)
+	}
+	if !task.Due.IsZero() {
+		fmt.Fprintf(w, "Due:     %s\n", task.Due.Format(model.DateLayout))
+	}
+	if task.Branch != "" {
+		fmt.Fprintf(w, "Branch:  %s\n", task.Branch)
+	}
+	if task.Source != nil {
+		fmt.Fprintf(w, "Source:  %s\n", task.Source)
+	}
+	tools := make([]string, 0, len(task.Refs))
+	for tool := range task.Refs {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	for _, tool := range tools {
+		fmt.Fprintf(w, "Ref:     %s %s\n", tool, task.Refs[tool])
 	}
 
 	// Description
-	fmt.Fprintln(stdout)
+	fmt.Fprintln(w)
 	if task.Description != nil && *task.Description != "" {
-		fmt.Fprintf(stdout, "Description:\n  %s\n", *task.Description)
+		fmt.Fprintf(w, "Description:\n  %s\n", *task.Description)
 	} else {
-		fmt.Fprintf(stdout, "Description: %s(none)%s\n", colorGray, colorReset)
+		fmt.Fprintf(w, "Description: %s(none)%s\n", colorGray, colorReset)
 	}
 
 	// Timestamps
-	fmt.Fprintln(stdout)
-	fmt.Fprintf(stdout, "%sCreated: %s%s\n", colorGray, task.CreatedAt.Format("2006-01-02 15:04:05"), colorReset)
-	fmt.Fprintf(stdout, "%sUpdated: %s%s\n", colorGray, task.UpdatedAt.Format("2006-01-02 15:04:05"), colorReset)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%sCreated: %s%s\n", colorGray, task.CreatedAt.Format("2006-01-02 15:04:05"), colorReset)
+	fmt.Fprintf(w, "%sUpdated: %s%s\n", colorGray, task.UpdatedAt.Format("2006-01-02 15:04:05"), colorReset)
 
 	// Notes
 	if len(task.Notes) > 0 {
-		fmt.Fprintln(stdout)
-		fmt.Fprintf(stdout, "Notes (%d):\n", len(task.Notes))
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Notes (%d):\n", len(task.Notes))
 		for _, note := range task.Notes {
-			fmt.Fprintf(stdout, "  %s[%s]%s %s\n",
+			fmt.Fprintf(w, "  %s[%s]%s %s\n",
 				colorGray,
 				note.CreatedAt.Format("2006-01-02 15:04"),
 				colorReset,
@@ -142,6 +189,4 @@ func printTaskDetail(task *model.Task) error {
 			)
 		}
 	}
-
-	return nil
 }
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jackreid/task/internal/site"
+)
+
+// defaultSiteDir is where task site writes when --out is not given
+const defaultSiteDir = "_site"
+
+func runSite(args []string) error {
+	fs := flag.NewFlagSet("site", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var out, title string
+	var filters filterFlags
+
+	fs.StringVar(&out, "out", defaultSiteDir, "Output directory")
+	fs.StringVar(&out, "o", defaultSiteDir, "Output directory")
+	fs.StringVar(&title, "title", "", "Site title (default: project directory name)")
+	filters.register(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Generate a static HTML site of the tasks.
+
+The site has an index with filterable board and list views, a page per task
+and per label, and the tasks as JSON. It needs no server, so the output
+directory can be published on any static host.
+
+Usage:
+  task site [flags]
+
+Flags:
+  -o, --out string    Output directory (default "_site")
+  --title string      Site title (default: project directory name)
+  -l, --label string  Only include tasks with this label
+  -t, --type string   Only include tasks of this type
+  -s, --status string Only include tasks with this status
+  --archived          Include archived tasks instead of live tasks
+  --all               Include both live and archived tasks
+
+Examples:
+  task site
+  task site --out public --title "Release 2.0" --all`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter, err := filters.filter()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	s := getStore()
+	tasks, err := s.ListFiltered(filter)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	if title == "" {
+		title = projectName()
+	}
+
+	dir := out
+	if !filepath.IsAbs(dir) && workDir != "" {
+		dir = filepath.Join(workDir, dir)
+	}
+
+	n, err := site.Generate(dir, tasks, site.Options{Title: title})
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Generated %d file(s) for %d task(s) in %s\n", n, len(tasks), out)
+	return nil
+}
+
+// projectName returns the name of the project directory
+func projectName() string {
+	dir, err := filepath.Abs(filepath.Dir(getStore().Dir()))
+	if err != nil {
+		return "Tasks"
+	}
+	return filepath.Base(dir)
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// Output formats accepted by --format
+const (
+	formatPretty = "pretty"
+	formatTable  = "table"
+	formatJSON   = "json"
+	// formatTemplate is selected by --template rather than --format
+	formatTemplate = "template"
+)
+
+// columnGap is the spacing between table columns
+const columnGap = "  "
+
+// minTitleWidth is the narrowest the title column is squeezed to
+const minTitleWidth = 10
+
+// tableColumn describes one selectable column of table output
+type tableColumn struct {
+	Header string
+	// Value returns the plain cell text for a task
+	Value func(t model.Task, now time.Time) string
+	// Color returns the ANSI color for a cell, or "" for none
+	Color func(t model.Task) string
+	// Flex marks the column that shrinks to fit the terminal width
+	Flex bool
+}
+
+func gray(model.Task) string { return colorGray }
+
+// tableColumns are the columns available to --columns, keyed by name
+var tableColumns = map[string]tableColumn{
+	"id": {
+		Header: "ID",
+		Value:  func(t model.Task, _ time.Time) string { return t.ID },
+		Color:  func(model.Task) string { return colorCyan },
+	},
+	"status": {
+		Header: "STATUS",
+		Value: func(t model.Task, _ time.Time) string {
+			return statusSymbol(t.Status) + " " + string(t.Status)
+		},
+		Color: func(t model.Task) string { return getStatusColor(t.Status) },
+	},
+	"type": {
+		Header: "TYPE",
+		Value: func(t model.Task, _ time.Time) string {
+			return getTypeIcon(t.Type) + " " + string(t.Type)
+		},
+	},
+	"title": {
+		Header: "TITLE",
+		Value:  func(t model.Task, _ time.Time) string { return t.Title },
+		Flex:   true,
+	},
+	"labels": {
+		Header: "LABELS",
+		Value:  func(t model.Task, _ time.Time) string { return strings.Join(t.Labels, ", ") },
+		Color:  gray,
+	},
+	"notes": {
+		Header: "NOTES",
+		Value: func(t model.Task, _ time.Time) string {
+			if len(t.Notes) == 0 {
+				return ""
+			}
+			return fmt.Sprintf("%d", len(t.Notes))
+		},
+		Color: gray,
+	},
+	"created": {
+		Header: "CREATED",
+		Value:  func(t model.Task, now time.Time) string { return formatRelative(t.CreatedAt, now) },
+		Color:  gray,
+	},
+	"updated": {
+		Header: "UPDATED",
+		Value:  func(t model.Task, now time.Time) string { return formatRelative(t.UpdatedAt, now) },
+		Color:  gray,
+	},
+}
+
+// parseColumns parses a comma separated list of column names
+func parseColumns(spec []string) ([]tableColumn, error) {
+	var columns []tableColumn
+	for _, name := range spec {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		col, ok := tableColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column: %s (available: %s)", name, strings.Join(columnNames(), ", "))
+		}
+		columns = append(columns, col)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns given")
+	}
+	return columns, nil
+}
+
+// columnNames returns the names of all available columns, sorted
+func columnNames() []string {
+	names := make([]string, 0, len(tableColumns))
+	for name := range tableColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formatRelative formats t relative to now, e.g. "3d ago"
+func formatRelative(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	case d < 14*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	case d < 60*24*time.Hour:
+		return fmt.Sprintf("%dw ago", int(d/(7*24*time.Hour)))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d/(30*24*time.Hour)))
+	default:
+		return fmt.Sprintf("%dy ago", int(d/(365*24*time.Hour)))
+	}
+}
+
+// printTasksTable prints tasks as aligned columns fitted to width cells.
+// When sourceHeader is set, each task's source is shown as the first column
+func printTasksTable(entries []sourcedTask, columns []tableColumn, width int, sourceHeader string) error {
+	if len(entries) == 0 {
+		fmt.Fprintln(stdout, "No tasks found.")
+		return nil
+	}
+
+	if sourceHeader != "" {
+		source := tableColumn{Header: sourceHeader, Color: func(model.Task) string { return colorMagenta }}
+		columns = append([]tableColumn{source}, columns...)
+	}
+
+	now := time.Now()
+	cells := make([][]string, len(entries))
+	for r, e := range entries {
+		cells[r] = make([]string, len(columns))
+		for c, col := range columns {
+			if col.Value == nil {
+				cells[r][c] = e.Source
+			} else {
+				cells[r][c] = col.Value(e.Task, now)
+			}
+		}
+	}
+
+	widths := make([]int, len(columns))
+	for c, col := range columns {
+		widths[c] = visibleWidth(col.Header)
+		for r := range cells {
+			if w := visibleWidth(cells[r][c]); w > widths[c] {
+				widths[c] = w
+			}
+		}
+	}
+	fitFlexColumn(columns, widths, width)
+
+	header := make([]string, len(columns))
+	for c, col := range columns {
+		header[c] = padRight(col.Header, widths[c])
+	}
+	fmt.Fprintln(stdout, ansiBold+strings.TrimRight(strings.Join(header, columnGap), " ")+colorReset)
+
+	for r, e := range entries {
+		parts := make([]string, len(columns))
+		for c, col := range columns {
+			cell := padRight(truncateText(cells[r][c], widths[c]), widths[c])
+			if col.Color != nil {
+				cell = col.Color(e.Task) + cell + colorReset
+			}
+			parts[c] = cell
+		}
+		fmt.Fprintln(stdout, strings.TrimRight(strings.Join(parts, columnGap), " "))
+	}
+	return nil
+}
+
+// fitFlexColumn shrinks the flexible column so the table fits within width,
+// but never below minTitleWidth
+func fitFlexColumn(columns []tableColumn, widths []int, width int) {
+	total := visibleWidth(columnGap) * (len(columns) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	if total <= width {
+		return
+	}
+
+	for c, col := range columns {
+		if !col.Flex {
+			continue
+		}
+		fit := widths[c] - (total - width)
+		if fit < minTitleWidth {
+			fit = minTitleWidth
+		}
+		if fit < widths[c] {
+			widths[c] = fit
+		}
+		return
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// templatesDir is the directory within .task holding named templates
+const templatesDir = "templates"
+
+// templateExt is the file extension of named templates
+const templateExt = ".tmpl"
+
+// templateNamePattern matches --template values that refer to a named template
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// templateData is the value a template is executed with. Task fields are
+// available directly, e.g. {{.ID}}, alongside the task's collection
+type templateData struct {
+	model.Task
+	Collection string
+}
+
+// templateFuncs are the helper functions available to templates
+var templateFuncs = template.FuncMap{
+	"date": func(layout string, t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Local().Format(layout)
+	},
+	"ago": func(t time.Time) string {
+		return formatRelative(t, time.Now())
+	},
+	"join": func(sep string, items []string) string {
+		return strings.Join(items, sep)
+	},
+	"truncate": func(width int, s string) string {
+		return truncateText(s, width)
+	},
+	"pad": func(width int, s string) string {
+		return padRight(s, width)
+	},
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+	"symbol": statusSymbol,
+	"icon":   getTypeIcon,
+	"colorStatus": func(s model.Status) string {
+		return getStatusColor(s) + string(s) + colorReset
+	},
+	"color": func(name, s string) string {
+		c := colorByName(name)
+		if c == "" {
+			return s
+		}
+		return c + s + colorReset
+	},
+}
+
+// loadTemplate parses the --template value. A value naming a file in
+// .task/templates/ (with or without the .tmpl extension) uses that file,
+// anything else is parsed as the template text itself, with \t and \n
+// escapes outside actions unescaped since shells pass them through literally
+func loadTemplate(value string) (*template.Template, error) {
+	text := value
+	if templateNamePattern.MatchString(value) {
+		named, err := readNamedTemplate(value)
+		if err != nil {
+			return nil, err
+		}
+		text = named
+	} else {
+		text = unescapeTemplate(value)
+	}
+
+	tmpl, err := template.New("task").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateEscapes unescapes the escapes allowed in inline template text
+var templateEscapes = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n")
+
+// unescapeTemplate unescapes the text between the actions of an inline
+// template, leaving the actions themselves for the template parser
+func unescapeTemplate(text string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			b.WriteString(templateEscapes.Replace(text))
+			return b.String()
+		}
+		b.WriteString(templateEscapes.Replace(text[:start]))
+		end := strings.Index(text[start:], "}}")
+		if end < 0 {
+			b.WriteString(text[start:])
+			return b.String()
+		}
+		end += start + len("}}")
+		b.WriteString(text[start:end])
+		text = text[end:]
+	}
+}
+
+// readNamedTemplate reads a template from .task/templates/
+func readNamedTemplate(name string) (string, error) {
+	dir := filepath.Join(getStore().Dir(), templatesDir)
+	for _, path := range []string{filepath.Join(dir, name+templateExt), filepath.Join(dir, name)} {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return string(data), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("reading template: %w", err)
+		}
+	}
+	return "", fmt.Errorf("template not found: %s (looked in %s)", name, dir)
+}
+
+// printTemplate executes tmpl once per entry, ending each output with a
+// newline unless the template already did
+func printTemplate(w io.Writer, tmpl *template.Template, entries []sourcedTask) error {
+	for _, e := range entries {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, templateData{Task: e.Task, Collection: e.Source}); err != nil {
+			return fmt.Errorf("executing template: %w", err)
+		}
+		out := b.String()
+		if !strings.HasSuffix(out, "\n") {
+			out += "\n"
+		}
+		fmt.Fprint(w, out)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI sequences for full-screen terminal output
+const (
+	ansiClearScreen = "\033[2J\033[H"
+	ansiHideCursor  = "\033[?25l"
+	ansiShowCursor  = "\033[?25h"
+	ansiAltScreen   = "\033[?1049h"
+	ansiMainScreen  = "\033[?1049l"
+	ansiReverse     = "\033[7m"
+	ansiBold        = "\033[1m"
+)
+
+// Default terminal size when it cannot be detected
+const (
+	defaultTermWidth  = 80
+	defaultTermHeight = 24
+)
+
+// ansiPattern matches ANSI escape sequences
+var ansiPattern = regexp.MustCompile("\033\\[[0-9;?]*[A-Za-z]")
+
+// terminalSize returns the terminal width and height. $COLUMNS/$LINES take
+// precedence, then the controlling terminal is asked via stty, and finally 80x24
+func terminalSize() (int, int) {
+	width, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	height, _ := strconv.Atoi(os.Getenv("LINES"))
+
+	if width <= 0 || height <= 0 {
+		if tty, err := os.Open("/dev/tty"); err == nil {
+			var rows, cols int
+			cmd := exec.Command("stty", "size")
+			cmd.Stdin = tty
+			if out, err := cmd.Output(); err == nil {
+				fmt.Sscanf(string(out), "%d %d", &rows, &cols)
+			}
+			tty.Close()
+			if width <= 0 {
+				width = cols
+			}
+			if height <= 0 {
+				height = rows
+			}
+		}
+	}
+
+	if width <= 0 {
+		width = defaultTermWidth
+	}
+	if height <= 0 {
+		height = defaultTermHeight
+	}
+	return width, height
+}
+
+// openRawTTY opens the controlling terminal and switches it to raw mode so
+// single key presses can be read. The returned function restores the
+// terminal's previous settings and closes it
+func openRawTTY() (*os.File, func(), error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening terminal: %w", err)
+	}
+
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = tty
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+
+	saved, err := stty("-g")
+	if err != nil {
+		tty.Close()
+		return nil, nil, fmt.Errorf("reading terminal settings: %w", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		tty.Close()
+		return nil, nil, fmt.Errorf("setting raw mode: %w", err)
+	}
+
+	restore := func() {
+		stty(saved)
+		tty.Close()
+	}
+	return tty, restore, nil
+}
+
+// runeWidth returns the number of terminal cells used to display r
+func runeWidth(r rune) int {
+	switch {
+	case r == 0x2728, // ✨
+		r >= 0x1100 && r <= 0x115F,
+		r >= 0x2E80 && r <= 0xA4CF,
+		r >= 0xAC00 && r <= 0xD7A3,
+		r >= 0xF900 && r <= 0xFAFF,
+		r >= 0xFE30 && r <= 0xFE4F,
+		r >= 0xFF00 && r <= 0xFF60,
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1FAFF:
+		return 2
+	}
+	return 1
+}
+
+// visibleWidth returns the display width of s, ignoring ANSI escape sequences
+func visibleWidth(s string) int {
+	width := 0
+	for _, r := range ansiPattern.ReplaceAllString(s, "") {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// truncateText shortens plain text to at most width cells, ending with an
+// ellipsis when anything was cut
+func truncateText(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if visibleWidth(s) <= width {
+		return s
+	}
+
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := runeWidth(r)
+		if used+w > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	b.WriteString("…")
+	return b.String()
+}
+
+// truncateVisible cuts s to at most width display cells while keeping any
+// ANSI escape sequences intact, so colored lines never wrap
+func truncateVisible(s string, width int) string {
+	if visibleWidth(s) <= width {
+		return s
+	}
+
+	var b strings.Builder
+	used := 0
+	for i := 0; i < len(s); {
+		if loc := ansiPattern.FindStringIndex(s[i:]); loc != nil && loc[0] == 0 {
+			b.WriteString(s[i : i+loc[1]])
+			i += loc[1]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := runeWidth(r)
+		if used+w > width {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+		i += size
+	}
+	b.WriteString(colorReset)
+	return b.String()
+}
+
+// padRight pads s with spaces to width display cells
+func padRight(s string, width int) string {
+	if pad := width - visibleWidth(s); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
+}
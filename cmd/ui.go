@@ -0,0 +1,502 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackreid/task/internal/id"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+// uiMode is the current screen or input mode of the terminal UI
+type uiMode int
+
+const (
+	uiModeBoard uiMode = iota
+	uiModeDetail
+	uiModeNote
+	uiModeFilter
+)
+
+// uiState holds everything the terminal UI displays and is updated by key
+// presses. It is kept free of terminal I/O so it can be driven in tests
+type uiState struct {
+	store      *store.Store
+	filterText string
+	filter     store.Filter
+	columns    []boardColumn
+	cursor     boardCursor
+	mode       uiMode
+	returnMode uiMode
+	input      string
+	message    string
+	modTime    time.Time
+	quit       bool
+}
+
+func runUI(args []string) error {
+	fs := flag.NewFlagSet("ui", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var where string
+	var interval time.Duration
+
+	fs.StringVar(&where, "where", "", "Initial filter, e.g. \"label=frontend,type=bug\"")
+	fs.DurationVar(&interval, "interval", 500*time.Millisecond, "How often to check the task file for changes")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Open a full-screen kanban board of tasks.
+
+The board reloads automatically when the task file changes on disk.
+
+Usage:
+  task ui [flags]
+
+Flags:
+  --where string       Initial filter, e.g. "label=frontend,type=bug"
+  --interval duration  How often to check for changes (default 500ms)
+
+Keys:
+  ←/→ h/l    Select column        ↑/↓ k/j   Select task
+  H/L < >    Move task to the previous/next column
+  enter      Show task details    esc       Back to the board
+  n          Add a note           /         Filter (status=, type=, label=, text=)
+  r          Reload               q         Quit`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	state := newUIState(getStore())
+	if where != "" {
+		if err := state.setFilter(where); err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+	}
+	if err := state.reload(); err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	// Send webhooks as changes are made rather than when the UI is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sendWebhooksInBackground(ctx)
+
+	tty, restore, err := openRawTTY()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	defer restore()
+
+	fmt.Fprint(tty, ansiAltScreen+ansiHideCursor)
+	defer fmt.Fprint(tty, ansiShowCursor+ansiMainScreen)
+
+	keys := make(chan string)
+	go readKeys(tty, keys)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		width, height := terminalSize()
+		fmt.Fprint(tty, ansiClearScreen+strings.Join(state.render(width, height), "\r\n"))
+
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			state.handleKey(key)
+			if state.quit {
+				return nil
+			}
+		case <-ticker.C:
+			if !state.changedOnDisk() {
+				continue
+			}
+			if err := state.reload(); err != nil {
+				state.message = err.Error()
+			}
+		}
+	}
+}
+
+// newUIState creates the UI state for a store
+func newUIState(s *store.Store) *uiState {
+	return &uiState{store: s, columns: groupByStatus(nil, boardStatuses)}
+}
+
+// selected returns the task under the cursor, or nil if the column is empty
+func (u *uiState) selected() *model.Task {
+	if u.cursor.Column >= len(u.columns) {
+		return nil
+	}
+	tasks := u.columns[u.cursor.Column].Tasks
+	if u.cursor.Row < 0 || u.cursor.Row >= len(tasks) {
+		return nil
+	}
+	return &tasks[u.cursor.Row]
+}
+
+// reload reads the tasks from disk, keeping the cursor on the same task when possible
+func (u *uiState) reload() error {
+	selectedID := ""
+	if t := u.selected(); t != nil {
+		selectedID = t.ID
+	}
+
+	if modTime, err := u.store.ModTime(); err == nil {
+		u.modTime = modTime
+	}
+
+	tasks, err := u.store.ListFiltered(u.filter)
+	if err != nil {
+		return err
+	}
+	u.columns = groupByStatus(tasks, boardStatuses)
+
+	if selectedID != "" && u.selectID(selectedID) {
+		return nil
+	}
+	u.clampCursor()
+	return nil
+}
+
+// changedOnDisk reports whether the task file was modified since the last reload
+func (u *uiState) changedOnDisk() bool {
+	modTime, err := u.store.ModTime()
+	return err == nil && !modTime.Equal(u.modTime)
+}
+
+// selectID moves the cursor to the task with the given ID
+func (u *uiState) selectID(taskID string) bool {
+	for c, col := range u.columns {
+		for r, t := range col.Tasks {
+			if t.ID == taskID {
+				u.cursor = boardCursor{Column: c, Row: r}
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// clampCursor keeps the cursor within the current column
+func (u *uiState) clampCursor() {
+	if u.cursor.Column < 0 {
+		u.cursor.Column = 0
+	}
+	if u.cursor.Column >= len(u.columns) {
+		u.cursor.Column = len(u.columns) - 1
+	}
+	n := len(u.columns[u.cursor.Column].Tasks)
+	if u.cursor.Row >= n {
+		u.cursor.Row = n - 1
+	}
+	if u.cursor.Row < 0 {
+		u.cursor.Row = 0
+	}
+}
+
+// setFilter parses and applies a --where style filter; an empty string clears it
+func (u *uiState) setFilter(text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		u.filterText = ""
+		u.filter = store.Filter{}
+		return nil
+	}
+
+	filter, err := parseWhere(text)
+	if err != nil {
+		return err
+	}
+	u.filterText = text
+	u.filter = filter
+	return nil
+}
+
+// handleKey applies a decoded key press to the state
+func (u *uiState) handleKey(key string) {
+	if key == "ctrl+c" {
+		u.quit = true
+		return
+	}
+
+	switch u.mode {
+	case uiModeNote, uiModeFilter:
+		u.handleInputKey(key)
+	case uiModeDetail:
+		switch key {
+		case "esc", "q", "enter", "backspace":
+			u.mode = uiModeBoard
+		case "n":
+			u.startInput(uiModeNote, "")
+		case "H", "<":
+			u.moveSelected(-1)
+		case "L", ">":
+			u.moveSelected(1)
+		}
+	default:
+		u.message = ""
+		switch key {
+		case "q":
+			u.quit = true
+		case "left", "h":
+			u.cursor.Column--
+			u.clampCursor()
+		case "right", "l":
+			u.cursor.Column++
+			u.clampCursor()
+		case "up", "k":
+			u.cursor.Row--
+			u.clampCursor()
+		case "down", "j":
+			u.cursor.Row++
+			u.clampCursor()
+		case "H", "<":
+			u.moveSelected(-1)
+		case "L", ">":
+			u.moveSelected(1)
+		case "enter":
+			if u.selected() != nil {
+				u.mode = uiModeDetail
+			}
+		case "n":
+			if u.selected() != nil {
+				u.startInput(uiModeNote, "")
+			}
+		case "/":
+			u.startInput(uiModeFilter, u.filterText)
+		case "r":
+			if err := u.reload(); err != nil {
+				u.message = err.Error()
+			}
+		}
+	}
+}
+
+// startInput switches to a text input mode, returning to the current mode afterwards
+func (u *uiState) startInput(mode uiMode, initial string) {
+	u.returnMode = u.mode
+	u.mode = mode
+	u.input = initial
+}
+
+// handleInputKey edits the input line and commits or cancels it
+func (u *uiState) handleInputKey(key string) {
+	switch key {
+	case "esc":
+		u.mode = u.returnMode
+	case "backspace":
+		if u.input != "" {
+			_, size := utf8.DecodeLastRuneInString(u.input)
+			u.input = u.input[:len(u.input)-size]
+		}
+	case "enter":
+		mode := u.mode
+		u.mode = u.returnMode
+		if mode == uiModeNote {
+			u.addNote(u.input)
+		} else {
+			u.applyFilter(u.input)
+		}
+	default:
+		if utf8.RuneCountInString(key) == 1 {
+			u.input += key
+		}
+	}
+}
+
+// applyFilter sets the filter from user input and reloads
+func (u *uiState) applyFilter(text string) {
+	if err := u.setFilter(text); err != nil {
+		u.message = err.Error()
+		return
+	}
+	if err := u.reload(); err != nil {
+		u.message = err.Error()
+	}
+}
+
+// addNote appends a note to the selected task and saves it
+func (u *uiState) addNote(content string) {
+	content = strings.TrimSpace(content)
+	t := u.selected()
+	if t == nil || content == "" {
+		return
+	}
+
+	noteID, err := id.GenerateNoteID(t.ID)
+	if err != nil {
+		u.message = err.Error()
+		return
+	}
+	task := *t
+	task.AddNote(noteID, content)
+	if err := u.store.Update(&task); err != nil {
+		u.message = err.Error()
+		return
+	}
+
+	u.message = "Added note to task " + task.ID
+	if err := u.reload(); err != nil {
+		u.message = err.Error()
+	}
+}
+
+// moveSelected moves the selected task delta columns left or right, changing its status
+func (u *uiState) moveSelected(delta int) {
+	t := u.selected()
+	target := u.cursor.Column + delta
+	if t == nil || target < 0 || target >= len(u.columns) {
+		return
+	}
+
+	task := *t
+	status := u.columns[target].Status
+	if err := task.SetStatus(status); err != nil {
+		u.message = err.Error()
+		return
+	}
+	if err := u.store.Update(&task); err != nil {
+		u.message = err.Error()
+		return
+	}
+
+	u.message = fmt.Sprintf("Updated task %s to %s", task.ID, status)
+	if err := u.reload(); err != nil {
+		u.message = err.Error()
+		return
+	}
+	u.selectID(task.ID)
+}
+
+// render draws the current screen as lines of at most width cells
+func (u *uiState) render(width, height int) []string {
+	header := ansiBold + "task ui" + colorReset
+	if u.store.Collection() != store.DefaultCollection {
+		header += colorMagenta + " [" + u.store.Collection() + "]" + colorReset
+	}
+	if u.filterText != "" {
+		header += colorGray + "  filter: " + u.filterText + colorReset
+	}
+	if u.message != "" {
+		header += "  " + colorYellow + u.message + colorReset
+	}
+
+	lines := []string{header, ""}
+	bodyHeight := height - 4
+
+	if u.mode == uiModeDetail || (u.returnMode == uiModeDetail && u.mode == uiModeNote) {
+		if t := u.selected(); t != nil {
+			var buf bytes.Buffer
+			writeTaskDetail(&buf, t)
+			detail := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+			if len(detail) > bodyHeight {
+				detail = detail[len(detail)-bodyHeight:]
+			}
+			lines = append(lines, detail...)
+		}
+	} else {
+		// Two lines of each column are the header and rule
+		lines = append(lines, renderBoard(u.columns, width, bodyHeight-2, &u.cursor)...)
+	}
+
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+
+	switch u.mode {
+	case uiModeNote:
+		lines = append(lines, "Note: "+u.input+ansiReverse+" "+colorReset)
+	case uiModeFilter:
+		lines = append(lines, "Filter (status=, type=, label=, text=): "+u.input+ansiReverse+" "+colorReset)
+	case uiModeDetail:
+		lines = append(lines, colorGray+"esc back  n note  H/L move  ctrl+c quit"+colorReset)
+	default:
+		lines = append(lines, colorGray+"←/→ column  ↑/↓ task  H/L move  enter details  n note  / filter  r reload  q quit"+colorReset)
+	}
+
+	for i := range lines {
+		lines[i] = truncateVisible(lines[i], width)
+	}
+	return lines
+}
+
+// readKeys reads raw terminal input from r and sends decoded keys until r fails
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		for _, key := range decodeKeys(buf[:n]) {
+			keys <- key
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// decodeKeys converts raw terminal input into key names such as "up",
+// "enter" and "esc", or the typed character itself
+func decodeKeys(data []byte) []string {
+	var keys []string
+	for len(data) > 0 {
+		switch data[0] {
+		case 0x1b:
+			if len(data) >= 3 && data[1] == '[' {
+				// Skip parameters to the sequence's final byte (0x40-0x7e),
+				// so modified arrows like ESC[1;5C still decode
+				i := 2
+				for i < len(data) && (data[i] < 0x40 || data[i] > 0x7e) {
+					i++
+				}
+				if i < len(data) {
+					switch data[i] {
+					case 'A':
+						keys = append(keys, "up")
+					case 'B':
+						keys = append(keys, "down")
+					case 'C':
+						keys = append(keys, "right")
+					case 'D':
+						keys = append(keys, "left")
+					}
+				}
+				data = data[min(i+1, len(data)):]
+				continue
+			}
+			keys = append(keys, "esc")
+			data = data[1:]
+		case '\r', '\n':
+			keys = append(keys, "enter")
+			data = data[1:]
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+			data = data[1:]
+		case 0x03:
+			keys = append(keys, "ctrl+c")
+			data = data[1:]
+		default:
+			r, size := utf8.DecodeRune(data)
+			if r >= 0x20 {
+				keys = append(keys, string(r))
+			}
+			data = data[size:]
+		}
+	}
+	return keys
+}
@@ -3,6 +3,8 @@ package cmd
 import (
 	"flag"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackreid/task/internal/model"
 )
@@ -16,6 +18,8 @@ func runUpdate(args []string) error {
 	var labels labelList
 	var taskType string
 	var status string
+	var due string
+	var bulk bulkFlags
 
 	fs.StringVar(&name, "n", "", "New task name")
 	fs.StringVar(&name, "name", "", "New task name")
@@ -27,89 +31,174 @@ func runUpdate(args []string) error {
 	fs.StringVar(&taskType, "type", "", "Task type (task, bug, feature)")
 	fs.StringVar(&status, "s", "", "Task status (todo, progress, blocked, abandon, done)")
 	fs.StringVar(&status, "status", "", "Task status (todo, progress, blocked, abandon, done)")
+	fs.StringVar(&due, "due", "", "Due date (YYYY-MM-DD, or none to clear it)")
+	bulk.register(fs)
 
 	fs.Usage = func() {
-		fmt.Fprintln(stderr, `Update an existing task.
+		fmt.Fprintln(stderr, `Update one or more existing tasks.
 
 Usage:
-  task update <id> [flags]
+  task update <id>... [+label...] [flags] [-- -label...]
+  task update --where <filter> [+label...] [flags] [-- -label...]
+
+Labels can be added with +label and removed with -label without replacing
+the rest of the task's labels. Removals go after --, so they are never
+mistaken for flags.
 
 Flags:
   -n, --name string        New task name
   -d, --description string Task description
-  -l, --label string       Label to add (can be specified multiple times)
+  -l, --label string       Replace labels (can be specified multiple times)
   -t, --type string        Task type: task, bug, feature
   -s, --status string      Task status: todo, progress, blocked, abandon, done
+  --due string             Due date (YYYY-MM-DD, or none to clear it)
+`+bulkFlagsUsage+`
 
 Examples:
   task update abc -n "New name"
   task update abc -s done
-  task update abc -l urgent -l priority`)
+  task update abc -l urgent -l priority
+  task update abc def -t bug
+  task update abc --due 2024-07-01
+  task update abc +frontend -- -front-end
+  task update --where "status=blocked,label=api" -s todo --dry-run`)
 	}
 
-	// Reorder args to allow positional arguments before flags
-	reorderedArgs := reorderArgsForFlexibleFlags(args)
-	if err := fs.Parse(reorderedArgs); err != nil {
+	args, addLabels, removeLabels := extractLabelEdits(fs, args)
+	if err := fs.Parse(splitArgs(fs, args)); err != nil {
 		return err
 	}
 
-	if fs.NArg() < 1 {
+	if fs.NArg() < 1 && bulk.where == "" {
 		errorf("Error: task ID is required")
 		fs.Usage()
 		return fmt.Errorf("task ID is required")
 	}
 
-	taskID := fs.Arg(0)
-
-	s := getStore()
-
-	task, err := s.FindByID(taskID)
-	if err != nil {
+	// Validate values once before touching any task
+	if err := projectConfig.ValidateLabels(append(append([]string{}, labels...), addLabels...)); err != nil {
 		errorf("Error: %v", err)
 		return err
 	}
 
-	if task == nil {
-		errorf("Error: task not found: %s", taskID)
-		return fmt.Errorf("task not found: %s", taskID)
-	}
-
-	// Apply updates
-	if name != "" {
-		task.SetTitle(name)
-	}
-
-	if description != "" {
-		task.SetDescription(description)
-	}
-
-	if len(labels) > 0 {
-		task.SetLabels(labels)
-	}
-
+	var tt model.TaskType
 	if taskType != "" {
-		tt, err := model.ParseTaskType(taskType)
+		parsed, err := model.ParseTaskType(taskType)
 		if err != nil {
 			errorf("Error: %v", err)
 			return err
 		}
-		task.SetType(tt)
+		tt = parsed
 	}
 
+	var st model.Status
 	if status != "" {
-		st, err := model.ParseStatus(status)
+		parsed, err := model.ParseStatus(status)
 		if err != nil {
 			errorf("Error: %v", err)
 			return err
 		}
-		task.SetStatus(st)
+		st = parsed
 	}
 
-	if err := s.Update(task); err != nil {
-		errorf("Error: %v", err)
+	var dueDate time.Time
+	if due != "" {
+		parsed, err := parseDue(due)
+		if err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+		dueDate = parsed
+	}
+
+	s := getStore()
+
+	updated, err := applyBulk(s, fs.Args(), bulk, "update", func(task *model.Task) error {
+		if name != "" {
+			task.SetTitle(name)
+		}
+
+		if description != "" {
+			task.SetDescription(description)
+		}
+
+		if len(labels) > 0 {
+			task.SetLabels(labels)
+		}
+
+		for _, label := range addLabels {
+			task.AddLabel(label)
+		}
+
+		for _, label := range removeLabels {
+			task.RemoveLabel(label)
+		}
+
+		if tt != "" {
+			if err := task.SetType(tt); err != nil {
+				return err
+			}
+		}
+
+		if st != "" {
+			if err := task.SetStatus(st); err != nil {
+				return err
+			}
+		}
+
+		if due != "" {
+			task.SetDue(dueDate)
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
-	fmt.Fprintf(stdout, "Updated task %s\n", task.ID)
+	for _, task := range updated {
+		fmt.Fprintf(stdout, "Updated task %s\n", task.ID)
+	}
 	return nil
 }
+
+// extractLabelEdits pulls incremental label changes ("+label" and "-label")
+// out of args. "+label" is accepted anywhere, but "-label" only after a "--"
+// terminator so that help, typos and unknown flags still reach fs.Parse.
+// Values of flags that take an argument are never treated as label edits
+func extractLabelEdits(fs *flag.FlagSet, args []string) (rest, add, remove []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, arg)
+			for _, arg := range args[i+1:] {
+				switch {
+				case len(arg) > 1 && arg[0] == '+':
+					add = append(add, arg[1:])
+				case len(arg) > 1 && arg[0] == '-':
+					remove = append(remove, arg[1:])
+				default:
+					rest = append(rest, arg)
+				}
+			}
+			break
+		}
+		if len(arg) > 1 && arg[0] == '+' {
+			add = append(add, arg[1:])
+			continue
+		}
+		rest = append(rest, arg)
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := fs.Lookup(name); f != nil && !isBoolFlag(f) && i+1 < len(args) {
+			rest = append(rest, args[i+1])
+			i++
+		}
+	}
+	return rest, add, remove
+}
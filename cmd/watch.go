@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/watch"
+)
+
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var jsonOutput bool
+	var interval time.Duration
+	var label, taskType string
+
+	fs.BoolVar(&jsonOutput, "json", false, "Output events as newline-delimited JSON")
+	fs.DurationVar(&interval, "interval", watch.DefaultInterval, "How often to check for changes")
+	fs.StringVar(&label, "l", "", "Only show events for tasks with this label")
+	fs.StringVar(&label, "label", "", "Only show events for tasks with this label")
+	fs.StringVar(&taskType, "t", "", "Only show events for tasks of this type")
+	fs.StringVar(&taskType, "type", "", "Only show events for tasks of this type")
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Watch the tasks and print each change as it happens.
+
+Events are created, updated (with the changed fields), status_changed,
+note_added, deleted and archived. Runs until interrupted.
+
+Usage:
+  task watch [flags]
+
+Flags:
+  --json               Output events as newline-delimited JSON
+  --interval duration  How often to check for changes (default 1s)
+  -l, --label string   Only show events for tasks with this label
+  -t, --type string    Only show events for tasks of this type
+
+Examples:
+  task watch
+  task watch --json | jq 'select(.type == "status_changed")'`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if interval <= 0 {
+		err := errors.New("--interval must be positive")
+		errorf("Error: %v", err)
+		return err
+	}
+	var filterType model.TaskType
+	if taskType != "" {
+		tt, err := model.ParseTaskType(taskType)
+		if err != nil {
+			errorf("Error: %v", err)
+			return err
+		}
+		filterType = tt
+	}
+
+	s := getStore()
+	if !s.IsInitialized() {
+		err := errors.New("task not initialized, run 'task init' first")
+		errorf("Error: %v", err)
+		return err
+	}
+
+	w, err := watch.New(s)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	w.OnError = func(err error) {
+		errorf("Warning: %v, retrying", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if !jsonOutput {
+		fmt.Fprintf(stderr, "Watching for changes (Ctrl+C to stop)\n")
+	}
+
+	err = w.Run(ctx, interval, func(e watch.Event) error {
+		if label != "" && !e.Task.HasLabel(label) {
+			return nil
+		}
+		if filterType != "" && e.Task.Type != filterType {
+			return nil
+		}
+		return printEvent(e, jsonOutput)
+	})
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// printEvent writes an event as a line of JSON or as a human readable line
+func printEvent(e watch.Event, jsonOutput bool) error {
+	if jsonOutput {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(stdout, "%s\n", data)
+		return err
+	}
+
+	detail := ""
+	switch e.Type {
+	case watch.EventUpdated:
+		detail = colorGray + "(" + strings.Join(e.Fields, ", ") + ")" + colorReset
+	case watch.EventStatusChanged:
+		detail = getStatusColor(e.From) + string(e.From) + colorReset + " → " + getStatusColor(e.To) + string(e.To) + colorReset
+	case watch.EventNoteAdded:
+		first, _, _ := strings.Cut(e.Note.Content, "\n")
+		detail = colorGray + truncateText(first, 60) + colorReset
+	}
+
+	line := fmt.Sprintf("%s%s%s %-14s %s%s%s %s",
+		colorGray, e.Time.Local().Format("15:04:05"), colorReset,
+		e.Type, colorCyan, e.ID, colorReset, e.Task.Title)
+	if detail != "" {
+		line += "  " + detail
+	}
+	_, err := fmt.Fprintln(stdout, line)
+	return err
+}
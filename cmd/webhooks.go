@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/store"
+	"github.com/jackreid/task/internal/webhook"
+)
+
+func runWebhooks(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "flush":
+			return runWebhooksFlush(args[1:])
+		case "clear":
+			return runWebhooksClear(args[1:])
+		case "list":
+			args = args[1:]
+		}
+	}
+	return runWebhooksList(args)
+}
+
+// newDispatcher returns a webhook dispatcher for the project's outbox
+func newDispatcher() *webhook.Dispatcher {
+	return webhook.New(store.New(workDir).Dir(), projectConfig.Webhooks, stderr)
+}
+
+// commandDispatcher returns the dispatcher for the current command's changes
+func commandDispatcher() *webhook.Dispatcher {
+	if dispatcher == nil {
+		dispatcher = newDispatcher()
+	}
+	return dispatcher
+}
+
+func runWebhooksList(args []string) error {
+	fs := flag.NewFlagSet("webhooks", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `List the configured webhooks and deliveries waiting to be retried.
+
+Webhooks are configured in .task/config and are sent a JSON payload for every
+task change. Deliveries that fail are kept in .task/webhooks.outbox and
+retried by later task commands, backing off up to an hour between attempts.
+
+Usage:
+  task webhooks
+  task webhooks flush
+  task webhooks clear
+
+Subcommands:
+  flush  Retry every pending delivery now
+  clear  Discard every pending delivery`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		errorf("Error: unknown webhooks subcommand: %s", fs.Arg(0))
+		fs.Usage()
+		return fmt.Errorf("unknown webhooks subcommand: %s", fs.Arg(0))
+	}
+
+	if len(projectConfig.Webhooks) == 0 {
+		fmt.Fprintln(stdout, "No webhooks configured in .task/config")
+	}
+	for _, w := range projectConfig.Webhooks {
+		events := "all events"
+		if len(w.Events) > 0 {
+			events = strings.Join(w.Events, ", ")
+		}
+		signed := ""
+		if w.Secret != "" || w.SecretEnv != "" {
+			signed = ", signed"
+		}
+		fmt.Fprintf(stdout, "%s %s(%s%s)%s\n", w.URL, colorGray, events, signed, colorReset)
+	}
+
+	pending, err := newDispatcher().Pending()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "\n%d pending delivery(s):\n", len(pending))
+	now := time.Now()
+	for _, d := range pending {
+		retry := "next attempt at " + d.NextAttempt.Local().Format("15:04:05")
+		if !d.NextAttempt.After(now) {
+			retry = "due"
+		}
+		if d.Attempts >= webhook.MaxAttempts {
+			retry = "gave up, use 'task webhooks flush'"
+		}
+		fmt.Fprintf(stdout, "  %s%s%s %s %s %s(%d attempt(s), %s)%s\n",
+			colorCyan, d.ID, colorReset, d.Event, d.URL, colorGray, d.Attempts, retry, colorReset)
+		if d.LastError != "" {
+			fmt.Fprintf(stdout, "    %s%s%s\n", colorRed, d.LastError, colorReset)
+		}
+	}
+	return nil
+}
+
+func runWebhooksFlush(args []string) error {
+	fs := flag.NewFlagSet("webhooks flush", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Retry every pending webhook delivery now, ignoring backoff.
+
+Usage:
+  task webhooks flush`)
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	result, err := newDispatcher().Flush(true)
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Delivered %d, failed %d", result.Delivered, result.Failed)
+	if result.Dropped > 0 {
+		fmt.Fprintf(stdout, ", dropped %d for webhooks no longer configured", result.Dropped)
+	}
+	fmt.Fprintln(stdout)
+
+	if result.Failed > 0 {
+		err := fmt.Errorf("%d delivery(s) failed", result.Failed)
+		errorf("Error: %v (see 'task webhooks')", err)
+		return err
+	}
+	return nil
+}
+
+func runWebhooksClear(args []string) error {
+	fs := flag.NewFlagSet("webhooks clear", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Discard every pending webhook delivery.
+
+Usage:
+  task webhooks clear`)
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	n, err := newDispatcher().Clear()
+	if err != nil {
+		errorf("Error: %v", err)
+		return err
+	}
+	fmt.Fprintf(stdout, "Discarded %d pending delivery(s)\n", n)
+	return nil
+}
+
+// sendWebhooks sends the changes the command made, along with deliveries
+// left over from earlier commands that are due for another attempt
+func sendWebhooks() {
+	if len(projectConfig.Webhooks) == 0 {
+		return
+	}
+	commandDispatcher().Send()
+}
+
+// sendWebhooksInBackground sends changes as they are made until ctx is done,
+// for commands that run until stopped such as serve, mcp and ui
+func sendWebhooksInBackground(ctx context.Context) {
+	if len(projectConfig.Webhooks) == 0 {
+		return
+	}
+	go commandDispatcher().Run(ctx)
+}
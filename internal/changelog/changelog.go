@@ -0,0 +1,188 @@
+package changelog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// Unreleased is the version of a release that hasn't been tagged yet
+const Unreleased = "Unreleased"
+
+// header starts a new changelog file
+const header = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).
+`
+
+// Release is the completed tasks going into a version
+type Release struct {
+	Version  string
+	Date     time.Time
+	Sections []Section
+}
+
+// Section is a group of tasks of a kind, such as bug fixes
+type Section struct {
+	Title string
+	Tasks []model.Task
+}
+
+// sections maps task types to the section they are listed in, in order.
+// Types not listed go in Other
+var sections = []struct {
+	title string
+	types []model.TaskType
+}{
+	{"Features", []model.TaskType{model.TypeFeature}},
+	{"Bug fixes", []model.TaskType{model.TypeBug}},
+}
+
+// DoneAt returns when a task was completed: when its status last changed, or
+// when it was last updated for tasks completed before that was recorded
+func DoneAt(t model.Task) time.Time {
+	if !t.StatusChangedAt.IsZero() {
+		return t.StatusChangedAt
+	}
+	return t.UpdatedAt
+}
+
+// New builds a release from the tasks that were completed after since and no
+// later than until. A zero since includes everything before until
+func New(version string, date time.Time, tasks []model.Task, since, until time.Time) Release {
+	var done []model.Task
+	for _, t := range tasks {
+		if t.Status != model.StatusDone {
+			continue
+		}
+		at := DoneAt(t)
+		if at.After(since) && !at.After(until) {
+			done = append(done, t)
+		}
+	}
+	sort.SliceStable(done, func(i, j int) bool {
+		return DoneAt(done[i]).Before(DoneAt(done[j]))
+	})
+
+	r := Release{Version: version, Date: date}
+	used := make(map[string]bool)
+	for _, s := range sections {
+		section := Section{Title: s.title}
+		for _, t := range done {
+			if containsType(s.types, t.Type) {
+				section.Tasks = append(section.Tasks, t)
+				used[t.ID] = true
+			}
+		}
+		if len(section.Tasks) > 0 {
+			r.Sections = append(r.Sections, section)
+		}
+	}
+
+	other := Section{Title: "Other"}
+	for _, t := range done {
+		if !used[t.ID] {
+			other.Tasks = append(other.Tasks, t)
+		}
+	}
+	if len(other.Tasks) > 0 {
+		r.Sections = append(r.Sections, other)
+	}
+	return r
+}
+
+// Empty reports whether the release has no tasks
+func (r Release) Empty() bool {
+	return len(r.Sections) == 0
+}
+
+// Heading returns the release's Keep a Changelog heading, such as
+// "## [1.2.0] - 2024-05-01", or "## [Unreleased]"
+func (r Release) Heading() string {
+	if r.Version == Unreleased || r.Date.IsZero() {
+		return fmt.Sprintf("## [%s]", r.Version)
+	}
+	return fmt.Sprintf("## [%s] - %s", r.Version, r.Date.Format("2006-01-02"))
+}
+
+// Markdown renders the release as a Keep a Changelog entry
+func (r Release) Markdown() string {
+	var b strings.Builder
+	b.WriteString(r.Heading())
+	b.WriteString("\n")
+	for _, s := range r.Sections {
+		fmt.Fprintf(&b, "\n### %s\n\n", s.Title)
+		for _, t := range s.Tasks {
+			fmt.Fprintf(&b, "- %s (%s)\n", t.Title, t.ID)
+		}
+	}
+	return b.String()
+}
+
+// Prepend adds an entry to a changelog above its latest release, keeping the
+// file's header. A changelog that doesn't exist yet (empty existing) gets the
+// standard Keep a Changelog header. An existing entry with the same heading,
+// typically [Unreleased], is replaced
+func Prepend(existing, entry string) string {
+	entry = strings.TrimRight(entry, "\n") + "\n"
+	if strings.TrimSpace(existing) == "" {
+		return header + "\n" + entry
+	}
+
+	heading, _, _ := strings.Cut(entry, "\n")
+	lines := strings.SplitAfter(existing, "\n")
+
+	// The entry goes before the first release heading, or at the end
+	insert := len(lines)
+	for i, line := range lines {
+		if strings.HasPrefix(line, "## ") {
+			insert = i
+			break
+		}
+	}
+
+	// Drop a previous entry with the same heading
+	end := insert
+	if insert < len(lines) && strings.TrimRight(lines[insert], "\r\n") == heading {
+		end = insert + 1
+		for end < len(lines) && !strings.HasPrefix(lines[end], "## ") && !isLinkReference(lines[end]) {
+			end++
+		}
+	}
+
+	var b strings.Builder
+	before := strings.Join(lines[:insert], "")
+	b.WriteString(before)
+	if before != "" && !strings.HasSuffix(before, "\n\n") {
+		if !strings.HasSuffix(before, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(entry)
+	if end < len(lines) {
+		b.WriteString("\n")
+		b.WriteString(strings.Join(lines[end:], ""))
+	}
+	return b.String()
+}
+
+// isLinkReference reports whether a line is a Markdown link reference such as
+// "[1.0.0]: https://...", which Keep a Changelog files end with
+func isLinkReference(line string) bool {
+	return strings.HasPrefix(line, "[") && strings.Contains(line, "]: ")
+}
+
+func containsType(types []model.TaskType, t model.TaskType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
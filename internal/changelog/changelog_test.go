@@ -0,0 +1,90 @@
+package changelog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// doneTask returns a task completed at the given time
+func doneTask(id, title string, taskType model.TaskType, at time.Time) model.Task {
+	t := model.NewTask(id, title, taskType)
+	t.Status = model.StatusDone
+	t.StatusChangedAt = at
+	t.UpdatedAt = at
+	return *t
+}
+
+func TestNew(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	legacy := doneTask("leg", "Completed before status times", model.TypeTask, time.Time{})
+	legacy.UpdatedAt = base.Add(2 * time.Hour)
+	edited := doneTask("edt", "Edited after completion", model.TypeFeature, base.Add(-time.Hour))
+	edited.UpdatedAt = base.Add(time.Hour)
+	open := model.NewTask("opn", "Still open", model.TypeFeature)
+
+	tasks := []model.Task{
+		doneTask("fb2", "Second feature", model.TypeFeature, base.Add(3*time.Hour)),
+		doneTask("bug", "Crash on start", model.TypeBug, base.Add(time.Hour)),
+		doneTask("fb1", "First feature", model.TypeFeature, base.Add(time.Hour)),
+		doneTask("old", "Before the range", model.TypeBug, base.Add(-time.Hour)),
+		doneTask("new", "After the range", model.TypeBug, base.Add(48*time.Hour)),
+		legacy,
+		edited,
+		*open,
+	}
+
+	r := New("1.2.0", base, tasks, base, base.Add(24*time.Hour))
+	want := `## [1.2.0] - 2024-05-01
+
+### Features
+
+- First feature (fb1)
+- Second feature (fb2)
+
+### Bug fixes
+
+- Crash on start (bug)
+
+### Other
+
+- Completed before status times (leg)
+`
+	if got := r.Markdown(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+
+	if empty := New(Unreleased, time.Time{}, nil, time.Time{}, base); !empty.Empty() || empty.Markdown() != "## [Unreleased]\n" {
+		t.Errorf("empty release = %q", empty.Markdown())
+	}
+}
+
+func TestPrepend(t *testing.T) {
+	entry := "## [1.1.0] - 2024-06-01\n\n### Features\n\n- New thing (abc)\n"
+
+	created := Prepend("", entry)
+	if created != header+"\n"+entry {
+		t.Errorf("Prepend() to a new file =\n%s", created)
+	}
+
+	existing := header + "\n## [1.0.0] - 2024-05-01\n\n### Bug fixes\n\n- Old fix (def)\n\n[1.0.0]: https://example.com/v1.0.0\n"
+	want := header + "\n" + entry + "\n## [1.0.0] - 2024-05-01\n\n### Bug fixes\n\n- Old fix (def)\n\n[1.0.0]: https://example.com/v1.0.0\n"
+	if got := Prepend(existing, entry); got != want {
+		t.Errorf("Prepend() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Regenerating the unreleased entry replaces it
+	unreleased := "## [Unreleased]\n\n### Other\n\n- First (aaa)\n"
+	once := Prepend(existing, unreleased)
+	twice := Prepend(once, "## [Unreleased]\n\n### Other\n\n- First (aaa)\n- Second (bbb)\n")
+	want = header + "\n## [Unreleased]\n\n### Other\n\n- First (aaa)\n- Second (bbb)\n\n## [1.0.0] - 2024-05-01\n\n### Bug fixes\n\n- Old fix (def)\n\n[1.0.0]: https://example.com/v1.0.0\n"
+	if twice != want {
+		t.Errorf("Prepend() replacing [Unreleased] =\n%s\nwant\n%s", twice, want)
+	}
+
+	// A file with only a header gets the entry at the end
+	if got := Prepend("# Changelog\n", entry); got != "# Changelog\n\n"+entry {
+		t.Errorf("Prepend() after a bare header = %q", got)
+	}
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the filename for project configuration within the task directory
+const FileName = "config"
+
+// Config holds per-project settings stored as JSON in .task/config
+type Config struct {
+	// DefaultCollection is the collection used when --collection is not given
+	DefaultCollection string `json:"default_collection,omitempty"`
+	// BulkConfirmThreshold is the number of tasks above which bulk commands ask
+	// for confirmation; zero uses DefaultBulkConfirmThreshold
+	BulkConfirmThreshold int `json:"bulk_confirm_threshold,omitempty"`
+	// Labels describes the project's known labels
+	Labels []Label `json:"labels,omitempty"`
+	// RestrictLabels rejects labels that are not listed in Labels
+	RestrictLabels bool `json:"restrict_labels,omitempty"`
+	// TableColumns is the column set used by table output when --columns is
+	// not given; empty uses DefaultTableColumns
+	TableColumns []string `json:"table_columns,omitempty"`
+	// Webhooks are sent a JSON payload for every task change
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+	// BranchPattern names the branches created by task branch; empty uses
+	// DefaultBranchPattern
+	BranchPattern string `json:"branch_pattern,omitempty"`
+	// Remote is the issue tracker task remote pushes tasks to and pulls them
+	// from
+	Remote *Remote `json:"remote,omitempty"`
+}
+
+// Remote is an issue tracker tasks are mirrored to
+type Remote struct {
+	// Type is github, or file for a local JSON file of issues
+	Type string `json:"type"`
+	// Repo is the GitHub repository as owner/name
+	Repo string `json:"repo,omitempty"`
+	// URL is the API base URL, for GitHub Enterprise; empty uses
+	// https://api.github.com
+	URL string `json:"url,omitempty"`
+	// TokenEnv names the environment variable holding the API token; empty
+	// uses DefaultRemoteTokenEnv. Tokens are never stored in the config
+	TokenEnv string `json:"token_env,omitempty"`
+	// Path is the issues file of the file type, relative to the project
+	Path string `json:"path,omitempty"`
+}
+
+// DefaultRemoteTokenEnv is used when Remote.TokenEnv is not set
+const DefaultRemoteTokenEnv = "GITHUB_TOKEN"
+
+// Token returns the API token from the environment
+func (r Remote) Token() string {
+	if r.TokenEnv != "" {
+		return os.Getenv(r.TokenEnv)
+	}
+	return os.Getenv(DefaultRemoteTokenEnv)
+}
+
+// Webhook is a URL notified of task changes
+type Webhook struct {
+	URL string `json:"url"`
+	// Secret signs payloads with HMAC-SHA256. SecretEnv names an environment
+	// variable to read it from instead, keeping it out of version control
+	Secret    string `json:"secret,omitempty"`
+	SecretEnv string `json:"secret_env,omitempty"`
+	// Events limits the webhook to create, update, status_change, delete,
+	// archive or restore events; empty sends every event
+	Events []string `json:"events,omitempty"`
+}
+
+// SigningSecret returns the secret used to sign payloads, or "" if unsigned
+func (w Webhook) SigningSecret() string {
+	if w.SecretEnv != "" {
+		return os.Getenv(w.SecretEnv)
+	}
+	return w.Secret
+}
+
+// Label describes a project label
+type Label struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Color is a terminal color name: red, green, yellow, blue, magenta, cyan or gray
+	Color string `json:"color,omitempty"`
+}
+
+// FindLabel returns the definition for the named label, or nil if it is not defined
+func (c *Config) FindLabel(name string) *Label {
+	for i := range c.Labels {
+		if c.Labels[i].Name == name {
+			return &c.Labels[i]
+		}
+	}
+	return nil
+}
+
+// ValidateLabels checks labels against the allowlist when RestrictLabels is set
+func (c *Config) ValidateLabels(labels []string) error {
+	if !c.RestrictLabels {
+		return nil
+	}
+	for _, label := range labels {
+		if c.FindLabel(label) == nil {
+			names := make([]string, len(c.Labels))
+			for i, l := range c.Labels {
+				names[i] = l.Name
+			}
+			return fmt.Errorf("unknown label: %s (allowed: %s)", label, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// DefaultBulkConfirmThreshold is used when BulkConfirmThreshold is not set
+const DefaultBulkConfirmThreshold = 10
+
+// ConfirmThreshold returns the effective bulk confirmation threshold
+func (c *Config) ConfirmThreshold() int {
+	if c.BulkConfirmThreshold > 0 {
+		return c.BulkConfirmThreshold
+	}
+	return DefaultBulkConfirmThreshold
+}
+
+// DefaultTableColumns is used when TableColumns is not set
+var DefaultTableColumns = []string{"id", "status", "type", "title", "labels", "updated"}
+
+// Columns returns the effective table column set
+func (c *Config) Columns() []string {
+	if len(c.TableColumns) > 0 {
+		return c.TableColumns
+	}
+	return DefaultTableColumns
+}
+
+// DefaultBranchPattern is used when BranchPattern is not set
+const DefaultBranchPattern = "{type}/{id}-{slug}"
+
+// BranchNamePattern returns the effective branch name pattern
+func (c *Config) BranchNamePattern() string {
+	if c.BranchPattern != "" {
+		return c.BranchPattern
+	}
+	return DefaultBranchPattern
+}
+
+// Path returns the config file path within the given task directory
+func Path(taskDir string) string {
+	return filepath.Join(taskDir, FileName)
+}
+
+// Load reads the config from the given task directory
+// A missing or empty config file yields the zero Config
+func Load(taskDir string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(Path(taskDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return cfg, nil
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Save writes the config as indented JSON to the given task directory
+func (c *Config) Save(taskDir string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+	data = append(data, '\n')
+
+	if err := os.WriteFile(Path(taskDir), data, 0644); err != nil {
+		return fmt.Errorf("writing config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadMissing(t *testing.T) {
+	cfg, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DefaultCollection != "" {
+		t.Errorf("Load().DefaultCollection = %q, want empty", cfg.DefaultCollection)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg := &Config{DefaultCollection: "api"}
+	if err := cfg.Save(dir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.DefaultCollection != "api" {
+		t.Errorf("Load().DefaultCollection = %q, want %q", loaded.DefaultCollection, "api")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, FileName), []byte("{not json"), 0644)
+
+	if _, err := Load(dir); err == nil {
+		t.Error("Load() should return error for invalid JSON")
+	}
+}
+
+func TestConfirmThreshold(t *testing.T) {
+	cfg := &Config{}
+	if got := cfg.ConfirmThreshold(); got != DefaultBulkConfirmThreshold {
+		t.Errorf("ConfirmThreshold() = %d, want %d", got, DefaultBulkConfirmThreshold)
+	}
+
+	cfg.BulkConfirmThreshold = 3
+	if got := cfg.ConfirmThreshold(); got != 3 {
+		t.Errorf("ConfirmThreshold() = %d, want 3", got)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	cfg := &Config{}
+	if got := strings.Join(cfg.Columns(), ","); got != strings.Join(DefaultTableColumns, ",") {
+		t.Errorf("Columns() = %s, want defaults", got)
+	}
+
+	cfg.TableColumns = []string{"id", "title"}
+	if got := strings.Join(cfg.Columns(), ","); got != "id,title" {
+		t.Errorf("Columns() = %s, want id,title", got)
+	}
+}
+
+func TestBranchNamePattern(t *testing.T) {
+	cfg := &Config{}
+	if got := cfg.BranchNamePattern(); got != DefaultBranchPattern {
+		t.Errorf("BranchNamePattern() = %s, want the default", got)
+	}
+
+	cfg.BranchPattern = "{id}/{slug}"
+	if got := cfg.BranchNamePattern(); got != "{id}/{slug}" {
+		t.Errorf("BranchNamePattern() = %s, want {id}/{slug}", got)
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	cfg := &Config{
+		Labels: []Label{{Name: "frontend", Color: "blue"}, {Name: "backend"}},
+	}
+
+	if err := cfg.ValidateLabels([]string{"anything"}); err != nil {
+		t.Errorf("ValidateLabels() without RestrictLabels error = %v", err)
+	}
+
+	cfg.RestrictLabels = true
+	if err := cfg.ValidateLabels([]string{"frontend", "backend"}); err != nil {
+		t.Errorf("ValidateLabels() of allowed labels error = %v", err)
+	}
+	if err := cfg.ValidateLabels([]string{"front-end"}); err == nil {
+		t.Error("ValidateLabels() of an unknown label should return error")
+	}
+
+	if l := cfg.FindLabel("frontend"); l == nil || l.Color != "blue" {
+		t.Errorf("FindLabel(frontend) = %v, want blue definition", l)
+	}
+}
@@ -0,0 +1,349 @@
+package exchange
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// CSV columns written by WriteCSV and understood by ReadCSV
+const (
+	colID            = "id"
+	colTitle         = "title"
+	colType          = "type"
+	colStatus        = "status"
+	colLabels        = "labels"
+	colDescription   = "description"
+	colNotes         = "notes"
+	colCreatedAt     = "created_at"
+	colUpdatedAt     = "updated_at"
+	colNoteCreatedAt = "note_created_at"
+	colNote          = "note"
+)
+
+var csvColumns = []string{
+	colID, colTitle, colType, colStatus, colLabels, colDescription,
+	colNotes, colCreatedAt, colUpdatedAt,
+}
+
+var csvNoteColumns = []string{colNoteCreatedAt, colNote}
+
+// csvAliases maps alternative header names onto CSV columns
+var csvAliases = map[string]string{
+	"name":      colTitle,
+	"summary":   colTitle,
+	"kind":      colType,
+	"state":     colStatus,
+	"label":     colLabels,
+	"tags":      colLabels,
+	"body":      colDescription,
+	"created":   colCreatedAt,
+	"updated":   colUpdatedAt,
+	"note_date": colNoteCreatedAt,
+}
+
+// CSVOptions controls how tasks are written as CSV
+type CSVOptions struct {
+	// Comma is the field separator; zero uses ','
+	Comma rune
+	// Notes writes each note as an extra row after its task
+	Notes bool
+}
+
+// WriteCSV writes tasks as CSV with a header row. Labels are joined with
+// ", " and the notes column holds the note count. With Notes set, each note
+// follows its task as a row holding only the task ID and the note columns
+func WriteCSV(w io.Writer, tasks []model.Task, opts CSVOptions) error {
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+
+	header := csvColumns
+	if opts.Notes {
+		header = append(append([]string{}, csvColumns...), csvNoteColumns...)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		description := ""
+		if t.Description != nil {
+			description = *t.Description
+		}
+		row := []string{
+			t.ID,
+			t.Title,
+			string(t.Type),
+			string(t.Status),
+			strings.Join(t.Labels, ", "),
+			description,
+			strconv.Itoa(len(t.Notes)),
+			formatTime(t.CreatedAt),
+			formatTime(t.UpdatedAt),
+		}
+		if opts.Notes {
+			row = append(row, "", "")
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+
+		if !opts.Notes {
+			continue
+		}
+		for _, n := range t.Notes {
+			noteRow := make([]string, len(header))
+			noteRow[0] = t.ID
+			noteRow[len(header)-2] = formatTime(n.CreatedAt)
+			noteRow[len(header)-1] = n.Content
+			if err := cw.Write(noteRow); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// RowError reports a problem with one row of an imported file
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// ReadCSV reads tasks from CSV with a header row, matching header names to
+// task fields case-insensitively. Only title is required; type and status
+// default to task and todo. Rows without a title but with a note are added
+// as notes to the preceding task. Imported tasks have no IDs, so callers
+// assign fresh ones. Invalid rows are skipped and returned as RowErrors;
+// the returned error is only set when the file as a whole cannot be read
+func ReadCSV(r io.Reader, comma rune) ([]model.Task, []*RowError, error) {
+	cr := csv.NewReader(r)
+	if comma != 0 {
+		cr.Comma = comma
+	}
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("file is empty")
+		}
+		return nil, nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.ReplaceAll(name, " ", "_")
+		if alias, ok := csvAliases[name]; ok {
+			name = alias
+		}
+		if _, dup := index[name]; name != "" && !dup {
+			index[name] = i
+		}
+	}
+	if _, ok := index[colTitle]; !ok {
+		return nil, nil, fmt.Errorf("header has no %s column", colTitle)
+	}
+
+	var tasks []model.Task
+	var rowErrors []*RowError
+	sourceIDs := make(map[string]int)
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, &RowError{Line: parseErr.Line, Err: parseErr.Err})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		field := func(name string) string {
+			if i, ok := index[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		if field(colTitle) == "" {
+			if field(colNote) == "" {
+				if isBlank(record) {
+					continue
+				}
+				rowErrors = append(rowErrors, &RowError{Line: line, Err: fmt.Errorf("%s is required", colTitle)})
+				continue
+			}
+			target := len(tasks) - 1
+			if id := field(colID); id != "" {
+				i, ok := sourceIDs[id]
+				if !ok {
+					rowErrors = append(rowErrors, &RowError{Line: line, Err: fmt.Errorf("note for unknown task: %s", id)})
+					continue
+				}
+				target = i
+			}
+			if target < 0 {
+				rowErrors = append(rowErrors, &RowError{Line: line, Err: fmt.Errorf("note before any task")})
+				continue
+			}
+			note, err := parseNote(field(colNoteCreatedAt), field(colNote))
+			if err != nil {
+				rowErrors = append(rowErrors, &RowError{Line: line, Err: err})
+				continue
+			}
+			tasks[target].Notes = append(tasks[target].Notes, note)
+			continue
+		}
+
+		task, err := parseTaskRow(field)
+		if err != nil {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: err})
+			continue
+		}
+		if id := field(colID); id != "" {
+			sourceIDs[id] = len(tasks)
+		}
+		tasks = append(tasks, *task)
+	}
+
+	return tasks, rowErrors, nil
+}
+
+// parseTaskRow builds a task from the fields of one row
+func parseTaskRow(field func(string) string) (*model.Task, error) {
+	taskType := model.TypeTask
+	if v := field(colType); v != "" {
+		tt, err := model.ParseTaskType(strings.ToLower(v))
+		if err != nil {
+			return nil, err
+		}
+		taskType = tt
+	}
+
+	task := model.NewTask("", field(colTitle), taskType)
+
+	if v := field(colStatus); v != "" {
+		status, err := model.ParseStatus(strings.ToLower(v))
+		if err != nil {
+			return nil, err
+		}
+		task.Status = status
+	}
+
+	if v := field(colLabels); v != "" {
+		task.Labels = SplitLabels(v)
+	}
+
+	if v := field(colDescription); v != "" {
+		task.Description = &v
+	}
+
+	if v := field(colCreatedAt); v != "" {
+		created, err := ParseTime(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", colCreatedAt, err)
+		}
+		task.CreatedAt = created
+		task.UpdatedAt = created
+	}
+
+	if v := field(colUpdatedAt); v != "" {
+		updated, err := ParseTime(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", colUpdatedAt, err)
+		}
+		task.UpdatedAt = updated
+	}
+
+	return task, nil
+}
+
+// parseNote builds a note from a note row. Note IDs are left for the caller
+func parseNote(createdAt, content string) (model.Note, error) {
+	note := model.Note{CreatedAt: time.Now().UTC(), Content: content}
+	if createdAt != "" {
+		t, err := ParseTime(createdAt)
+		if err != nil {
+			return note, fmt.Errorf("%s: %w", colNoteCreatedAt, err)
+		}
+		note.CreatedAt = t
+	}
+	return note, nil
+}
+
+// SplitLabels splits a comma or semicolon separated list of labels
+func SplitLabels(s string) []string {
+	labels := []string{}
+	seen := make(map[string]bool)
+	for _, l := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		l = strings.TrimSpace(l)
+		if l == "" || seen[l] {
+			continue
+		}
+		seen[l] = true
+		labels = append(labels, l)
+	}
+	return labels
+}
+
+// timeLayouts are the layouts accepted by ParseTime, most precise first
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTime parses an RFC 3339 timestamp or a plain date, as UTC
+func ParseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s (use YYYY-MM-DD or RFC 3339)", s)
+}
+
+// formatTime formats t as RFC 3339 in UTC, or "" for the zero time
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// isBlank reports whether every field of a record is empty
+func isBlank(record []string) bool {
+	for _, f := range record {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package exchange
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+func sampleTasks() []model.Task {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	first := model.NewTask("aaa", "Fix login, again", model.TypeBug)
+	first.CreatedAt = created
+	first.UpdatedAt = created.Add(time.Hour)
+	first.Labels = []string{"auth", "urgent"}
+	first.Notes = []model.Note{
+		{ID: "aaa-n01", CreatedAt: created.Add(time.Minute), Content: "Seen on \"staging\""},
+		{ID: "aaa-n02", CreatedAt: created.Add(2 * time.Minute), Content: "Line one\nline two"},
+	}
+
+	second := model.NewTask("bbb", "Write docs", model.TypeTask)
+	second.CreatedAt = created
+	second.UpdatedAt = created
+	second.SetStatus(model.StatusDone)
+	second.UpdatedAt = created
+	desc := "Cover the\tCLI"
+	second.Description = &desc
+
+	return []model.Task{*first, *second}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, sampleTasks(), CSVOptions{}); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	want := `id,title,type,status,labels,description,notes,created_at,updated_at
+aaa,"Fix login, again",bug,todo,"auth, urgent",,2,2024-03-01T09:30:00Z,2024-03-01T10:30:00Z
+bbb,Write docs,task,done,,Cover the	CLI,0,2024-03-01T09:30:00Z,2024-03-01T09:30:00Z
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteCSVNotesRoundTrip(t *testing.T) {
+	for _, comma := range []rune{',', '\t'} {
+		var buf bytes.Buffer
+		if err := WriteCSV(&buf, sampleTasks(), CSVOptions{Comma: comma, Notes: true}); err != nil {
+			t.Fatalf("WriteCSV() error = %v", err)
+		}
+
+		tasks, rowErrors, err := ReadCSV(&buf, comma)
+		if err != nil {
+			t.Fatalf("ReadCSV() error = %v", err)
+		}
+		if len(rowErrors) != 0 {
+			t.Fatalf("ReadCSV() row errors = %v", rowErrors)
+		}
+		if len(tasks) != 2 {
+			t.Fatalf("ReadCSV() returned %d tasks, want 2", len(tasks))
+		}
+
+		first := tasks[0]
+		if first.ID != "" {
+			t.Errorf("imported task ID = %q, want empty", first.ID)
+		}
+		if first.Title != "Fix login, again" || first.Type != model.TypeBug {
+			t.Errorf("imported task = %+v", first)
+		}
+		if strings.Join(first.Labels, "|") != "auth|urgent" {
+			t.Errorf("imported labels = %v, want [auth urgent]", first.Labels)
+		}
+		if len(first.Notes) != 2 || first.Notes[1].Content != "Line one\nline two" {
+			t.Errorf("imported notes = %+v", first.Notes)
+		}
+		if !first.UpdatedAt.Equal(time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)) {
+			t.Errorf("imported UpdatedAt = %v", first.UpdatedAt)
+		}
+
+		second := tasks[1]
+		if second.Status != model.StatusDone || second.Description == nil || *second.Description != "Cover the\tCLI" {
+			t.Errorf("imported second task = %+v", second)
+		}
+	}
+}
+
+func TestReadCSVHeaderMapping(t *testing.T) {
+	input := "\ufeffSummary,State,Tags,Created\nShip it,Progress,a; b,2024-05-02\n"
+	tasks, rowErrors, err := ReadCSV(strings.NewReader(input), ',')
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("ReadCSV() error = %v, row errors = %v", err, rowErrors)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("ReadCSV() returned %d tasks, want 1", len(tasks))
+	}
+
+	task := tasks[0]
+	if task.Title != "Ship it" || task.Status != model.StatusProgress || task.Type != model.TypeTask {
+		t.Errorf("imported task = %+v", task)
+	}
+	if strings.Join(task.Labels, "|") != "a|b" {
+		t.Errorf("imported labels = %v, want [a b]", task.Labels)
+	}
+	if !task.CreatedAt.Equal(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("imported CreatedAt = %v", task.CreatedAt)
+	}
+}
+
+func TestReadCSVRowErrors(t *testing.T) {
+	input := `title,type,status,created_at
+Good,bug,todo,
+Bad type,epic,todo,
+,task,todo,
+Bad status,task,finished,
+Bad date,task,todo,yesterday
+
+Also good,,,
+`
+	tasks, rowErrors, err := ReadCSV(strings.NewReader(input), ',')
+	if err != nil {
+		t.Fatalf("ReadCSV() error = %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Title != "Good" || tasks[1].Title != "Also good" {
+		t.Errorf("ReadCSV() tasks = %v, want Good and Also good", tasks)
+	}
+
+	wantLines := []int{3, 4, 5, 6}
+	if len(rowErrors) != len(wantLines) {
+		t.Fatalf("ReadCSV() row errors = %v, want %d", rowErrors, len(wantLines))
+	}
+	for i, line := range wantLines {
+		if rowErrors[i].Line != line {
+			t.Errorf("row error %d line = %d, want %d (%v)", i, rowErrors[i].Line, line, rowErrors[i])
+		}
+	}
+	if !strings.Contains(rowErrors[0].Error(), "invalid type: epic") {
+		t.Errorf("row error = %v, want invalid type", rowErrors[0])
+	}
+}
+
+func TestReadCSVMissingTitleColumn(t *testing.T) {
+	if _, _, err := ReadCSV(strings.NewReader("id,status\naaa,todo\n"), ','); err == nil {
+		t.Error("ReadCSV() without a title column should return error")
+	}
+	if _, _, err := ReadCSV(strings.NewReader(""), ','); err == nil {
+		t.Error("ReadCSV() of an empty file should return error")
+	}
+}
@@ -0,0 +1,167 @@
+package exchange
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/version"
+)
+
+// DefaultICSDomain is the UID domain used when ICSOptions.Domain is empty
+const DefaultICSDomain = "task"
+
+const (
+	// icsTimeLayout is a UTC date-time in iCalendar
+	icsTimeLayout = "20060102T150405Z"
+	// icsDateLayout is a date in iCalendar
+	icsDateLayout = "20060102"
+	// icsLineLength is the most octets on a line before it is folded
+	icsLineLength = 75
+)
+
+// ICSOptions controls how tasks are written as iCalendar
+type ICSOptions struct {
+	// Name is the calendar name shown by calendar apps
+	Name string
+	// Domain makes UIDs unique across projects: a task's UID is its ID at
+	// the domain. Empty uses DefaultICSDomain
+	Domain string
+	// Events adds an all-day VEVENT on the due date of each task that has
+	// one, for calendars that don't show to-dos
+	Events bool
+}
+
+// ICSUID returns the UID WriteICS gives a task's VTODO
+func ICSUID(taskID, domain string) string {
+	if domain == "" {
+		domain = DefaultICSDomain
+	}
+	return fmt.Sprintf("%s@%s", taskID, domain)
+}
+
+// WriteICS writes tasks as an iCalendar (RFC 5545) calendar of VTODOs. UIDs
+// are derived from task IDs, so subscribed calendars update entries in place.
+// Statuses map to NEEDS-ACTION (todo and blocked), IN-PROCESS, COMPLETED and
+// CANCELLED, labels to CATEGORIES, and the due date to an all-day DUE
+func WriteICS(w io.Writer, tasks []model.Task, opts ICSOptions) error {
+	cw := &icsWriter{w: bufio.NewWriter(w)}
+	cw.line("BEGIN", "VCALENDAR")
+	cw.line("VERSION", "2.0")
+	cw.line("PRODID", "-//task//task "+version.Version+"//EN")
+	cw.line("CALSCALE", "GREGORIAN")
+	if opts.Name != "" {
+		cw.line("X-WR-CALNAME", icsText(opts.Name))
+	}
+
+	for _, t := range tasks {
+		uid := ICSUID(t.ID, opts.Domain)
+		stamp := t.UpdatedAt.UTC().Format(icsTimeLayout)
+
+		cw.line("BEGIN", "VTODO")
+		cw.line("UID", uid)
+		cw.line("DTSTAMP", stamp)
+		cw.line("CREATED", t.CreatedAt.UTC().Format(icsTimeLayout))
+		cw.line("LAST-MODIFIED", stamp)
+		cw.line("SUMMARY", icsText(t.Title))
+		if t.Description != nil && *t.Description != "" {
+			cw.line("DESCRIPTION", icsText(*t.Description))
+		}
+		if !t.Due.IsZero() {
+			cw.line("DUE;VALUE=DATE", t.Due.Format(icsDateLayout))
+		}
+		cw.line("STATUS", icsTodoStatus(t.Status))
+		if t.Status == model.StatusDone {
+			completed := t.StatusChangedAt
+			if completed.IsZero() {
+				completed = t.UpdatedAt
+			}
+			cw.line("COMPLETED", completed.UTC().Format(icsTimeLayout))
+			cw.line("PERCENT-COMPLETE", "100")
+		}
+		if len(t.Labels) > 0 {
+			categories := make([]string, len(t.Labels))
+			for i, label := range t.Labels {
+				categories[i] = icsText(label)
+			}
+			cw.line("CATEGORIES", strings.Join(categories, ","))
+		}
+		cw.line("END", "VTODO")
+
+		if opts.Events && !t.Due.IsZero() {
+			cw.line("BEGIN", "VEVENT")
+			cw.line("UID", ICSUID(t.ID+"-due", opts.Domain))
+			cw.line("DTSTAMP", stamp)
+			cw.line("DTSTART;VALUE=DATE", t.Due.Format(icsDateLayout))
+			cw.line("DTEND;VALUE=DATE", t.Due.AddDate(0, 0, 1).Format(icsDateLayout))
+			cw.line("SUMMARY", icsText(t.Title))
+			cw.line("TRANSP", "TRANSPARENT")
+			if t.Status == model.StatusAbandon {
+				cw.line("STATUS", "CANCELLED")
+			} else {
+				cw.line("STATUS", "CONFIRMED")
+			}
+			cw.line("RELATED-TO", uid)
+			cw.line("END", "VEVENT")
+		}
+	}
+
+	cw.line("END", "VCALENDAR")
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
+
+// icsTodoStatus maps a task status to a VTODO STATUS
+func icsTodoStatus(status model.Status) string {
+	switch status {
+	case model.StatusProgress:
+		return "IN-PROCESS"
+	case model.StatusDone:
+		return "COMPLETED"
+	case model.StatusAbandon:
+		return "CANCELLED"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
+
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// icsText escapes a TEXT value
+func icsText(s string) string {
+	return icsTextEscaper.Replace(s)
+}
+
+// icsWriter writes content lines, folding long ones and ending each with
+// CRLF. The first write error is kept and later writes are skipped
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (cw *icsWriter) line(name, value string) {
+	if cw.err != nil {
+		return
+	}
+	line := name + ":" + value
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if width+size > icsLineLength {
+			// Continuation lines start with a space, which counts towards
+			// their length
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+	_, cw.err = cw.w.WriteString(b.String())
+}
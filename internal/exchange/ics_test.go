@@ -0,0 +1,119 @@
+package exchange
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/version"
+)
+
+func TestWriteICS(t *testing.T) {
+	tasks := sampleTasks()
+	tasks[0].Due = time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	tasks[0].Status = model.StatusProgress
+	tasks[1].StatusChangedAt = time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, tasks, ICSOptions{Name: "Demo; tasks", Domain: "demo", Events: true}); err != nil {
+		t.Fatalf("WriteICS() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//task//task " + version.Version + "//EN",
+		"CALSCALE:GREGORIAN",
+		`X-WR-CALNAME:Demo\; tasks`,
+		"BEGIN:VTODO",
+		"UID:aaa@demo",
+		"DTSTAMP:20240301T103000Z",
+		"CREATED:20240301T093000Z",
+		"LAST-MODIFIED:20240301T103000Z",
+		`SUMMARY:Fix login\, again`,
+		"DUE;VALUE=DATE:20240401",
+		"STATUS:IN-PROCESS",
+		"CATEGORIES:auth,urgent",
+		"END:VTODO",
+		"BEGIN:VEVENT",
+		"UID:aaa-due@demo",
+		"DTSTAMP:20240301T103000Z",
+		"DTSTART;VALUE=DATE:20240401",
+		"DTEND;VALUE=DATE:20240402",
+		`SUMMARY:Fix login\, again`,
+		"TRANSP:TRANSPARENT",
+		"STATUS:CONFIRMED",
+		"RELATED-TO:aaa@demo",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:bbb@demo",
+		"DTSTAMP:20240301T093000Z",
+		"CREATED:20240301T093000Z",
+		"LAST-MODIFIED:20240301T093000Z",
+		"SUMMARY:Write docs",
+		"DESCRIPTION:Cover the\tCLI",
+		"STATUS:COMPLETED",
+		"COMPLETED:20240302T080000Z",
+		"PERCENT-COMPLETE:100",
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if got := buf.String(); got != want {
+		t.Errorf("WriteICS() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteICSStatuses(t *testing.T) {
+	tests := []struct {
+		status model.Status
+		want   string
+	}{
+		{model.StatusTodo, "NEEDS-ACTION"},
+		{model.StatusBlocked, "NEEDS-ACTION"},
+		{model.StatusProgress, "IN-PROCESS"},
+		{model.StatusDone, "COMPLETED"},
+		{model.StatusAbandon, "CANCELLED"},
+	}
+	for _, tt := range tests {
+		task := model.NewTask("aaa", "Task", model.TypeTask)
+		task.Status = tt.status
+
+		var buf bytes.Buffer
+		if err := WriteICS(&buf, []model.Task{*task}, ICSOptions{}); err != nil {
+			t.Fatalf("WriteICS() error = %v", err)
+		}
+		if !strings.Contains(buf.String(), "\r\nSTATUS:"+tt.want+"\r\n") {
+			t.Errorf("status %s: want STATUS:%s in\n%s", tt.status, tt.want, buf.String())
+		}
+		if !strings.Contains(buf.String(), "\r\nUID:aaa@"+DefaultICSDomain+"\r\n") {
+			t.Errorf("want UID at %s in\n%s", DefaultICSDomain, buf.String())
+		}
+	}
+}
+
+func TestWriteICSFoldsAndEscapes(t *testing.T) {
+	task := model.NewTask("aaa", strings.Repeat("é", 50), model.TypeTask)
+	desc := "First line\nsecond, with a back\\slash"
+	task.Description = &desc
+
+	var buf bytes.Buffer
+	if err := WriteICS(&buf, []model.Task{*task}, ICSOptions{}); err != nil {
+		t.Fatalf("WriteICS() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line is %d octets: %q", len(line), line)
+		}
+	}
+	if unfolded := strings.ReplaceAll(out, "\r\n ", ""); !strings.Contains(unfolded, "\r\nSUMMARY:"+strings.Repeat("é", 50)+"\r\n") {
+		t.Errorf("summary not folded cleanly:\n%s", out)
+	}
+	if !strings.Contains(out, `DESCRIPTION:First line\nsecond\, with a back\\slash`) {
+		t.Errorf("description not escaped:\n%s", out)
+	}
+}
@@ -0,0 +1,222 @@
+package exchange
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// Groupings accepted by MarkdownOptions.GroupBy
+const (
+	GroupByStatus = "status"
+	GroupByLabel  = "label"
+	GroupByNone   = "none"
+)
+
+// markdownStatuses is the order of status sections in a Markdown report
+var markdownStatuses = []model.Status{
+	model.StatusProgress,
+	model.StatusBlocked,
+	model.StatusTodo,
+	model.StatusDone,
+	model.StatusAbandon,
+}
+
+// markdownTimeFormat matches the timestamps shown by task show
+const markdownTimeFormat = "2006-01-02 15:04:05"
+
+// MarkdownOptions controls how tasks are written as a Markdown report
+type MarkdownOptions struct {
+	// Title is written as a top level heading when set
+	Title string
+	// GroupBy is GroupByStatus, GroupByLabel or GroupByNone; empty groups by status
+	GroupBy string
+	// Brief leaves out descriptions and notes
+	Brief bool
+}
+
+// ValidGroupBy checks a MarkdownOptions.GroupBy value
+func ValidGroupBy(groupBy string) error {
+	switch groupBy {
+	case "", GroupByStatus, GroupByLabel, GroupByNone:
+		return nil
+	}
+	return fmt.Errorf("invalid grouping: %s (must be status, label or none)", groupBy)
+}
+
+// WriteMarkdown writes tasks as a Markdown checklist, with done tasks checked
+// and abandoned tasks struck through. Descriptions and notes are nested
+// under each task unless opts.Brief is set. When grouping by label, a task
+// appears under each of its labels
+func WriteMarkdown(w io.Writer, tasks []model.Task, opts MarkdownOptions) error {
+	if err := ValidGroupBy(opts.GroupBy); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	level := "##"
+	if opts.Title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", opts.Title)
+	} else {
+		level = "#"
+	}
+
+	switch opts.GroupBy {
+	case GroupByNone:
+		writeMarkdownList(&b, tasks, opts.Brief)
+	case GroupByLabel:
+		groups, unlabelled := groupByLabel(tasks)
+		names := make([]string, 0, len(groups))
+		for name := range groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			writeMarkdownSection(&b, level, name, groups[name], opts.Brief)
+		}
+		if len(unlabelled) > 0 {
+			writeMarkdownSection(&b, level, "Unlabelled", unlabelled, opts.Brief)
+		}
+	default:
+		for _, status := range markdownStatuses {
+			var section []model.Task
+			for _, t := range tasks {
+				if t.Status == status {
+					section = append(section, t)
+				}
+			}
+			if len(section) > 0 {
+				writeMarkdownSection(&b, level, statusHeading(status), section, opts.Brief)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, strings.TrimRight(b.String(), "\n")+"\n")
+	return err
+}
+
+// WriteMarkdownTask writes a single task as a self-contained Markdown
+// document with the same fields as task show
+func WriteMarkdownTask(w io.Writer, task *model.Task) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", task.Title)
+	fmt.Fprintln(&b, "| Field | Value |")
+	fmt.Fprintln(&b, "| --- | --- |")
+	fmt.Fprintf(&b, "| ID | `%s` |\n", task.ID)
+	fmt.Fprintf(&b, "| Status | %s |\n", task.Status)
+	fmt.Fprintf(&b, "| Type | %s |\n", task.Type)
+	labels := "(none)"
+	if len(task.Labels) > 0 {
+		labels = strings.Join(task.Labels, ", ")
+	}
+	fmt.Fprintf(&b, "| Labels | %s |\n", escapeTableCell(labels))
+	fmt.Fprintf(&b, "| Created | %s |\n", task.CreatedAt.Format(markdownTimeFormat))
+	fmt.Fprintf(&b, "| Updated | %s |\n", task.UpdatedAt.Format(markdownTimeFormat))
+
+	fmt.Fprintln(&b, "\n## Description")
+	fmt.Fprintln(&b)
+	if task.Description != nil && *task.Description != "" {
+		fmt.Fprintln(&b, strings.TrimRight(*task.Description, "\n"))
+	} else {
+		fmt.Fprintln(&b, "_(none)_")
+	}
+
+	if len(task.Notes) > 0 {
+		fmt.Fprintf(&b, "\n## Notes (%d)\n\n", len(task.Notes))
+		for _, note := range task.Notes {
+			fmt.Fprintf(&b, "- **%s** %s\n", note.CreatedAt.Format("2006-01-02 15:04"), indentContinuation(note.Content, "  "))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMarkdownSection writes a heading followed by the task list
+func writeMarkdownSection(b *strings.Builder, level, heading string, tasks []model.Task, brief bool) {
+	fmt.Fprintf(b, "%s %s (%d)\n\n", level, heading, len(tasks))
+	writeMarkdownList(b, tasks, brief)
+	fmt.Fprintln(b)
+}
+
+// writeMarkdownList writes one checklist item per task
+func writeMarkdownList(b *strings.Builder, tasks []model.Task, brief bool) {
+	for _, t := range tasks {
+		check := " "
+		if t.Status == model.StatusDone {
+			check = "x"
+		}
+		title := t.Title
+		if t.Status == model.StatusAbandon {
+			title = "~~" + title + "~~"
+		}
+
+		meta := []string{string(t.Type)}
+		if t.Status != model.StatusDone && t.Status != model.StatusTodo {
+			meta = append(meta, string(t.Status))
+		}
+		if len(t.Labels) > 0 {
+			meta = append(meta, strings.Join(t.Labels, ", "))
+		}
+		fmt.Fprintf(b, "- [%s] %s `%s` _(%s)_\n", check, title, t.ID, strings.Join(meta, "; "))
+
+		if brief {
+			continue
+		}
+		if t.Description != nil && *t.Description != "" {
+			fmt.Fprintf(b, "\n  %s\n\n", indentContinuation(strings.TrimRight(*t.Description, "\n"), "  "))
+		}
+		for _, note := range t.Notes {
+			fmt.Fprintf(b, "  - _%s_ %s\n", note.CreatedAt.Format("2006-01-02 15:04"), indentContinuation(note.Content, "    "))
+		}
+	}
+}
+
+// groupByLabel maps each label to its tasks, returning unlabelled tasks separately
+func groupByLabel(tasks []model.Task) (map[string][]model.Task, []model.Task) {
+	groups := make(map[string][]model.Task)
+	var unlabelled []model.Task
+	for _, t := range tasks {
+		if len(t.Labels) == 0 {
+			unlabelled = append(unlabelled, t)
+			continue
+		}
+		for _, label := range t.Labels {
+			groups[label] = append(groups[label], t)
+		}
+	}
+	return groups, unlabelled
+}
+
+// statusHeading returns the section heading for a status
+func statusHeading(s model.Status) string {
+	switch s {
+	case model.StatusTodo:
+		return "Todo"
+	case model.StatusProgress:
+		return "In progress"
+	case model.StatusBlocked:
+		return "Blocked"
+	case model.StatusDone:
+		return "Done"
+	case model.StatusAbandon:
+		return "Abandoned"
+	default:
+		return string(s)
+	}
+}
+
+// indentContinuation indents every line after the first so multi-line text
+// stays inside its list item
+func indentContinuation(s, indent string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+indent)
+}
+
+// escapeTableCell escapes pipes so text can be placed in a table cell
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
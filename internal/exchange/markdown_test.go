@@ -0,0 +1,104 @@
+package exchange
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+func TestWriteMarkdownByStatus(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, sampleTasks(), MarkdownOptions{Title: "Sprint 4"}); err != nil {
+		t.Fatalf("WriteMarkdown() error = %v", err)
+	}
+
+	want := "# Sprint 4\n" +
+		"\n" +
+		"## Todo (1)\n" +
+		"\n" +
+		"- [ ] Fix login, again `aaa` _(bug; auth, urgent)_\n" +
+		"  - _2024-03-01 09:31_ Seen on \"staging\"\n" +
+		"  - _2024-03-01 09:32_ Line one\n" +
+		"    line two\n" +
+		"\n" +
+		"## Done (1)\n" +
+		"\n" +
+		"- [x] Write docs `bbb` _(task)_\n" +
+		"\n" +
+		"  Cover the\tCLI\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteMarkdown() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteMarkdownByLabel(t *testing.T) {
+	tasks := sampleTasks()
+	abandoned := model.NewTask("ccc", "Old idea", model.TypeFeature)
+	abandoned.Labels = []string{"auth"}
+	abandoned.SetStatus(model.StatusAbandon)
+	tasks = append(tasks, *abandoned)
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, tasks, MarkdownOptions{GroupBy: GroupByLabel, Brief: true}); err != nil {
+		t.Fatalf("WriteMarkdown() error = %v", err)
+	}
+	got := buf.String()
+
+	for _, want := range []string{
+		"# auth (2)\n\n- [ ] Fix login, again `aaa`",
+		"- [ ] ~~Old idea~~ `ccc` _(feature; abandon; auth)_\n",
+		"# urgent (1)\n",
+		"# Unlabelled (1)\n\n- [x] Write docs `bbb` _(task)_\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteMarkdown() should contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "staging") || strings.Contains(got, "Cover the") {
+		t.Errorf("WriteMarkdown() with Brief should leave out notes and descriptions, got:\n%s", got)
+	}
+	if strings.Index(got, "# auth") > strings.Index(got, "# urgent") {
+		t.Error("WriteMarkdown() should sort label sections")
+	}
+}
+
+func TestWriteMarkdownInvalidGroup(t *testing.T) {
+	if err := WriteMarkdown(&bytes.Buffer{}, nil, MarkdownOptions{GroupBy: "type"}); err == nil {
+		t.Error("WriteMarkdown() with an invalid grouping should return error")
+	}
+}
+
+func TestWriteMarkdownTask(t *testing.T) {
+	tasks := sampleTasks()
+
+	var buf bytes.Buffer
+	if err := WriteMarkdownTask(&buf, &tasks[0]); err != nil {
+		t.Fatalf("WriteMarkdownTask() error = %v", err)
+	}
+
+	want := "# Fix login, again\n" +
+		"\n" +
+		"| Field | Value |\n" +
+		"| --- | --- |\n" +
+		"| ID | `aaa` |\n" +
+		"| Status | todo |\n" +
+		"| Type | bug |\n" +
+		"| Labels | auth, urgent |\n" +
+		"| Created | 2024-03-01 09:30:00 |\n" +
+		"| Updated | 2024-03-01 10:30:00 |\n" +
+		"\n" +
+		"## Description\n" +
+		"\n" +
+		"_(none)_\n" +
+		"\n" +
+		"## Notes (2)\n" +
+		"\n" +
+		"- **2024-03-01 09:31** Seen on \"staging\"\n" +
+		"- **2024-03-01 09:32** Line one\n" +
+		"  line two\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteMarkdownTask() =\n%s\nwant\n%s", got, want)
+	}
+}
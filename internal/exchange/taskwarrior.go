@@ -0,0 +1,329 @@
+package exchange
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// TaskwarriorRef is the key in Task.Refs holding a task's Taskwarrior UUID
+const TaskwarriorRef = "taskwarrior"
+
+// ProjectLabelPrefix marks the label a Taskwarrior project maps to
+const ProjectLabelPrefix = "project:"
+
+// taskwarriorTimeLayout is the UTC timestamp format used by Taskwarrior
+const taskwarriorTimeLayout = "20060102T150405Z"
+
+// Taskwarrior statuses
+const (
+	twPending   = "pending"
+	twWaiting   = "waiting"
+	twCompleted = "completed"
+	twDeleted   = "deleted"
+	twRecurring = "recurring"
+)
+
+// twPriorities maps Taskwarrior priorities to the priority:x labels used for
+// todo.txt priorities
+var twPriorities = map[string]string{"H": "h", "M": "m", "L": "l"}
+
+// twTask is a task in Taskwarrior's export format. The task ID, a type other
+// than task and the description are kept as user defined attributes, which
+// Taskwarrior preserves when it imports and exports
+type twTask struct {
+	UUID        string         `json:"uuid"`
+	Description string         `json:"description"`
+	Status      string         `json:"status"`
+	Entry       string         `json:"entry,omitempty"`
+	Modified    string         `json:"modified,omitempty"`
+	Start       string         `json:"start,omitempty"`
+	End         string         `json:"end,omitempty"`
+	Due         string         `json:"due,omitempty"`
+	Project     string         `json:"project,omitempty"`
+	Priority    string         `json:"priority,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Annotations []twAnnotation `json:"annotations,omitempty"`
+	TaskID      string         `json:"taskid,omitempty"`
+	TaskType    string         `json:"tasktype,omitempty"`
+	Details     string         `json:"details,omitempty"`
+}
+
+type twAnnotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// TaskwarriorUUID returns the Taskwarrior UUID for a task: the one it was
+// imported with, otherwise one derived from its ID so repeated exports update
+// the same Taskwarrior tasks
+func TaskwarriorUUID(t model.Task) string {
+	if uuid := t.Refs[TaskwarriorRef]; uuid != "" {
+		return uuid
+	}
+	sum := sha1.Sum([]byte("task:" + t.ID))
+	sum[6] = sum[6]&0x0f | 0x50 // version 5
+	sum[8] = sum[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// WriteTaskwarrior writes tasks as a JSON array that "task import" in
+// Taskwarrior accepts. Labels become tags, except project:x and priority:h,
+// m or l labels which set the project and priority, and notes become
+// annotations. Progress tasks are pending with a start time, blocked tasks
+// are waiting, and abandoned tasks are deleted
+func WriteTaskwarrior(w io.Writer, tasks []model.Task) error {
+	if _, err := io.WriteString(w, "[\n"); err != nil {
+		return err
+	}
+	for i, t := range tasks {
+		data, err := json.Marshal(toTaskwarrior(t))
+		if err != nil {
+			return err
+		}
+		if i < len(tasks)-1 {
+			data = append(data, ',')
+		}
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "]\n")
+	return err
+}
+
+// toTaskwarrior converts a task to Taskwarrior's format
+func toTaskwarrior(t model.Task) twTask {
+	tw := twTask{
+		UUID:        TaskwarriorUUID(t),
+		Description: t.Title,
+		Entry:       twTime(t.CreatedAt),
+		Modified:    twTime(t.UpdatedAt),
+		TaskID:      t.ID,
+	}
+	if t.Type != model.TypeTask {
+		tw.TaskType = string(t.Type)
+	}
+	if t.Description != nil {
+		tw.Details = *t.Description
+	}
+	if !t.Due.IsZero() {
+		// Due dates are midnight local time, as "due:2024-03-05" would be
+		due := time.Date(t.Due.Year(), t.Due.Month(), t.Due.Day(), 0, 0, 0, 0, time.Local)
+		tw.Due = twTime(due)
+	}
+
+	for _, label := range t.Labels {
+		if project, ok := strings.CutPrefix(label, ProjectLabelPrefix); ok && project != "" && tw.Project == "" {
+			tw.Project = project
+			continue
+		}
+		if p, ok := labelPriority(label); ok && tw.Priority == "" && twPriorities[p] != "" {
+			tw.Priority = p
+			continue
+		}
+		tw.Tags = append(tw.Tags, TagLabel(label))
+	}
+
+	changed := t.StatusChangedAt
+	if changed.IsZero() {
+		changed = t.UpdatedAt
+	}
+	switch t.Status {
+	case model.StatusProgress:
+		tw.Status = twPending
+		tw.Start = twTime(changed)
+	case model.StatusBlocked:
+		tw.Status = twWaiting
+	case model.StatusDone:
+		tw.Status = twCompleted
+		tw.End = twTime(changed)
+	case model.StatusAbandon:
+		tw.Status = twDeleted
+		tw.End = twTime(changed)
+	default:
+		tw.Status = twPending
+	}
+
+	for _, n := range t.Notes {
+		tw.Annotations = append(tw.Annotations, twAnnotation{Entry: twTime(n.CreatedAt), Description: n.Content})
+	}
+	return tw
+}
+
+// ReadTaskwarrior reads the output of Taskwarrior's "task export": a JSON
+// array, or one JSON object per line as older versions write. Each task
+// keeps its UUID in Refs and, when it was exported by WriteTaskwarrior, its
+// ID, so callers can match it to an existing task. Recurring task templates
+// are skipped since their instances are exported as well. Tasks that can't
+// be read are skipped and returned as RowErrors
+func ReadTaskwarrior(r io.Reader) ([]model.Task, []*RowError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil, fmt.Errorf("file is empty")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	array := trimmed[0] == '['
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var tasks []model.Task
+	var rowErrors []*RowError
+	for !array || dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if !array && errors.Is(err, io.EOF) {
+				break
+			}
+			line := 1 + bytes.Count(data[:dec.InputOffset()], []byte("\n"))
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		start := int(dec.InputOffset()) - len(raw)
+		line := 1 + bytes.Count(data[:start], []byte("\n"))
+
+		var tw twTask
+		if err := json.Unmarshal(raw, &tw); err != nil {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: err})
+			continue
+		}
+		if tw.Status == twRecurring {
+			continue
+		}
+		task, err := fromTaskwarrior(tw)
+		if err != nil {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: err})
+			continue
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, rowErrors, nil
+}
+
+// fromTaskwarrior converts a task from Taskwarrior's format
+func fromTaskwarrior(tw twTask) (*model.Task, error) {
+	title := strings.TrimSpace(tw.Description)
+	if title == "" {
+		return nil, fmt.Errorf("description is required")
+	}
+
+	taskType := model.TypeTask
+	if tw.TaskType != "" {
+		tt, err := model.ParseTaskType(tw.TaskType)
+		if err != nil {
+			return nil, err
+		}
+		taskType = tt
+	}
+	task := model.NewTask(tw.TaskID, title, taskType)
+	if tw.Details != "" {
+		details := tw.Details
+		task.Description = &details
+	}
+	if tw.UUID != "" {
+		task.Refs = map[string]string{TaskwarriorRef: tw.UUID}
+	}
+
+	var labels []string
+	if tw.Priority != "" {
+		p, ok := twPriorities[strings.ToUpper(tw.Priority)]
+		if !ok {
+			return nil, fmt.Errorf("invalid priority: %s", tw.Priority)
+		}
+		labels = append(labels, PriorityLabelPrefix+p)
+	}
+	if tw.Project != "" {
+		labels = append(labels, ProjectLabelPrefix+tw.Project)
+	}
+	labels = append(labels, tw.Tags...)
+	task.Labels = SplitLabels(strings.Join(labels, ","))
+
+	var parseErr error
+	parse := func(name, value string) time.Time {
+		if value == "" || parseErr != nil {
+			return time.Time{}
+		}
+		t, err := parseTWTime(value)
+		if err != nil {
+			parseErr = fmt.Errorf("invalid %s: %s", name, value)
+		}
+		return t
+	}
+	entry, modified := parse("entry", tw.Entry), parse("modified", tw.Modified)
+	start, end, due := parse("start", tw.Start), parse("end", tw.End), parse("due", tw.Due)
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	if !entry.IsZero() {
+		task.CreatedAt = entry
+		task.UpdatedAt = entry
+	}
+	if !modified.IsZero() {
+		task.UpdatedAt = modified
+	}
+	if !due.IsZero() {
+		local := due.In(time.Local)
+		task.Due = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	switch tw.Status {
+	case twPending, "":
+		if !start.IsZero() {
+			task.Status = model.StatusProgress
+			task.StatusChangedAt = start
+		}
+	case twWaiting:
+		task.Status = model.StatusBlocked
+	case twCompleted:
+		task.Status = model.StatusDone
+		task.StatusChangedAt = end
+	case twDeleted:
+		task.Status = model.StatusAbandon
+		task.StatusChangedAt = end
+	default:
+		return nil, fmt.Errorf("invalid status: %s", tw.Status)
+	}
+
+	for _, a := range tw.Annotations {
+		content := strings.TrimSpace(a.Description)
+		if content == "" {
+			continue
+		}
+		created, err := parseTWTime(a.Entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid annotation entry: %s", a.Entry)
+		}
+		task.Notes = append(task.Notes, model.Note{CreatedAt: created, Content: content})
+	}
+	return task, nil
+}
+
+// twTime formats a time as a Taskwarrior timestamp
+func twTime(t time.Time) string {
+	return t.UTC().Format(taskwarriorTimeLayout)
+}
+
+// parseTWTime parses a Taskwarrior timestamp, also accepting RFC 3339 as
+// written by some conversion scripts
+func parseTWTime(value string) (time.Time, error) {
+	if t, err := time.Parse(taskwarriorTimeLayout, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
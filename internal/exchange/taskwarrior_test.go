@@ -0,0 +1,165 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+func TestTaskwarriorUUID(t *testing.T) {
+	task := model.NewTask("abc", "Task", model.TypeTask)
+	uuid := TaskwarriorUUID(*task)
+	if len(uuid) != 36 || uuid[14] != '5' || !strings.ContainsAny(uuid[19:20], "89ab") {
+		t.Errorf("TaskwarriorUUID() = %q, want a version 5 UUID", uuid)
+	}
+	if TaskwarriorUUID(*task) != uuid {
+		t.Error("TaskwarriorUUID() should be stable")
+	}
+	task.Refs = map[string]string{TaskwarriorRef: "kept-uuid"}
+	if got := TaskwarriorUUID(*task); got != "kept-uuid" {
+		t.Errorf("TaskwarriorUUID() = %q, want the imported UUID", got)
+	}
+}
+
+func TestWriteTaskwarrior(t *testing.T) {
+	tasks := sampleTasks()
+	tasks[0].Labels = []string{"auth", "project:web", "priority:h", "two words"}
+	tasks[1].StatusChangedAt = time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	if err := WriteTaskwarrior(&buf, tasks); err != nil {
+		t.Fatalf("WriteTaskwarrior() error = %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 4 {
+		t.Errorf("WriteTaskwarrior() should write one task per line, got:\n%s", buf.String())
+	}
+
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not a JSON array: %v\n%s", err, buf.String())
+	}
+	first, second := got[0], got[1]
+	if first["description"] != "Fix login, again" || first["status"] != "pending" || first["taskid"] != "aaa" || first["tasktype"] != "bug" {
+		t.Errorf("first task = %v", first)
+	}
+	if first["project"] != "web" || first["priority"] != "H" || !reflect.DeepEqual(first["tags"], []any{"auth", "two-words"}) {
+		t.Errorf("first task project, priority and tags = %v, %v, %v", first["project"], first["priority"], first["tags"])
+	}
+	if first["entry"] != "20240301T093000Z" || first["modified"] != "20240301T103000Z" {
+		t.Errorf("first task times = %v, %v", first["entry"], first["modified"])
+	}
+	annotations, _ := first["annotations"].([]any)
+	if len(annotations) != 2 || annotations[0].(map[string]any)["description"] != `Seen on "staging"` {
+		t.Errorf("annotations = %v", first["annotations"])
+	}
+	if second["status"] != "completed" || second["end"] != "20240302T080000Z" || second["details"] != "Cover the\tCLI" {
+		t.Errorf("second task = %v", second)
+	}
+	if _, ok := second["tasktype"]; ok {
+		t.Error("tasktype should be left out for plain tasks")
+	}
+}
+
+func TestReadTaskwarrior(t *testing.T) {
+	input := `[
+{"id":1,"description":"Pay rent","entry":"20240301T093000Z","modified":"20240302T093000Z","status":"pending","start":"20240302T093000Z","uuid":"11111111-1111-4111-8111-111111111111","project":"home","priority":"M","tags":["money","monthly"],"due":"20240305T000000Z","urgency":8.1,"annotations":[{"entry":"20240301T100000Z","description":"Ask about the deposit"}]},
+{"id":0,"description":"Old thing","entry":"20240101T000000Z","end":"20240110T000000Z","status":"deleted","uuid":"22222222-2222-4222-8222-222222222222"},
+{"id":2,"description":"Later","entry":"20240101T000000Z","status":"waiting","wait":"20250101T000000Z","uuid":"33333333-3333-4333-8333-333333333333"},
+{"id":0,"description":"Weekly review","entry":"20240101T000000Z","status":"recurring","recur":"weekly","uuid":"44444444-4444-4444-8444-444444444444"},
+{"id":0,"description":"Shipped","entry":"20240101T000000Z","end":"20240201T120000Z","status":"completed","uuid":"55555555-5555-4555-8555-555555555555"},
+{"id":3,"description":"","status":"pending","uuid":"66666666-6666-4666-8666-666666666666"},
+{"id":4,"description":"Bad status","status":"sleeping","uuid":"77777777-7777-4777-8777-777777777777"}
+]`
+	tasks, rowErrors, err := ReadTaskwarrior(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadTaskwarrior() error = %v", err)
+	}
+	if len(tasks) != 4 {
+		t.Fatalf("ReadTaskwarrior() read %d task(s), want 4 (recurring template skipped)", len(tasks))
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Line != 7 || rowErrors[1].Line != 8 {
+		t.Errorf("row errors = %v, want lines 7 and 8", rowErrors)
+	}
+
+	rent := tasks[0]
+	if rent.Title != "Pay rent" || rent.Status != model.StatusProgress || rent.ID != "" {
+		t.Errorf("pending task = %+v", rent)
+	}
+	if rent.Refs[TaskwarriorRef] != "11111111-1111-4111-8111-111111111111" {
+		t.Errorf("refs = %v, want the UUID kept", rent.Refs)
+	}
+	if want := []string{"priority:m", "project:home", "money", "monthly"}; !reflect.DeepEqual(rent.Labels, want) {
+		t.Errorf("labels = %v, want %v", rent.Labels, want)
+	}
+	if rent.Due.IsZero() || rent.CreatedAt.Format(time.RFC3339) != "2024-03-01T09:30:00Z" || rent.UpdatedAt.Format(time.RFC3339) != "2024-03-02T09:30:00Z" {
+		t.Errorf("due = %v, created = %v, updated = %v", rent.Due, rent.CreatedAt, rent.UpdatedAt)
+	}
+	if len(rent.Notes) != 1 || rent.Notes[0].Content != "Ask about the deposit" || rent.Notes[0].CreatedAt.Hour() != 10 {
+		t.Errorf("notes = %+v", rent.Notes)
+	}
+
+	if tasks[1].Status != model.StatusAbandon || tasks[2].Status != model.StatusBlocked || tasks[3].Status != model.StatusDone {
+		t.Errorf("statuses = %s, %s, %s, want abandon, blocked, done", tasks[1].Status, tasks[2].Status, tasks[3].Status)
+	}
+	if tasks[3].StatusChangedAt.Format(time.RFC3339) != "2024-02-01T12:00:00Z" {
+		t.Errorf("completed at = %v", tasks[3].StatusChangedAt)
+	}
+}
+
+func TestReadTaskwarriorLines(t *testing.T) {
+	input := `{"description":"First","status":"pending","uuid":"a"}
+{"description":"Second","status":"completed","uuid":"b"}
+`
+	tasks, rowErrors, err := ReadTaskwarrior(strings.NewReader(input))
+	if err != nil || len(rowErrors) > 0 {
+		t.Fatalf("ReadTaskwarrior() error = %v, row errors = %v", err, rowErrors)
+	}
+	if len(tasks) != 2 || tasks[1].Title != "Second" || tasks[1].Status != model.StatusDone {
+		t.Errorf("ReadTaskwarrior() = %+v", tasks)
+	}
+
+	if _, _, err := ReadTaskwarrior(strings.NewReader("[{\"description\":")); err == nil {
+		t.Error("ReadTaskwarrior() should fail on malformed JSON")
+	}
+}
+
+func TestTaskwarriorRoundTrip(t *testing.T) {
+	tasks := sampleTasks()
+	tasks[0].Labels = []string{"priority:l", "project:api", "auth"}
+	tasks[0].Due = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tasks[0].Status = model.StatusBlocked
+	tasks[1].Status = model.StatusAbandon
+
+	var buf bytes.Buffer
+	if err := WriteTaskwarrior(&buf, tasks); err != nil {
+		t.Fatalf("WriteTaskwarrior() error = %v", err)
+	}
+	read, rowErrors, err := ReadTaskwarrior(&buf)
+	if err != nil || len(rowErrors) > 0 {
+		t.Fatalf("ReadTaskwarrior() error = %v, row errors = %v", err, rowErrors)
+	}
+	for i, got := range read {
+		want := tasks[i]
+		if got.ID != want.ID || got.Title != want.Title || got.Type != want.Type || got.Status != want.Status ||
+			!reflect.DeepEqual(got.Labels, want.Labels) || !got.Due.Equal(want.Due) ||
+			!got.CreatedAt.Equal(want.CreatedAt) || !reflect.DeepEqual(got.Description, want.Description) {
+			t.Errorf("task %d = %+v, want %+v", i, got, want)
+		}
+		if len(got.Notes) != len(want.Notes) {
+			t.Errorf("task %d notes = %+v, want %+v", i, got.Notes, want.Notes)
+		}
+		for j := range got.Notes {
+			if got.Notes[j].Content != want.Notes[j].Content || !got.Notes[j].CreatedAt.Equal(want.Notes[j].CreatedAt) {
+				t.Errorf("task %d note %d = %+v, want %+v", i, j, got.Notes[j], want.Notes[j])
+			}
+		}
+		if got.Refs[TaskwarriorRef] != TaskwarriorUUID(want) {
+			t.Errorf("task %d UUID = %q, want %q", i, got.Refs[TaskwarriorRef], TaskwarriorUUID(want))
+		}
+	}
+}
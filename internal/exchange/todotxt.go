@@ -0,0 +1,244 @@
+package exchange
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// PriorityLabelPrefix marks the label a todo.txt priority maps to: (A) is the
+// label "priority:a"
+const PriorityLabelPrefix = "priority:"
+
+// todo.txt key:value tags written by WriteTodoTxt. Other key:value pairs are
+// left in the title, so URLs in titles survive a round trip
+const (
+	todoKeyID       = "id"
+	todoKeyDue      = "due"
+	todoKeyType     = "type"
+	todoKeyStatus   = "status"
+	todoKeyPriority = "pri"
+)
+
+var todoPriorityPattern = regexp.MustCompile(`^\(([A-Z])\)$`)
+
+// WriteTodoTxt writes tasks in todo.txt format, one per line. Closed tasks
+// are marked complete with "x". Labels become +project tags, or @context
+// tags for labels that start with @, and a priority:x label becomes the
+// priority. The due date, a type other than task, a status todo.txt can't
+// express and the task ID are written as key:value tags
+func WriteTodoTxt(w io.Writer, tasks []model.Task) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range tasks {
+		fmt.Fprintln(bw, todoLine(t))
+	}
+	return bw.Flush()
+}
+
+// todoLine formats one task as a todo.txt line
+func todoLine(t model.Task) string {
+	var parts, tags []string
+
+	priority := ""
+	for _, label := range t.Labels {
+		if p, ok := labelPriority(label); ok && priority == "" {
+			priority = p
+			continue
+		}
+		label = TagLabel(label)
+		if strings.HasPrefix(label, "@") {
+			tags = append(tags, label)
+		} else {
+			tags = append(tags, "+"+label)
+		}
+	}
+
+	closed := t.Status.IsClosed()
+	if closed {
+		completed := t.StatusChangedAt
+		if completed.IsZero() {
+			completed = t.UpdatedAt
+		}
+		parts = append(parts, "x", completed.Format(model.DateLayout))
+		if priority != "" {
+			tags = append(tags, todoKeyPriority+":"+priority)
+		}
+	} else if priority != "" {
+		parts = append(parts, "("+priority+")")
+	}
+	parts = append(parts, t.CreatedAt.Format(model.DateLayout))
+	parts = append(parts, strings.Join(strings.Fields(t.Title), " "))
+
+	if !t.Due.IsZero() {
+		tags = append(tags, todoKeyDue+":"+t.Due.Format(model.DateLayout))
+	}
+	if t.Type != model.TypeTask {
+		tags = append(tags, todoKeyType+":"+string(t.Type))
+	}
+	if t.Status != model.StatusTodo && t.Status != model.StatusDone {
+		tags = append(tags, todoKeyStatus+":"+string(t.Status))
+	}
+	if t.ID != "" {
+		tags = append(tags, todoKeyID+":"+t.ID)
+	}
+
+	return strings.Join(append(parts, tags...), " ")
+}
+
+// TagLabel returns a label as written to formats whose tags can't contain
+// spaces: todo.txt projects and contexts, and Taskwarrior tags
+func TagLabel(label string) string {
+	return strings.Join(strings.Fields(label), "-")
+}
+
+// labelPriority returns the todo.txt priority for a priority:x label
+func labelPriority(label string) (string, bool) {
+	p, ok := strings.CutPrefix(label, PriorityLabelPrefix)
+	if !ok || len(p) != 1 || p[0] < 'a' || p[0] > 'z' {
+		return "", false
+	}
+	return strings.ToUpper(p), true
+}
+
+// ReadTodoTxt reads tasks from todo.txt lines. Tasks keep the ID from an id:
+// tag so callers can match them to existing tasks; tasks without one have
+// no ID. Lines that can't be read are skipped and returned as RowErrors
+func ReadTodoTxt(r io.Reader) ([]model.Task, []*RowError, error) {
+	var tasks []model.Task
+	var rowErrors []*RowError
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if text == "" {
+			continue
+		}
+		task, err := parseTodoLine(text)
+		if err != nil {
+			rowErrors = append(rowErrors, &RowError{Line: line, Err: err})
+			continue
+		}
+		tasks = append(tasks, *task)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return tasks, rowErrors, nil
+}
+
+// parseTodoLine parses one todo.txt line
+func parseTodoLine(line string) (*model.Task, error) {
+	fields := strings.Fields(line)
+
+	done := false
+	var completed, created time.Time
+	priority := ""
+
+	if fields[0] == "x" {
+		done = true
+		fields = fields[1:]
+		if len(fields) > 0 {
+			if d, err := time.Parse(model.DateLayout, fields[0]); err == nil {
+				completed = d
+				fields = fields[1:]
+			}
+		}
+	} else if m := todoPriorityPattern.FindStringSubmatch(fields[0]); m != nil {
+		priority = m[1]
+		fields = fields[1:]
+	}
+	if len(fields) > 0 {
+		if d, err := time.Parse(model.DateLayout, fields[0]); err == nil {
+			created = d
+			fields = fields[1:]
+		}
+	}
+
+	var words, labels []string
+	var id string
+	var due time.Time
+	taskType := model.TypeTask
+	status := model.Status("")
+
+	for _, f := range fields {
+		if len(f) > 1 && (f[0] == '+' || f[0] == '@') {
+			label := f
+			if f[0] == '+' {
+				label = f[1:]
+			}
+			labels = append(labels, label)
+			continue
+		}
+
+		key, value, ok := strings.Cut(f, ":")
+		if !ok || value == "" {
+			words = append(words, f)
+			continue
+		}
+		switch key {
+		case todoKeyID:
+			id = value
+		case todoKeyDue:
+			d, err := time.Parse(model.DateLayout, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid due date: %s (use YYYY-MM-DD)", value)
+			}
+			due = d
+		case todoKeyType:
+			tt, err := model.ParseTaskType(value)
+			if err != nil {
+				return nil, err
+			}
+			taskType = tt
+		case todoKeyStatus:
+			s, err := model.ParseStatus(value)
+			if err != nil {
+				return nil, err
+			}
+			status = s
+		case todoKeyPriority:
+			if len(value) == 1 {
+				priority = strings.ToUpper(value)
+			} else {
+				words = append(words, f)
+			}
+		default:
+			words = append(words, f)
+		}
+	}
+
+	if len(words) == 0 {
+		return nil, fmt.Errorf("%s is required", colTitle)
+	}
+
+	task := model.NewTask(id, strings.Join(words, " "), taskType)
+	if priority != "" {
+		labels = append([]string{PriorityLabelPrefix + strings.ToLower(priority)}, labels...)
+	}
+	task.Labels = SplitLabels(strings.Join(labels, ","))
+	task.Due = due
+
+	switch {
+	case done && (status == "" || !status.IsClosed()):
+		task.Status = model.StatusDone
+	case status != "":
+		task.Status = status
+	}
+
+	if !created.IsZero() {
+		task.CreatedAt = created
+		task.UpdatedAt = created
+	}
+	if !completed.IsZero() && task.Status.IsClosed() {
+		task.StatusChangedAt = completed
+		if completed.After(task.UpdatedAt) {
+			task.UpdatedAt = completed
+		}
+	}
+	return task, nil
+}
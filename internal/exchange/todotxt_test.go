@@ -0,0 +1,107 @@
+package exchange
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+func TestWriteTodoTxt(t *testing.T) {
+	tasks := sampleTasks()
+	tasks[0].Labels = []string{"auth", "priority:a", "@office", "two words"}
+	tasks[0].Due = time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	tasks[1].StatusChangedAt = time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)
+	blocked := model.NewTask("ccc", "Waiting on review", model.TypeFeature)
+	blocked.CreatedAt = tasks[0].CreatedAt
+	blocked.Status = model.StatusBlocked
+	tasks = append(tasks, *blocked)
+
+	var buf bytes.Buffer
+	if err := WriteTodoTxt(&buf, tasks); err != nil {
+		t.Fatalf("WriteTodoTxt() error = %v", err)
+	}
+
+	want := `(A) 2024-03-01 Fix login, again +auth @office +two-words due:2024-04-01 type:bug id:aaa
+x 2024-03-02 2024-03-01 Write docs id:bbb
+2024-03-01 Waiting on review type:feature status:blocked id:ccc
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteTodoTxt() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestReadTodoTxt(t *testing.T) {
+	input := "\ufeff(B) 2024-03-01 Call Mom +family @phone due:2024-03-05 see http://example.com\n" +
+		"\n" +
+		"x 2024-03-04 2024-03-02 Pay rent pri:A id:abc\n" +
+		"Plain task type:bug status:progress\n" +
+		"Bad date due:tomorrow\n" +
+		"+onlyaproject\n"
+
+	tasks, rowErrors, err := ReadTodoTxt(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadTodoTxt() error = %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("ReadTodoTxt() read %d task(s), want 3", len(tasks))
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Line != 5 || rowErrors[1].Line != 6 {
+		t.Errorf("row errors = %v, want lines 5 and 6", rowErrors)
+	}
+
+	call := tasks[0]
+	if call.Title != "Call Mom see http://example.com" || call.ID != "" {
+		t.Errorf("title = %q, id = %q", call.Title, call.ID)
+	}
+	if want := []string{"priority:b", "family", "@phone"}; !reflect.DeepEqual(call.Labels, want) {
+		t.Errorf("labels = %v, want %v", call.Labels, want)
+	}
+	if call.Due.Format(model.DateLayout) != "2024-03-05" || call.CreatedAt.Format(model.DateLayout) != "2024-03-01" {
+		t.Errorf("due = %v, created = %v", call.Due, call.CreatedAt)
+	}
+	if call.Status != model.StatusTodo {
+		t.Errorf("status = %s, want todo", call.Status)
+	}
+
+	rent := tasks[1]
+	if rent.ID != "abc" || rent.Status != model.StatusDone || !reflect.DeepEqual(rent.Labels, []string{"priority:a"}) {
+		t.Errorf("completed task = %+v", rent)
+	}
+	if rent.StatusChangedAt.Format(model.DateLayout) != "2024-03-04" {
+		t.Errorf("completion date = %v, want 2024-03-04", rent.StatusChangedAt)
+	}
+
+	if tasks[2].Type != model.TypeBug || tasks[2].Status != model.StatusProgress {
+		t.Errorf("type and status tags = %s, %s", tasks[2].Type, tasks[2].Status)
+	}
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	tasks := sampleTasks()
+	tasks[0].Labels = []string{"priority:c", "auth", "@home"}
+	tasks[0].Due = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tasks[1].Status = model.StatusAbandon
+
+	var buf bytes.Buffer
+	if err := WriteTodoTxt(&buf, tasks); err != nil {
+		t.Fatalf("WriteTodoTxt() error = %v", err)
+	}
+	read, rowErrors, err := ReadTodoTxt(&buf)
+	if err != nil || len(rowErrors) > 0 {
+		t.Fatalf("ReadTodoTxt() error = %v, row errors = %v", err, rowErrors)
+	}
+	if len(read) != len(tasks) {
+		t.Fatalf("read %d task(s), want %d", len(read), len(tasks))
+	}
+	for i, got := range read {
+		want := tasks[i]
+		if got.ID != want.ID || got.Title != want.Title || got.Type != want.Type || got.Status != want.Status ||
+			!reflect.DeepEqual(got.Labels, want.Labels) || !got.Due.Equal(want.Due) {
+			t.Errorf("task %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package git
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// maxSlugLength keeps branch names from long titles manageable
+const maxSlugLength = 40
+
+// CurrentBranch returns the checked out branch, or an error when HEAD is detached
+func CurrentBranch(dir string) (string, error) {
+	branch, err := Run(dir, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		if errors.Is(err, ErrNotRepository) {
+			return "", err
+		}
+		return "", errors.New("HEAD is not on a branch")
+	}
+	return branch, nil
+}
+
+// BranchExists reports whether a local branch exists
+func BranchExists(dir, branch string) bool {
+	_, err := Run(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// Checkout switches to a branch, creating it from HEAD if it doesn't exist
+func Checkout(dir, branch string) error {
+	if BranchExists(dir, branch) {
+		_, err := Run(dir, "checkout", "--quiet", branch)
+		return err
+	}
+	_, err := Run(dir, "checkout", "--quiet", "-b", branch)
+	return err
+}
+
+// BranchName expands a branch pattern for a task. The pattern may use {id},
+// {type} and {slug}, a lowercase, dash separated form of the title
+func BranchName(pattern string, task *model.Task) string {
+	return strings.NewReplacer(
+		"{id}", task.ID,
+		"{type}", string(task.Type),
+		"{slug}", Slug(task.Title),
+	).Replace(pattern)
+}
+
+// TaskIDFromBranch extracts the task ID from a branch named by a pattern. It
+// returns false when the branch doesn't match or the pattern has no {id}
+func TaskIDFromBranch(pattern, branch string) (string, bool) {
+	if !strings.Contains(pattern, "{id}") {
+		return "", false
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for rest := pattern; rest != ""; {
+		start := strings.Index(rest, "{")
+		end := -1
+		if start >= 0 {
+			end = strings.Index(rest[start:], "}")
+		}
+		if end < 0 {
+			expr.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		end += start
+		expr.WriteString(regexp.QuoteMeta(rest[:start]))
+		switch rest[start : end+1] {
+		case "{id}":
+			expr.WriteString(`(?P<id>[a-z0-9]+)`)
+		case "{type}":
+			expr.WriteString(`[a-z]+`)
+		case "{slug}":
+			expr.WriteString(`[a-z0-9-]*`)
+		default:
+			expr.WriteString(regexp.QuoteMeta(rest[start : end+1]))
+		}
+		rest = rest[end+1:]
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return "", false
+	}
+	m := re.FindStringSubmatch(branch)
+	if m == nil {
+		return "", false
+	}
+	return m[re.SubexpIndex("id")], true
+}
+
+// Slug turns a title into a lowercase, dash separated branch name component
+func Slug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		if i := strings.LastIndex(slug, "-"); i > 0 {
+			slug = slug[:i]
+		}
+	}
+	if slug == "" {
+		return "task"
+	}
+	return slug
+}
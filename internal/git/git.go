@@ -0,0 +1,95 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotRepository is returned when the directory is not inside a git work tree
+var ErrNotRepository = errors.New("not a git repository")
+
+// Commit is a commit's hash and message
+type Commit struct {
+	Hash    string
+	Subject string
+	Message string
+}
+
+// ShortHash returns the abbreviated commit hash
+func (c *Commit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
+
+// Run runs git in dir and returns its trimmed output. Failures include
+// whatever git printed to stderr
+func Run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "not a git repository") {
+			return "", ErrNotRepository
+		}
+		if msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// HooksDir returns the directory git runs hooks from, honouring
+// core.hooksPath and worktrees
+func HooksDir(dir string) (string, error) {
+	path, err := Run(dir, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
+// HeadCommit returns the commit HEAD points to
+func HeadCommit(dir string) (*Commit, error) {
+	out, err := Run(dir, "log", "-1", "--format=%H%n%B", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	hash, message, _ := strings.Cut(out, "\n")
+	subject, _, _ := strings.Cut(message, "\n")
+	return &Commit{Hash: hash, Subject: strings.TrimSpace(subject), Message: message}, nil
+}
+
+// CommitTime returns when the commit a revision such as a tag names was made
+func CommitTime(dir, rev string) (time.Time, error) {
+	out, err := Run(dir, "log", "-1", "--format=%cI", rev, "--")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, out)
+}
+
+// LatestTag returns the most recent tag reachable from HEAD, or "" if there is none
+func LatestTag(dir string) (string, error) {
+	tag, err := Run(dir, "describe", "--tags", "--abbrev=0")
+	if err != nil {
+		if errors.Is(err, ErrNotRepository) {
+			return "", err
+		}
+		return "", nil
+	}
+	return tag, nil
+}
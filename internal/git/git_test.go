@@ -0,0 +1,182 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// initRepo creates a git repository with an identity configured
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+	} {
+		if _, err := Run(dir, args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+	return dir
+}
+
+func TestParseTrailers(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []Trailer
+	}{
+		{
+			name:    "subject only",
+			message: "Fixes: abc",
+		},
+		{
+			name:    "trailers",
+			message: "Fix the parser\n\nLonger explanation.\n\nTask: abc\nSigned-off-by: A <a@example.com>\n",
+			want:    []Trailer{{"Task", "abc"}, {"Signed-off-by", "A <a@example.com>"}},
+		},
+		{
+			name:    "continuation",
+			message: "Subject\n\nFixes: abc,\n  def",
+			want:    []Trailer{{"Fixes", "abc, def"}},
+		},
+		{
+			name:    "prose in last paragraph",
+			message: "Subject\n\nThis fixes: abc\nand more",
+		},
+		{
+			name:    "comments and scissors",
+			message: "Subject\n\nTask: abc\n# Please enter the commit message\n# ------------------------ >8 ------------------------\ndiff --git a/x b/x\n",
+			want:    []Trailer{{"Task", "abc"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseTrailers(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTrailers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	message := "Subject\n\nTask: abc, def\nfixes: def, #123\nCloses: ghi\nRefs: https://example.com/x\nReviewed-by: someone\n"
+	want := []Reference{{"abc", false}, {"def", true}}
+	if got := References(message); !reflect.DeepEqual(got, want) {
+		t.Errorf("References() = %v, want %v", got, want)
+	}
+
+	// A kernel-style Fixes: names a commit, and the words of its subject
+	// are not tasks
+	message = "Subject\n\nFixes: 1a2b3c4d5e6f (\"net: fix null deref\")\nTask: abc #12 def\n"
+	want = []Reference{{"abc", false}}
+	if got := References(message); !reflect.DeepEqual(got, want) {
+		t.Errorf("References() = %v, want %v", got, want)
+	}
+}
+
+func TestHeadCommitAndHooksDir(t *testing.T) {
+	dir := initRepo(t)
+
+	if _, err := HeadCommit(dir); err == nil {
+		t.Error("HeadCommit() in an empty repository should fail")
+	}
+
+	os.WriteFile(filepath.Join(dir, "file"), []byte("x"), 0644)
+	Run(dir, "add", "file")
+	if _, err := Run(dir, "commit", "-q", "-m", "Add file\n\nTask: abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := HeadCommit(dir)
+	if err != nil {
+		t.Fatalf("HeadCommit() error = %v", err)
+	}
+	if len(c.Hash) != 40 || len(c.ShortHash()) != 7 || c.Subject != "Add file" {
+		t.Errorf("HeadCommit() = %+v", c)
+	}
+	if refs := References(c.Message); len(refs) != 1 || refs[0].ID != "abc" {
+		t.Errorf("References(%q) = %v", c.Message, refs)
+	}
+
+	hooks, err := HooksDir(dir)
+	if err != nil || hooks != filepath.Join(dir, ".git", "hooks") {
+		t.Errorf("HooksDir() = %q, %v", hooks, err)
+	}
+
+	if _, err := Run(t.TempDir(), "status"); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("Run() outside a repository error = %v", err)
+	}
+}
+
+func TestBranchName(t *testing.T) {
+	task := model.NewTask("9nk", "Fix the parser's: crash!", model.TypeBug)
+	if got := BranchName("{type}/{id}-{slug}", task); got != "bug/9nk-fix-the-parser-s-crash" {
+		t.Errorf("BranchName() = %q", got)
+	}
+
+	long := model.NewTask("abc", "A title that is much too long to fit in a branch name comfortably", model.TypeTask)
+	if got := Slug(long.Title); got != "a-title-that-is-much-too-long-to-fit-in" {
+		t.Errorf("Slug() = %q", got)
+	}
+	if got := Slug("!!!"); got != "task" {
+		t.Errorf("Slug() of punctuation = %q", got)
+	}
+}
+
+func TestTaskIDFromBranch(t *testing.T) {
+	tests := []struct {
+		pattern, branch string
+		want            string
+		ok              bool
+	}{
+		{"{type}/{id}-{slug}", "bug/9nk-fix-the-parser", "9nk", true},
+		{"{type}/{id}-{slug}", "main", "", false},
+		{"{type}/{id}-{slug}", "bug/9nk", "", false},
+		{"task-{id}", "task-ab1", "ab1", true},
+		{"users/{user}/{id}", "users/{user}/ab1", "ab1", true},
+		{"{slug}", "anything", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := TaskIDFromBranch(tt.pattern, tt.branch)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("TaskIDFromBranch(%q, %q) = %q, %v, want %q, %v", tt.pattern, tt.branch, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	dir := initRepo(t)
+	if _, err := Run(dir, "commit", "-q", "--allow-empty", "-m", "Initial"); err != nil {
+		t.Fatal(err)
+	}
+	start, _ := CurrentBranch(dir)
+
+	if err := Checkout(dir, "bug/abc-crash"); err != nil {
+		t.Fatalf("Checkout() new branch error = %v", err)
+	}
+	if branch, err := CurrentBranch(dir); err != nil || branch != "bug/abc-crash" {
+		t.Errorf("CurrentBranch() = %q, %v", branch, err)
+	}
+	if err := Checkout(dir, start); err != nil {
+		t.Fatalf("Checkout() existing branch error = %v", err)
+	}
+	if !BranchExists(dir, "bug/abc-crash") || BranchExists(dir, "missing") {
+		t.Error("BranchExists() is wrong")
+	}
+
+	Run(dir, "checkout", "-q", "--detach")
+	if _, err := CurrentBranch(dir); err == nil {
+		t.Error("CurrentBranch() with a detached HEAD should fail")
+	}
+}
@@ -0,0 +1,113 @@
+package git
+
+import (
+	"strings"
+
+	"github.com/jackreid/task/internal/id"
+)
+
+// Trailer is a "Key: value" line at the end of a commit message
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+// Reference is a task named in a commit message trailer
+type Reference struct {
+	ID string
+	// Closes is set when the commit finishes the task (Fixes:)
+	Closes bool
+}
+
+// referenceKeys maps the trailer keys that name tasks, lowercased, to whether
+// they close the task
+var referenceKeys = map[string]bool{
+	"task":  false,
+	"fixes": true,
+}
+
+// CleanMessage removes what git strips from a message being edited: comment
+// lines and everything below the scissors line of a verbose commit
+func CleanMessage(message string) string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, "# ") && strings.Contains(line, ">8") {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// ParseTrailers returns the trailers of a commit message. Like git, only the
+// last paragraph is considered, it must not be the subject and every line in
+// it must be a trailer or the indented continuation of one
+func ParseTrailers(message string) []Trailer {
+	message = CleanMessage(message)
+	paragraphs := strings.Split(message, "\n\n")
+	if len(paragraphs) < 2 {
+		return nil
+	}
+
+	var trailers []Trailer
+	for _, line := range strings.Split(strings.TrimSpace(paragraphs[len(paragraphs)-1]), "\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(trailers) > 0 {
+			last := &trailers[len(trailers)-1]
+			last.Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || !validKey(key) {
+			return nil
+		}
+		trailers = append(trailers, Trailer{Key: key, Value: strings.TrimSpace(value)})
+	}
+	return trailers
+}
+
+// References returns the tasks named by a commit message's trailers, in the
+// order they first appear. Trailer keys are case-insensitive and a value may
+// list several IDs separated by commas or spaces. A value is read up to the
+// first word that can't be a task ID, so "#123" issue numbers, URLs and the
+// quoted subject after a commit hash are skipped
+func References(message string) []Reference {
+	var refs []Reference
+	index := make(map[string]int)
+	for _, t := range ParseTrailers(message) {
+		closes, ok := referenceKeys[strings.ToLower(t.Key)]
+		if !ok {
+			continue
+		}
+		for _, value := range strings.FieldsFunc(t.Value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			if !id.Valid(value) {
+				break
+			}
+			if i, seen := index[value]; seen {
+				refs[i].Closes = refs[i].Closes || closes
+				continue
+			}
+			index[value] = len(refs)
+			refs = append(refs, Reference{ID: value, Closes: closes})
+		}
+	}
+	return refs
+}
+
+// validKey reports whether key can be a trailer key: letters, digits and dashes
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
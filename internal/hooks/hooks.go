@@ -0,0 +1,209 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+// Dir is the directory within .task that holds the hook executables
+const Dir = "hooks"
+
+// DefaultTimeout is how long a hook may run before it is killed
+const DefaultTimeout = time.Minute
+
+// envHook is set to the running hook's name. Hooks are not run while it is
+// set, so a hook that calls task itself can't trigger hooks recursively
+const envHook = "TASK_HOOK"
+
+// Error reports a pre-* hook that rejected a change
+type Error struct {
+	Hook   string
+	Stderr string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Stderr != "" {
+		return fmt.Sprintf("%s hook failed: %s", e.Hook, e.Stderr)
+	}
+	return fmt.Sprintf("%s hook failed: %v", e.Hook, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Runner runs the executables in .task/hooks around store mutations:
+//
+//	pre-create, post-create                 a task is added
+//	pre-update, post-update                 a task is changed
+//	pre-status-change, post-status-change   a task's status is changed
+//	pre-delete, post-delete                 a task is removed
+//	pre-archive, post-archive               a task is moved into the archive
+//	pre-restore, post-restore               a task is restored from the archive
+//
+// Hooks run in the project directory with the task as JSON on stdin and
+// TASK_EVENT, TASK_ID, TASK_COLLECTION, TASK_BEFORE, TASK_AFTER,
+// TASK_OLD_STATUS and TASK_NEW_STATUS in the environment. A pre-* hook that
+// exits non-zero aborts the change; a failing post-* hook only prints a warning
+type Runner struct {
+	dir     string
+	workDir string
+	out     io.Writer
+	// Timeout limits how long each hook may run
+	Timeout time.Duration
+}
+
+// New creates a runner for the hooks in taskDir (the .task directory).
+// Hook output and warnings are written to out
+func New(taskDir string, out io.Writer) *Runner {
+	return &Runner{
+		dir:     filepath.Join(taskDir, Dir),
+		workDir: filepath.Dir(taskDir),
+		out:     out,
+		Timeout: DefaultTimeout,
+	}
+}
+
+// names returns the hooks to run for a mutation, without their pre-/post- prefix
+func names(m store.Mutation) []string {
+	switch m.Event {
+	case store.EventCreate:
+		return []string{"create"}
+	case store.EventDelete:
+		return []string{"delete"}
+	case store.EventArchive:
+		return []string{"archive"}
+	case store.EventRestore:
+		return []string{"restore"}
+	}
+	if m.Before != nil && m.After != nil && m.Before.Status != m.After.Status {
+		return []string{"update", "status-change"}
+	}
+	return []string{"update"}
+}
+
+// BeforeMutation runs the pre-* hooks, returning an *Error if one fails
+func (r *Runner) BeforeMutation(m store.Mutation) error {
+	if os.Getenv(envHook) != "" {
+		return nil
+	}
+	for _, name := range names(m) {
+		if err := r.run("pre-"+name, m, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AfterMutation runs the post-* hooks
+func (r *Runner) AfterMutation(m store.Mutation) {
+	if os.Getenv(envHook) != "" {
+		return
+	}
+	for _, name := range names(m) {
+		if err := r.run("post-"+name, m, false); err != nil {
+			fmt.Fprintf(r.out, "Warning: %v\n", err)
+		}
+	}
+}
+
+// run executes a hook if it is installed. The stderr of pre-* hooks is held
+// back so that it can be reported as the reason the change was rejected
+func (r *Runner) run(name string, m store.Mutation, pre bool) error {
+	path := filepath.Join(r.dir, name)
+	if !executable(path) {
+		return nil
+	}
+
+	input, err := json.Marshal(m.Task())
+	if err != nil {
+		return err
+	}
+	env, err := environment(name, m)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Dir = r.workDir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = r.out
+
+	var stderr bytes.Buffer
+	if pre {
+		cmd.Stderr = &stderr
+	} else {
+		cmd.Stderr = r.out
+	}
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", r.Timeout)
+	}
+	if err != nil {
+		return &Error{Hook: name, Stderr: strings.TrimSpace(stderr.String()), Err: err}
+	}
+	r.out.Write(stderr.Bytes())
+	return nil
+}
+
+// environment returns the variables describing a mutation to a hook
+func environment(name string, m store.Mutation) ([]string, error) {
+	task := m.Task()
+	env := []string{
+		envHook + "=" + name,
+		"TASK_EVENT=" + string(m.Event),
+		"TASK_ID=" + task.ID,
+		"TASK_COLLECTION=" + m.Collection,
+	}
+
+	for _, v := range []struct {
+		key  string
+		task *model.Task
+	}{{"TASK_BEFORE", m.Before}, {"TASK_AFTER", m.After}} {
+		value := ""
+		if v.task != nil {
+			data, err := json.Marshal(v.task)
+			if err != nil {
+				return nil, err
+			}
+			value = string(data)
+		}
+		env = append(env, v.key+"="+value)
+	}
+
+	if m.Before != nil {
+		env = append(env, "TASK_OLD_STATUS="+string(m.Before.Status))
+	}
+	if m.After != nil {
+		env = append(env, "TASK_NEW_STATUS="+string(m.After.Status))
+	}
+	return env, nil
+}
+
+// executable reports whether path is a file that can be run as a hook.
+// Like git, hooks without the executable bit are ignored
+func executable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode()&0111 != 0
+}
@@ -0,0 +1,223 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+// setup creates a store with a hook runner attached
+func setup(t *testing.T) (*store.Store, *bytes.Buffer) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need a POSIX shell")
+	}
+	t.Setenv(envHook, "")
+
+	s := store.New(t.TempDir())
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	var out bytes.Buffer
+	s.AddHook(New(s.Dir(), &out))
+	return s, &out
+}
+
+// writeHook installs a shell script as a hook
+func writeHook(t *testing.T, s *store.Store, name, script string, mode os.FileMode) {
+	t.Helper()
+	dir := filepath.Join(s.Dir(), Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPreHookAbortsWithStderr(t *testing.T) {
+	s, _ := setup(t)
+	writeHook(t, s, "pre-create", `echo "titles must start with a capital" >&2; exit 1`, 0755)
+
+	err := s.Add(model.NewTask("aaa", "lowercase", model.TypeTask))
+	var hookErr *Error
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("Add() error = %v, want a hook error", err)
+	}
+	if err.Error() != "pre-create hook failed: titles must start with a capital" {
+		t.Errorf("error = %q", err.Error())
+	}
+	if tasks, _ := s.Load(); len(tasks) != 0 {
+		t.Error("a failing pre-create hook should abort the add")
+	}
+}
+
+func TestPostHookEnvironment(t *testing.T) {
+	s, out := setup(t)
+	log := filepath.Join(t.TempDir(), "log")
+	writeHook(t, s, "post-update", `
+{
+  echo "hook=$TASK_HOOK event=$TASK_EVENT id=$TASK_ID collection=$TASK_COLLECTION"
+  echo "status=$TASK_OLD_STATUS->$TASK_NEW_STATUS"
+  echo "before=$TASK_BEFORE"
+  echo "stdin=$(cat)"
+  echo "pwd=$(pwd)"
+} > `+log+`
+echo "post-update ran"`, 0755)
+	writeHook(t, s, "post-status-change", `echo "status changed"`, 0755)
+
+	task := model.NewTask("aaa", "Original", model.TypeTask)
+	s.Add(task)
+	task.SetTitle("Renamed")
+	if err := s.Update(task); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("post-update hook did not run: %v", err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		"hook=post-update event=update id=aaa collection=default",
+		"status=todo->todo",
+		`"title":"Original"`,
+		`"id":"aaa","title":"Renamed"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("hook log missing %q:\n%s", want, got)
+		}
+	}
+	projectDir, _ := filepath.EvalSymlinks(filepath.Dir(s.Dir()))
+	if !strings.Contains(got, "pwd="+projectDir) {
+		t.Errorf("hook should run in the project directory %s:\n%s", projectDir, got)
+	}
+	if out.String() != "post-update ran\n" {
+		t.Errorf("output = %q, want only post-update (status unchanged)", out.String())
+	}
+
+	out.Reset()
+	task.SetStatus(model.StatusDone)
+	s.Update(task)
+	if out.String() != "post-update ran\nstatus changed\n" {
+		t.Errorf("output after status change = %q", out.String())
+	}
+}
+
+func TestPreDeleteAndPostFailure(t *testing.T) {
+	s, out := setup(t)
+	writeHook(t, s, "pre-delete", `
+task=$(cat)
+case "$task" in *'"status":"progress"'*) echo "task is in progress" >&2; exit 1;; esac`, 0755)
+	writeHook(t, s, "post-delete", `exit 3`, 0755)
+
+	task := model.NewTask("aaa", "Busy", model.TypeTask)
+	task.SetStatus(model.StatusProgress)
+	s.Add(task)
+	s.Add(model.NewTask("bbb", "Idle", model.TypeTask))
+
+	if err := s.DeleteMany([]string{"aaa", "bbb"}); err == nil || !strings.Contains(err.Error(), "task is in progress") {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+	if tasks, _ := s.Load(); len(tasks) != 2 {
+		t.Error("a failing pre-delete hook should abort the whole batch")
+	}
+
+	if err := s.Delete("bbb"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Warning: post-delete hook failed: exit status 3") {
+		t.Errorf("a failing post hook should warn, got %q", out.String())
+	}
+}
+
+func TestArchiveAndRestoreHooks(t *testing.T) {
+	s, out := setup(t)
+	writeHook(t, s, "pre-delete", `echo "no deleting" >&2; exit 1`, 0755)
+	writeHook(t, s, "post-archive", `echo "$TASK_HOOK $TASK_EVENT $TASK_ID"`, 0755)
+	writeHook(t, s, "post-restore", `echo "$TASK_HOOK $TASK_EVENT $TASK_ID"`, 0755)
+
+	task := model.NewTask("aaa", "Shipped", model.TypeTask)
+	task.SetStatus(model.StatusDone)
+	s.Add(task)
+
+	// Archiving is not a delete, so pre-delete can't block it
+	if _, err := s.Archive(time.Time{}); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	if _, err := s.Restore("aaa"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := out.String(); got != "post-archive archive aaa\npost-restore restore aaa\n" {
+		t.Errorf("hook output = %q", got)
+	}
+}
+
+func TestSkippedHooks(t *testing.T) {
+	s, out := setup(t)
+	writeHook(t, s, "pre-create", `echo "should not run" >&2; exit 1`, 0644)
+
+	if err := s.Add(model.NewTask("aaa", "Plain file", model.TypeTask)); err != nil {
+		t.Errorf("non-executable hooks should be ignored, got %v", err)
+	}
+
+	writeHook(t, s, "pre-create", `exit 1`, 0755)
+	t.Setenv(envHook, "post-update")
+	if err := s.Add(model.NewTask("bbb", "From a hook", model.TypeTask)); err != nil {
+		t.Errorf("hooks should not run from within a hook, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	s, _ := setup(t)
+	writeHook(t, s, "pre-create", `exec sleep 5`, 0755)
+
+	runner := New(s.Dir(), &bytes.Buffer{})
+	runner.Timeout = 50 * time.Millisecond
+	s2 := store.New(filepath.Dir(s.Dir()))
+	s2.AddHook(runner)
+
+	err := s2.Add(model.NewTask("aaa", "Slow", model.TypeTask))
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Add() error = %v, want a timeout", err)
+	}
+}
+
+func TestEnvironmentForCreate(t *testing.T) {
+	task := model.NewTask("aaa", "New", model.TypeBug)
+	env, err := environment("pre-create", store.Mutation{Event: store.EventCreate, Collection: "default", After: task})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars := make(map[string]string)
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		vars[k] = v
+	}
+	if vars["TASK_BEFORE"] != "" {
+		t.Errorf("TASK_BEFORE = %q, want empty for a create", vars["TASK_BEFORE"])
+	}
+	var after model.Task
+	if err := json.Unmarshal([]byte(vars["TASK_AFTER"]), &after); err != nil || after.Type != model.TypeBug {
+		t.Errorf("TASK_AFTER = %q", vars["TASK_AFTER"])
+	}
+	if _, ok := vars["TASK_OLD_STATUS"]; ok {
+		t.Error("TASK_OLD_STATUS should not be set for a create")
+	}
+	if vars["TASK_NEW_STATUS"] != "todo" {
+		t.Errorf("TASK_NEW_STATUS = %q", vars["TASK_NEW_STATUS"])
+	}
+}
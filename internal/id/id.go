@@ -3,6 +3,7 @@ package id
 import (
 	"crypto/rand"
 	"math/big"
+	"strings"
 )
 
 // charset for ID generation (alphanumeric, lowercase)
@@ -13,6 +14,20 @@ func Generate() (string, error) {
 	return generateWithLength(3)
 }
 
+// Valid reports whether s has the shape of a generated task ID: 3 or 4
+// characters from the charset
+func Valid(s string) bool {
+	if len(s) < 3 || len(s) > 4 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune(charset, r) {
+			return false
+		}
+	}
+	return true
+}
+
 // GenerateNoteID creates a note ID in the format "taskID-xxx"
 func GenerateNoteID(taskID string) (string, error) {
 	suffix, err := generateWithLength(3)
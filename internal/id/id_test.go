@@ -153,3 +153,16 @@ func TestGenerateEmptyExisting(t *testing.T) {
 		t.Errorf("GenerateUnique({}) length = %d, want 3", len(id2))
 	}
 }
+
+func TestValid(t *testing.T) {
+	for _, s := range []string{"abc", "9nk", "ab12"} {
+		if !Valid(s) {
+			t.Errorf("Valid(%q) = false, want true", s)
+		}
+	}
+	for _, s := range []string{"", "ab", "abcde", "ABC", "#12", "a-b", "1a2b3c4d5e6f"} {
+		if Valid(s) {
+			t.Errorf("Valid(%q) = true, want false", s)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -31,6 +32,11 @@ func (s Status) IsValid() bool {
 	return false
 }
 
+// IsClosed reports whether the status marks a task as finished (done or abandon)
+func (s Status) IsClosed() bool {
+	return s == StatusDone || s == StatusAbandon
+}
+
 // String returns the string representation of the status
 func (s Status) String() string {
 	return string(s)
@@ -82,6 +88,9 @@ func ParseTaskType(s string) (TaskType, error) {
 	return taskType, nil
 }
 
+// DateLayout is the format of dates without a time, such as due dates
+const DateLayout = "2006-01-02"
+
 // Note represents a note attached to a task
 type Note struct {
 	ID        string    `json:"id"`
@@ -100,6 +109,30 @@ type Task struct {
 	Status      Status    `json:"status"`
 	Labels      []string  `json:"labels"`
 	Notes       []Note    `json:"notes"`
+	// Branch is the git branch the task is being worked on in
+	Branch string `json:"branch,omitempty"`
+	// StatusChangedAt is when the status last changed, zero if it never has
+	StatusChangedAt time.Time `json:"status_changed_at"`
+	// Source is the code comment the task was created from by task scan
+	Source *Source `json:"source,omitempty"`
+	// Due is the date the task is due, zero if it has none
+	Due time.Time `json:"due"`
+	// Refs holds the task's identifiers in other tools, keyed by tool name
+	Refs map[string]string `json:"refs,omitempty"`
+}
+
+// Source locates the TODO-style comment in the code that a task tracks
+type Source struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	// Fingerprint identifies the comment across scans while it moves around
+	// its file
+	Fingerprint string `json:"fingerprint"`
+}
+
+// String returns the location as file:line
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
 }
 
 // NewTask creates a new task with the given title
@@ -140,6 +173,52 @@ func (t *Task) AddLabel(label string) {
 	t.UpdatedAt = time.Now().UTC()
 }
 
+// RemoveLabel removes a label from the task, returning whether it was present
+func (t *Task) RemoveLabel(label string) bool {
+	for i, l := range t.Labels {
+		if l == label {
+			t.Labels = append(t.Labels[:i:i], t.Labels[i+1:]...)
+			t.UpdatedAt = time.Now().UTC()
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceLabels replaces any of the from labels with the to label, keeping
+// the position of the first replaced label, or removes them when to is
+// empty. Returns whether anything changed
+func (t *Task) ReplaceLabels(from []string, to string) bool {
+	replace := make(map[string]bool, len(from))
+	for _, l := range from {
+		replace[l] = true
+	}
+
+	changed := false
+	labels := make([]string, 0, len(t.Labels))
+	seen := make(map[string]bool, len(t.Labels))
+	for _, l := range t.Labels {
+		if replace[l] && l != to {
+			changed = true
+			if to == "" {
+				continue
+			}
+			l = to
+		}
+		if seen[l] {
+			continue
+		}
+		seen[l] = true
+		labels = append(labels, l)
+	}
+
+	if changed {
+		t.Labels = labels
+		t.UpdatedAt = time.Now().UTC()
+	}
+	return changed
+}
+
 // SetLabels replaces the task's labels
 func (t *Task) SetLabels(labels []string) {
 	t.Labels = labels
@@ -151,8 +230,12 @@ func (t *Task) SetStatus(status Status) error {
 	if !status.IsValid() {
 		return fmt.Errorf("invalid status: %s", status)
 	}
+	now := time.Now().UTC()
+	if t.Status != status {
+		t.StatusChangedAt = now
+	}
 	t.Status = status
-	t.UpdatedAt = time.Now().UTC()
+	t.UpdatedAt = now
 	return nil
 }
 
@@ -172,6 +255,38 @@ func (t *Task) SetTitle(title string) {
 	t.UpdatedAt = time.Now().UTC()
 }
 
+// SetBranch records the git branch the task is being worked on in
+func (t *Task) SetBranch(branch string) {
+	t.Branch = branch
+	t.UpdatedAt = time.Now().UTC()
+}
+
+// SetDue sets the due date, or clears it when zero
+func (t *Task) SetDue(due time.Time) {
+	t.Due = due
+	t.UpdatedAt = time.Now().UTC()
+}
+
+// SetRef records the task's identifier in another tool, or removes it when
+// ref is empty
+func (t *Task) SetRef(tool, ref string) {
+	if ref == "" {
+		delete(t.Refs, tool)
+	} else {
+		if t.Refs == nil {
+			t.Refs = make(map[string]string)
+		}
+		t.Refs[tool] = ref
+	}
+	t.UpdatedAt = time.Now().UTC()
+}
+
+// SetSource records the code comment the task tracks
+func (t *Task) SetSource(source *Source) {
+	t.Source = source
+	t.UpdatedAt = time.Now().UTC()
+}
+
 // AddNote adds a note to the task
 func (t *Task) AddNote(noteID, content string) {
 	note := Note{
@@ -193,17 +308,52 @@ func (t *Task) HasLabel(label string) bool {
 	return false
 }
 
+// MatchesQuery reports whether query appears (case-insensitively) in the task's
+// title, description, labels or notes
+func (t *Task) MatchesQuery(query string) bool {
+	q := strings.ToLower(query)
+	if strings.Contains(strings.ToLower(t.Title), q) {
+		return true
+	}
+	if t.Description != nil && strings.Contains(strings.ToLower(*t.Description), q) {
+		return true
+	}
+	for _, l := range t.Labels {
+		if strings.Contains(strings.ToLower(l), q) {
+			return true
+		}
+	}
+	for _, n := range t.Notes {
+		if strings.Contains(strings.ToLower(n.Content), q) {
+			return true
+		}
+	}
+	return false
+}
+
 // MarshalJSON implements custom JSON marshaling
 func (t Task) MarshalJSON() ([]byte, error) {
 	type Alias Task
+	statusChangedAt := ""
+	if !t.StatusChangedAt.IsZero() {
+		statusChangedAt = t.StatusChangedAt.Format(time.RFC3339)
+	}
+	due := ""
+	if !t.Due.IsZero() {
+		due = t.Due.Format(DateLayout)
+	}
 	return json.Marshal(&struct {
-		CreatedAt string `json:"created_at"`
-		UpdatedAt string `json:"updated_at"`
+		CreatedAt       string `json:"created_at"`
+		UpdatedAt       string `json:"updated_at"`
+		StatusChangedAt string `json:"status_changed_at,omitempty"`
+		Due             string `json:"due,omitempty"`
 		*Alias
 	}{
-		CreatedAt: t.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: t.UpdatedAt.Format(time.RFC3339),
-		Alias:     (*Alias)(&t),
+		CreatedAt:       t.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       t.UpdatedAt.Format(time.RFC3339),
+		StatusChangedAt: statusChangedAt,
+		Due:             due,
+		Alias:           (*Alias)(&t),
 	})
 }
 
@@ -211,8 +361,10 @@ func (t Task) MarshalJSON() ([]byte, error) {
 func (t *Task) UnmarshalJSON(data []byte) error {
 	type Alias Task
 	aux := &struct {
-		CreatedAt string `json:"created_at"`
-		UpdatedAt string `json:"updated_at"`
+		CreatedAt       string `json:"created_at"`
+		UpdatedAt       string `json:"updated_at"`
+		StatusChangedAt string `json:"status_changed_at"`
+		Due             string `json:"due"`
 		*Alias
 	}{
 		Alias: (*Alias)(t),
@@ -229,6 +381,20 @@ func (t *Task) UnmarshalJSON(data []byte) error {
 	if err != nil {
 		return fmt.Errorf("parsing updated_at: %w", err)
 	}
+	t.StatusChangedAt = time.Time{}
+	if aux.StatusChangedAt != "" {
+		t.StatusChangedAt, err = time.Parse(time.RFC3339, aux.StatusChangedAt)
+		if err != nil {
+			return fmt.Errorf("parsing status_changed_at: %w", err)
+		}
+	}
+	t.Due = time.Time{}
+	if aux.Due != "" {
+		t.Due, err = time.Parse(DateLayout, aux.Due)
+		if err != nil {
+			return fmt.Errorf("parsing due: %w", err)
+		}
+	}
 	// Ensure labels and notes are not nil
 	if t.Labels == nil {
 		t.Labels = []string{}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -29,6 +30,25 @@ func TestStatusIsValid(t *testing.T) {
 	}
 }
 
+func TestStatusIsClosed(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   bool
+	}{
+		{StatusTodo, false},
+		{StatusProgress, false},
+		{StatusBlocked, false},
+		{StatusAbandon, true},
+		{StatusDone, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.status.IsClosed(); got != tt.want {
+			t.Errorf("Status(%q).IsClosed() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
 func TestParseStatus(t *testing.T) {
 	tests := []struct {
 		input   string
@@ -263,6 +283,30 @@ func TestTaskHasLabel(t *testing.T) {
 	}
 }
 
+func TestTaskMatchesQuery(t *testing.T) {
+	task := NewTask("abc", "Fix Login flow", TypeBug)
+	task.SetDescription("Session cookie expires early")
+	task.AddLabel("frontend")
+	task.AddNote("abc-001", "Repro on Safari")
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"login", true},
+		{"COOKIE", true},
+		{"front", true},
+		{"safari", true},
+		{"backend", false},
+	}
+
+	for _, tt := range tests {
+		if got := task.MatchesQuery(tt.query); got != tt.want {
+			t.Errorf("MatchesQuery(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
 func TestTaskJSONRoundTrip(t *testing.T) {
 	task := NewTask("abc", "Test Task", TypeBug)
 	task.SetDescription("A description")
@@ -303,6 +347,64 @@ func TestTaskJSONRoundTrip(t *testing.T) {
 	}
 }
 
+func TestTaskStatusChangedAt(t *testing.T) {
+	task := NewTask("abc", "Test Task", TypeTask)
+	if data, _ := json.Marshal(task); strings.Contains(string(data), "status_changed_at") {
+		t.Errorf("a task whose status never changed should omit status_changed_at: %s", data)
+	}
+
+	task.SetStatus(StatusTodo)
+	if !task.StatusChangedAt.IsZero() {
+		t.Error("setting the same status should not record a change")
+	}
+
+	task.SetStatus(StatusDone)
+	if task.StatusChangedAt.IsZero() {
+		t.Fatal("StatusChangedAt not set by a status change")
+	}
+	data, _ := json.Marshal(task)
+	var decoded Task
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.StatusChangedAt.Equal(task.StatusChangedAt.Truncate(time.Second)) {
+		t.Errorf("StatusChangedAt after round trip = %v, want %v", decoded.StatusChangedAt, task.StatusChangedAt)
+	}
+}
+
+func TestTaskSetBranch(t *testing.T) {
+	task := NewTask("abc", "Test Task", TypeTask)
+	if data, _ := json.Marshal(task); strings.Contains(string(data), "branch") {
+		t.Errorf("a task without a branch should omit it: %s", data)
+	}
+
+	task.SetBranch("feature/abc-test-task")
+	data, _ := json.Marshal(task)
+	var decoded Task
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Branch != "feature/abc-test-task" {
+		t.Errorf("Branch after round trip = %q, %v", decoded.Branch, err)
+	}
+}
+
+func TestTaskSetRef(t *testing.T) {
+	task := NewTask("abc", "Test Task", TypeTask)
+	if data, _ := json.Marshal(task); strings.Contains(string(data), "refs") {
+		t.Errorf("a task without refs should omit them: %s", data)
+	}
+
+	task.SetRef("taskwarrior", "1234")
+	data, _ := json.Marshal(task)
+	var decoded Task
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Refs["taskwarrior"] != "1234" {
+		t.Errorf("Refs after round trip = %v, %v", decoded.Refs, err)
+	}
+
+	task.SetRef("taskwarrior", "")
+	if len(task.Refs) != 0 {
+		t.Errorf("SetRef with an empty ref should remove it, got %v", task.Refs)
+	}
+}
+
 func TestTaskJSONFormat(t *testing.T) {
 	task := NewTask("9nk", "Task title", TypeTask)
 
@@ -388,3 +490,35 @@ func TestNoteJSONRoundTrip(t *testing.T) {
 		t.Errorf("CreatedAt mismatch")
 	}
 }
+
+func TestTaskRemoveLabel(t *testing.T) {
+	task := NewTask("abc", "Test", TypeTask)
+	task.SetLabels([]string{"a", "b", "c"})
+
+	if !task.RemoveLabel("b") {
+		t.Error("RemoveLabel(b) = false, want true")
+	}
+	if len(task.Labels) != 2 || task.Labels[0] != "a" || task.Labels[1] != "c" {
+		t.Errorf("Labels after RemoveLabel = %v, want [a c]", task.Labels)
+	}
+	if task.RemoveLabel("zzz") {
+		t.Error("RemoveLabel(zzz) = true, want false")
+	}
+}
+
+func TestTaskReplaceLabels(t *testing.T) {
+	task := NewTask("abc", "Test", TypeTask)
+	task.SetLabels([]string{"front-end", "urgent", "frontend-ui"})
+
+	if !task.ReplaceLabels([]string{"front-end", "frontend-ui"}, "frontend") {
+		t.Error("ReplaceLabels() = false, want true")
+	}
+	want := []string{"frontend", "urgent"}
+	if len(task.Labels) != len(want) || task.Labels[0] != want[0] || task.Labels[1] != want[1] {
+		t.Errorf("Labels after ReplaceLabels = %v, want %v", task.Labels, want)
+	}
+
+	if task.ReplaceLabels([]string{"missing"}, "other") {
+		t.Error("ReplaceLabels() with no matches = true, want false")
+	}
+}
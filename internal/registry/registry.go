@@ -0,0 +1,133 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	// AppDir is the directory name for user-level task configuration
+	AppDir = "task"
+	// FileName is the filename of the project registry within AppDir
+	FileName = "projects.json"
+)
+
+// Project is a task project registered for cross-project commands
+type Project struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// Registry is the user-level list of known task projects
+type Registry struct {
+	Projects []Project `json:"projects"`
+
+	path string
+}
+
+// Dir returns the user-level task configuration directory
+// $XDG_CONFIG_HOME/task is used when set, otherwise ~/.config/task
+func Dir() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, AppDir), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.New("cannot locate config directory: set XDG_CONFIG_HOME or HOME")
+	}
+	return filepath.Join(home, ".config", AppDir), nil
+}
+
+// Load reads the registry from the user-level configuration directory
+func Load() (*Registry, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(filepath.Join(dir, FileName))
+}
+
+// LoadFile reads the registry from path
+// A missing file yields an empty registry that will be created on Save
+func LoadFile(path string) (*Registry, error) {
+	r := &Registry{Projects: []Project{}, path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, fmt.Errorf("reading registry: %w", err)
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return r, nil
+	}
+
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("parsing registry: %w", err)
+	}
+	if r.Projects == nil {
+		r.Projects = []Project{}
+	}
+	return r, nil
+}
+
+// Save writes the registry back to the file it was loaded from
+func (r *Registry) Save() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding registry: %w", err)
+	}
+	data = append(data, '\n')
+
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("writing registry: %w", err)
+	}
+	return nil
+}
+
+// Register adds the project at path to the registry, named after its directory
+// Returns the project and whether it was newly added. Names are made unique by
+// appending a numeric suffix when two projects share a directory name
+func (r *Registry) Register(path string) (Project, bool, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Project{}, false, fmt.Errorf("resolving project path: %w", err)
+	}
+
+	for _, p := range r.Projects {
+		if p.Path == abs {
+			return p, false, nil
+		}
+	}
+
+	base := filepath.Base(abs)
+	name := base
+	for i := 2; r.hasName(name); i++ {
+		name = base + "-" + strconv.Itoa(i)
+	}
+
+	p := Project{Name: name, Path: abs}
+	r.Projects = append(r.Projects, p)
+	return p, true, nil
+}
+
+// hasName reports whether a project with the given name is registered
+func (r *Registry) hasName(name string) bool {
+	for _, p := range r.Projects {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
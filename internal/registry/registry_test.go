@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDirUsesXDGConfigHome(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+
+	dir, err := Dir()
+	if err != nil {
+		t.Fatalf("Dir() error = %v", err)
+	}
+	if dir != filepath.Join("/tmp/xdg", AppDir) {
+		t.Errorf("Dir() = %q, want %q", dir, filepath.Join("/tmp/xdg", AppDir))
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	r, err := LoadFile(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if len(r.Projects) != 0 {
+		t.Errorf("LoadFile() returned %d projects, want 0", len(r.Projects))
+	}
+}
+
+func TestRegisterAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", FileName)
+	r, _ := LoadFile(path)
+
+	p, added, err := r.Register("/work/api")
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if !added || p.Name != "api" || p.Path != "/work/api" {
+		t.Errorf("Register() = %+v, %v, want api at /work/api added", p, added)
+	}
+
+	// Registering the same path again is a no-op
+	if _, added, _ := r.Register("/work/api"); added {
+		t.Error("Register() of an existing path should not add it again")
+	}
+
+	// A second project with the same directory name gets a unique name
+	p, _, _ = r.Register("/other/api")
+	if p.Name != "api-2" {
+		t.Errorf("Register() name = %q, want %q", p.Name, "api-2")
+	}
+
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if len(loaded.Projects) != 2 {
+		t.Errorf("LoadFile() returned %d projects, want 2", len(loaded.Projects))
+	}
+}
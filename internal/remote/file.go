@@ -0,0 +1,171 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// File is a remote kept in a local JSON file, standing in for a real tracker
+// in tests and for trying out sync offline
+type File struct {
+	path string
+	// Now returns the time recorded on changes
+	Now func() time.Time
+}
+
+// fileIssue is an issue as stored in the file, along with its comments
+type fileIssue struct {
+	Issue
+	Comments []Comment `json:"comments,omitempty"`
+}
+
+// NewFile returns a remote stored at path. The file is created by the first
+// change
+func NewFile(path string) *File {
+	return &File{path: path, Now: time.Now}
+}
+
+// Name implements Remote
+func (f *File) Name() string {
+	return TypeFile
+}
+
+// Location implements Remote
+func (f *File) Location() string {
+	return f.path
+}
+
+// List implements Remote
+func (f *File) List() ([]Issue, error) {
+	stored, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	issues := make([]Issue, len(stored))
+	for i, s := range stored {
+		issues[i] = s.Issue
+	}
+	return issues, nil
+}
+
+// Get implements Remote
+func (f *File) Get(number int) (*Issue, error) {
+	stored, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	i := find(stored, number)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	return &stored[i].Issue, nil
+}
+
+// Create implements Remote
+func (f *File) Create(issue Issue) (*Issue, error) {
+	stored, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	issue.Number = len(stored) + 1
+	issue.UpdatedAt = f.now()
+	if issue.Labels == nil {
+		issue.Labels = []string{}
+	}
+	stored = append(stored, fileIssue{Issue: issue})
+	return &issue, f.save(stored)
+}
+
+// Update implements Remote
+func (f *File) Update(issue Issue) (*Issue, error) {
+	stored, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	i := find(stored, issue.Number)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	issue.UpdatedAt = f.now()
+	if issue.Labels == nil {
+		issue.Labels = []string{}
+	}
+	stored[i].Issue = issue
+	return &issue, f.save(stored)
+}
+
+// Comments implements Remote
+func (f *File) Comments(number int) ([]Comment, error) {
+	stored, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	i := find(stored, number)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	return stored[i].Comments, nil
+}
+
+// AddComment implements Remote
+func (f *File) AddComment(number int, body string) (*Comment, error) {
+	stored, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	i := find(stored, number)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	var id int64
+	for _, s := range stored {
+		id += int64(len(s.Comments))
+	}
+	comment := Comment{ID: id + 1, Body: body, CreatedAt: f.now()}
+	stored[i].Comments = append(stored[i].Comments, comment)
+	stored[i].UpdatedAt = comment.CreatedAt
+	return &comment, f.save(stored)
+}
+
+func (f *File) now() time.Time {
+	return f.Now().UTC().Truncate(time.Second)
+}
+
+func (f *File) load() ([]fileIssue, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var stored []fileIssue
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", f.path, err)
+	}
+	return stored, nil
+}
+
+func (f *File) save(stored []fileIssue) error {
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, append(data, '\n'), 0644)
+}
+
+// find returns the index of the issue with the number, or -1
+func find(stored []fileIssue, number int) int {
+	for i := range stored {
+		if stored[i].Number == number {
+			return i
+		}
+	}
+	return -1
+}
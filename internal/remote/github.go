@@ -0,0 +1,257 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/version"
+)
+
+// DefaultGitHubURL is the GitHub REST API used when no URL is configured
+const DefaultGitHubURL = "https://api.github.com"
+
+const (
+	// githubTimeout limits each API request
+	githubTimeout = 30 * time.Second
+	// githubPageSize is the most items GitHub returns per page
+	githubPageSize = 100
+)
+
+// GitHub is a remote for the issues of a GitHub repository, using the REST
+// API
+type GitHub struct {
+	url    string
+	repo   string
+	token  string
+	client *http.Client
+}
+
+// githubIssue is an issue as the API returns it
+type githubIssue struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	State       string `json:"state"`
+	StateReason string `json:"state_reason"`
+	Labels      []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	HTMLURL   string    `json:"html_url"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// PullRequest is set for pull requests, which the issues API also lists
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+// githubIssueRequest is the body of a request creating or editing an issue
+type githubIssueRequest struct {
+	Title       string   `json:"title"`
+	Body        string   `json:"body"`
+	Labels      []string `json:"labels"`
+	State       string   `json:"state,omitempty"`
+	StateReason string   `json:"state_reason,omitempty"`
+}
+
+// NewGitHub returns a remote for repo, given as owner/name. An empty url uses
+// DefaultGitHubURL, and an empty token makes unauthenticated requests, which
+// can only read public repositories
+func NewGitHub(url, repo, token string) *GitHub {
+	if url == "" {
+		url = DefaultGitHubURL
+	}
+	return &GitHub{
+		url:    strings.TrimSuffix(url, "/"),
+		repo:   repo,
+		token:  token,
+		client: &http.Client{Timeout: githubTimeout},
+	}
+}
+
+// Name implements Remote
+func (g *GitHub) Name() string {
+	return TypeGitHub
+}
+
+// Location implements Remote
+func (g *GitHub) Location() string {
+	return g.repo
+}
+
+// List implements Remote, leaving out pull requests
+func (g *GitHub) List() ([]Issue, error) {
+	var issues []Issue
+	for page := 1; ; page++ {
+		var batch []githubIssue
+		path := fmt.Sprintf("/repos/%s/issues?state=all&per_page=%d&page=%d", g.repo, githubPageSize, page)
+		if err := g.do(http.MethodGet, path, nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, gi := range batch {
+			if gi.PullRequest == nil {
+				issues = append(issues, gi.issue())
+			}
+		}
+		if len(batch) < githubPageSize {
+			return issues, nil
+		}
+	}
+}
+
+// Get implements Remote
+func (g *GitHub) Get(number int) (*Issue, error) {
+	var gi githubIssue
+	if err := g.do(http.MethodGet, fmt.Sprintf("/repos/%s/issues/%d", g.repo, number), nil, &gi); err != nil {
+		return nil, err
+	}
+	issue := gi.issue()
+	return &issue, nil
+}
+
+// Create implements Remote. Issues are always created open, so a closed
+// issue is closed by a second request
+func (g *GitHub) Create(issue Issue) (*Issue, error) {
+	req := newGitHubIssueRequest(issue)
+	req.State, req.StateReason = "", ""
+	var gi githubIssue
+	if err := g.do(http.MethodPost, fmt.Sprintf("/repos/%s/issues", g.repo), req, &gi); err != nil {
+		return nil, err
+	}
+	created := gi.issue()
+	if issue.State != StateClosed {
+		return &created, nil
+	}
+	issue.Number = created.Number
+	closed, err := g.Update(issue)
+	if err != nil {
+		return &created, err
+	}
+	return closed, nil
+}
+
+// Update implements Remote
+func (g *GitHub) Update(issue Issue) (*Issue, error) {
+	var gi githubIssue
+	path := fmt.Sprintf("/repos/%s/issues/%d", g.repo, issue.Number)
+	if err := g.do(http.MethodPatch, path, newGitHubIssueRequest(issue), &gi); err != nil {
+		return nil, err
+	}
+	updated := gi.issue()
+	return &updated, nil
+}
+
+// Comments implements Remote
+func (g *GitHub) Comments(number int) ([]Comment, error) {
+	var comments []Comment
+	for page := 1; ; page++ {
+		var batch []Comment
+		path := fmt.Sprintf("/repos/%s/issues/%d/comments?per_page=%d&page=%d", g.repo, number, githubPageSize, page)
+		if err := g.do(http.MethodGet, path, nil, &batch); err != nil {
+			return nil, err
+		}
+		comments = append(comments, batch...)
+		if len(batch) < githubPageSize {
+			return comments, nil
+		}
+	}
+}
+
+// AddComment implements Remote
+func (g *GitHub) AddComment(number int, body string) (*Comment, error) {
+	var comment Comment
+	path := fmt.Sprintf("/repos/%s/issues/%d/comments", g.repo, number)
+	if err := g.do(http.MethodPost, path, map[string]string{"body": body}, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// do sends a request with an optional JSON body and decodes the JSON
+// response into out
+func (g *GitHub) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, g.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("User-Agent", "task/"+version.Version)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodGet {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return fmt.Errorf("github: %s %s: %s: %s", method, strings.SplitN(path, "?", 2)[0], resp.Status, apiErr.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("github: decoding response: %w", err)
+	}
+	return nil
+}
+
+// issue converts an API issue
+func (gi githubIssue) issue() Issue {
+	issue := Issue{
+		Number:      gi.Number,
+		Title:       gi.Title,
+		Body:        gi.Body,
+		State:       gi.State,
+		StateReason: gi.StateReason,
+		Labels:      make([]string, len(gi.Labels)),
+		URL:         gi.HTMLURL,
+		UpdatedAt:   gi.UpdatedAt,
+	}
+	for i, label := range gi.Labels {
+		issue.Labels[i] = label.Name
+	}
+	return issue
+}
+
+func newGitHubIssueRequest(issue Issue) githubIssueRequest {
+	req := githubIssueRequest{
+		Title:  issue.Title,
+		Body:   issue.Body,
+		Labels: issue.Labels,
+		State:  issue.State,
+	}
+	if req.Labels == nil {
+		req.Labels = []string{}
+	}
+	if issue.State == StateClosed {
+		req.StateReason = issue.StateReason
+	}
+	return req
+}
@@ -0,0 +1,121 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/config"
+)
+
+// Remote types accepted in the config
+const (
+	TypeGitHub = "github"
+	TypeFile   = "file"
+)
+
+// Issue states
+const (
+	StateOpen   = "open"
+	StateClosed = "closed"
+)
+
+// Reasons an issue was closed
+const (
+	ReasonCompleted  = "completed"
+	ReasonNotPlanned = "not_planned"
+)
+
+// ErrNotFound is returned for an issue that doesn't exist
+var ErrNotFound = errors.New("issue not found")
+
+// Issue is an issue in a remote tracker
+type Issue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	// State is StateOpen or StateClosed
+	State string `json:"state"`
+	// StateReason is why a closed issue was closed: ReasonCompleted or
+	// ReasonNotPlanned
+	StateReason string    `json:"state_reason,omitempty"`
+	Labels      []string  `json:"labels"`
+	URL         string    `json:"url,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Comment is a comment on an issue
+type Comment struct {
+	ID        int64     `json:"id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Remote is an issue tracker. Implementations return issues with UpdatedAt
+// set to the tracker's own modification time, which sync compares against
+// the time of the last sync to detect edits
+type Remote interface {
+	// Name identifies the kind of tracker, and is the key of the task refs
+	// holding issue references
+	Name() string
+	// Location identifies the issue list within the tracker, such as a
+	// GitHub repository
+	Location() string
+	// List returns every issue, open and closed
+	List() ([]Issue, error)
+	// Get returns an issue, or ErrNotFound
+	Get(number int) (*Issue, error)
+	// Create adds an issue, ignoring its Number, and returns it as created.
+	// When the issue is created but a later step fails, it is returned along
+	// with the error
+	Create(issue Issue) (*Issue, error)
+	// Update replaces an issue's title, body, state and labels
+	Update(issue Issue) (*Issue, error)
+	// Comments returns an issue's comments, oldest first
+	Comments(number int) ([]Comment, error)
+	// AddComment adds a comment to an issue
+	AddComment(number int, body string) (*Comment, error)
+}
+
+// New returns the remote described by the config. Relative file paths are
+// resolved against root
+func New(cfg config.Remote, root string) (Remote, error) {
+	switch cfg.Type {
+	case TypeGitHub:
+		if strings.Count(cfg.Repo, "/") != 1 {
+			return nil, fmt.Errorf("remote repo must be owner/name, got %q", cfg.Repo)
+		}
+		return NewGitHub(cfg.URL, cfg.Repo, cfg.Token()), nil
+	case TypeFile:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("remote path is required for the file type")
+		}
+		path := cfg.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		return NewFile(path), nil
+	default:
+		return nil, fmt.Errorf("invalid remote type: %q (must be %s or %s)", cfg.Type, TypeGitHub, TypeFile)
+	}
+}
+
+// Ref returns the reference to an issue stored on its task, such as
+// owner/repo#12
+func Ref(r Remote, number int) string {
+	return fmt.Sprintf("%s#%d", r.Location(), number)
+}
+
+// IssueNumber returns the issue number from a reference made by Ref, or false
+// if the reference is to another location
+func IssueNumber(r Remote, ref string) (int, bool) {
+	rest, ok := strings.CutPrefix(ref, r.Location()+"#")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(rest)
+	return n, err == nil && n > 0
+}
@@ -0,0 +1,381 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/config"
+	"github.com/jackreid/task/internal/model"
+)
+
+// fakeClock returns a clock for the file remote that advances a second on
+// every reading, so each change has a later timestamp
+func fakeClock() func() time.Time {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+func newFileRemote(t *testing.T) *File {
+	f := NewFile(filepath.Join(t.TempDir(), "issues.json"))
+	f.Now = fakeClock()
+	return f
+}
+
+func TestNew(t *testing.T) {
+	r, err := New(config.Remote{Type: TypeGitHub, Repo: "owner/name"}, "")
+	if err != nil || r.Name() != "github" || r.Location() != "owner/name" {
+		t.Errorf("New(github) = %v, %v", r, err)
+	}
+	if _, err := New(config.Remote{Type: TypeGitHub, Repo: "name"}, ""); err == nil {
+		t.Error("New() should reject a repo without an owner")
+	}
+	r, err = New(config.Remote{Type: TypeFile, Path: "issues.json"}, "/project")
+	if err != nil || r.Location() != filepath.Join("/project", "issues.json") {
+		t.Errorf("New(file) = %v, %v", r, err)
+	}
+	if _, err := New(config.Remote{Type: "gitlab"}, ""); err == nil {
+		t.Error("New() should reject unknown types")
+	}
+}
+
+func TestRefs(t *testing.T) {
+	r := NewGitHub("", "owner/name", "")
+	ref := Ref(r, 12)
+	if ref != "owner/name#12" {
+		t.Errorf("Ref() = %q", ref)
+	}
+	if n, ok := IssueNumber(r, ref); !ok || n != 12 {
+		t.Errorf("IssueNumber(%q) = %d, %v", ref, n, ok)
+	}
+	for _, ref := range []string{"", "other/repo#12", "owner/name#", "owner/name#x"} {
+		if _, ok := IssueNumber(r, ref); ok {
+			t.Errorf("IssueNumber(%q) should fail", ref)
+		}
+	}
+}
+
+// fakeGitHub serves the parts of the GitHub issues API the remote uses
+type fakeGitHub struct {
+	mu       sync.Mutex
+	issues   []map[string]any
+	comments map[string][]map[string]any
+	auth     []string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+
+	path := strings.TrimPrefix(r.URL.Path, "/repos/owner/name/issues")
+	var body map[string]any
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	reply := func(v any) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	labels := func(names any) []map[string]any {
+		var out []map[string]any
+		for _, n := range names.([]any) {
+			out = append(out, map[string]any{"name": n})
+		}
+		return out
+	}
+
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		page := r.URL.Query().Get("page")
+		if page != "1" {
+			reply([]any{})
+			return
+		}
+		reply(f.issues)
+	case r.Method == http.MethodPost && path == "":
+		issue := map[string]any{
+			"number": len(f.issues) + 1, "title": body["title"], "body": body["body"], "state": "open",
+			"labels": labels(body["labels"]), "updated_at": "2024-05-01T12:00:00Z",
+		}
+		f.issues = append(f.issues, issue)
+		reply(issue)
+	case strings.HasSuffix(path, "/comments"):
+		number := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/comments")
+		if r.Method == http.MethodPost {
+			comment := map[string]any{"id": len(f.comments[number]) + 1, "body": body["body"], "created_at": "2024-05-01T12:00:01Z"}
+			f.comments[number] = append(f.comments[number], comment)
+			reply(comment)
+			return
+		}
+		reply(f.comments[number])
+	default:
+		for _, issue := range f.issues {
+			if "/"+strings.TrimSpace(jsonString(issue["number"])) != path {
+				continue
+			}
+			if r.Method == http.MethodPatch {
+				issue["title"], issue["body"], issue["state"] = body["title"], body["body"], body["state"]
+				issue["state_reason"] = body["state_reason"]
+				issue["labels"] = labels(body["labels"])
+				issue["updated_at"] = "2024-05-01T12:00:02Z"
+			}
+			reply(issue)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		reply(map[string]string{"message": "Not Found"})
+	}
+}
+
+func jsonString(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func TestGitHub(t *testing.T) {
+	fake := &fakeGitHub{
+		issues: []map[string]any{
+			{"number": 1, "title": "A pull request", "state": "open", "labels": []any{}, "pull_request": map[string]any{}},
+		},
+		comments: map[string][]map[string]any{},
+	}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+	gh := NewGitHub(ts.URL+"/", "owner/name", "secret")
+
+	created, err := gh.Create(Issue{Title: "Ship it", Body: "Details", State: StateClosed, StateReason: ReasonCompleted, Labels: []string{"release"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.Number != 2 || created.State != StateClosed || created.StateReason != ReasonCompleted || created.Labels[0] != "release" {
+		t.Errorf("Create() = %+v, want a closed issue", created)
+	}
+
+	issues, err := gh.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(issues) != 1 || issues[0].Title != "Ship it" {
+		t.Errorf("List() = %+v, want the issue without the pull request", issues)
+	}
+
+	if _, err := gh.AddComment(2, "First"); err != nil {
+		t.Fatalf("AddComment() error = %v", err)
+	}
+	comments, err := gh.Comments(2)
+	if err != nil || len(comments) != 1 || comments[0].Body != "First" || comments[0].CreatedAt.IsZero() {
+		t.Errorf("Comments() = %+v, %v", comments, err)
+	}
+
+	if _, err := gh.Get(9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a missing issue error = %v, want ErrNotFound", err)
+	}
+	if _, err := gh.Update(Issue{Number: 9, Title: "x"}); err == nil || !strings.Contains(err.Error(), "Not Found") {
+		t.Errorf("Update() of a missing issue error = %v", err)
+	}
+	for _, auth := range fake.auth {
+		if auth != "Bearer secret" {
+			t.Fatalf("Authorization = %q, want the token", auth)
+		}
+	}
+}
+
+func TestSyncPush(t *testing.T) {
+	r := newFileRemote(t)
+	sync := &Syncer{Remote: r, State: State{}}
+
+	task := model.NewTask("abc", "Write docs", model.TypeTask)
+	task.SetLabels([]string{"docs"})
+	task.AddNote("abc-001", "Started on the intro")
+
+	result, err := sync.Push(*task)
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if result.Action != ActionCreated || !result.Changed || result.Task.Refs["file"] != Ref(r, 1) {
+		t.Fatalf("Push() of a new task = %+v", result)
+	}
+	issue, _ := r.Get(1)
+	comments, _ := r.Comments(1)
+	if issue.Title != "Write docs" || issue.State != StateOpen || len(comments) != 1 {
+		t.Errorf("created issue = %+v, comments = %+v", issue, comments)
+	}
+	linked := result.Task
+
+	// Nothing to push until the task changes
+	if result, _ := sync.Push(linked); result.Action != ActionUnchanged {
+		t.Errorf("Push() of an unchanged task = %s", result.Action)
+	}
+
+	time.Sleep(time.Millisecond)
+	linked.SetStatus(model.StatusAbandon)
+	result, err = sync.Push(linked)
+	if err != nil || result.Action != ActionPushed {
+		t.Fatalf("Push() of a changed task = %+v, %v", result, err)
+	}
+	if issue, _ := r.Get(1); issue.State != StateClosed || issue.StateReason != ReasonNotPlanned {
+		t.Errorf("issue after closing the task = %+v", issue)
+	}
+
+	// Edit both sides
+	time.Sleep(time.Millisecond)
+	linked.SetTitle("Write the docs")
+	issue, _ = r.Get(1)
+	issue.Body = "Edited on the tracker"
+	r.Update(*issue)
+
+	if result, _ := sync.Push(linked); result.Action != ActionConflict {
+		t.Errorf("Push() with edits on both sides = %s, want conflict", result.Action)
+	}
+	if issue, _ := r.Get(1); issue.Title != "Write docs" {
+		t.Error("a conflict should leave the issue alone")
+	}
+	sync.Force = true
+	if result, _ := sync.Push(linked); result.Action != ActionPushed {
+		t.Errorf("Push() with Force = %s", result.Action)
+	}
+	if issue, _ := r.Get(1); issue.Title != "Write the docs" || issue.Body != "" {
+		t.Errorf("forced push should overwrite the issue, got %+v", issue)
+	}
+}
+
+// failingComments is a remote whose comments can't be added
+type failingComments struct {
+	*File
+}
+
+func (f failingComments) AddComment(number int, body string) (*Comment, error) {
+	return nil, errors.New("comments are down")
+}
+
+func TestSyncPushKeepsCreatedIssue(t *testing.T) {
+	r := newFileRemote(t)
+	sync := &Syncer{Remote: failingComments{r}, State: State{}}
+
+	task := model.NewTask("abc", "Write docs", model.TypeTask)
+	task.AddNote("abc-001", "Started on the intro")
+
+	result, err := sync.Push(*task)
+	if err == nil {
+		t.Fatal("Push() with failing comments should return an error")
+	}
+	if result.Action != ActionCreated || !result.Changed || result.Task.Refs["file"] != Ref(r, 1) {
+		t.Fatalf("Push() should still link the created issue, got %+v", result)
+	}
+
+	// Once comments work again, the next push finishes the same issue
+	sync.Remote = r
+	result, err = sync.Push(result.Task)
+	if err != nil || result.Action != ActionPushed {
+		t.Fatalf("Push() after the failure = %+v, %v", result, err)
+	}
+	issues, _ := r.List()
+	comments, _ := r.Comments(1)
+	if len(issues) != 1 || len(comments) != 1 {
+		t.Errorf("issues = %+v, comments = %+v, want one of each", issues, comments)
+	}
+}
+
+func TestSyncPull(t *testing.T) {
+	r := newFileRemote(t)
+	sync := &Syncer{Remote: r, State: State{}}
+
+	task := model.NewTask("abc", "Fix login", model.TypeBug)
+	result, _ := sync.Push(*task)
+	linked := result.Task
+
+	issue, _ := r.Get(1)
+	if result, _ := sync.Pull(linked, issue); result.Action != ActionUnchanged || result.Changed {
+		t.Errorf("Pull() of an unchanged issue = %+v", result)
+	}
+
+	issue.Title = "Fix the login"
+	issue.State = StateClosed
+	issue.Labels = []string{"auth"}
+	r.Update(*issue)
+	r.AddComment(1, "Fixed in v2")
+	issue, _ = r.Get(1)
+
+	result, err := sync.Pull(linked, issue)
+	if err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+	got := result.Task
+	if result.Action != ActionPulled || !result.Changed || got.Title != "Fix the login" || got.Status != model.StatusDone {
+		t.Errorf("Pull() = %+v", result)
+	}
+	if len(got.Labels) != 1 || got.Labels[0] != "auth" || len(got.Notes) != 1 || !strings.HasPrefix(got.Notes[0].ID, "abc-") {
+		t.Errorf("pulled labels = %v, notes = %+v", got.Labels, got.Notes)
+	}
+	if got.Type != model.TypeBug {
+		t.Error("Pull() should keep the task type")
+	}
+
+	// Reopening the issue reopens the task, unless the task changed too
+	time.Sleep(time.Millisecond)
+	got.SetDescription("Local edit")
+	issue.State = StateOpen
+	r.Update(*issue)
+	issue, _ = r.Get(1)
+	if result, _ := sync.Pull(got, issue); result.Action != ActionConflict {
+		t.Errorf("Pull() with edits on both sides = %s, want conflict", result.Action)
+	}
+	sync.Force = true
+	if result, _ := sync.Pull(got, issue); result.Task.Status != model.StatusTodo || result.Task.Description != nil {
+		t.Errorf("forced Pull() = %+v", result.Task)
+	}
+
+	if result, _ := sync.Pull(got, nil); result.Action != ActionMissing {
+		t.Errorf("Pull() of a deleted issue = %s", result.Action)
+	}
+}
+
+func TestSyncImport(t *testing.T) {
+	r := newFileRemote(t)
+	created, _ := r.Create(Issue{Title: "From the tracker", Body: "Body", State: StateOpen, Labels: []string{"ext"}})
+	r.AddComment(created.Number, "A comment")
+	sync := &Syncer{Remote: r, State: State{}}
+
+	issue, _ := r.Get(created.Number)
+	task, err := sync.Import(*issue)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if task.ID != "" || task.Title != "From the tracker" || *task.Description != "Body" || len(task.Notes) != 1 {
+		t.Errorf("Import() = %+v", task)
+	}
+	ref := Ref(r, created.Number)
+	if task.Refs["file"] != ref {
+		t.Errorf("refs = %v", task.Refs)
+	}
+	if _, ok := sync.State[ref]; !ok {
+		t.Error("Import() should record the sync")
+	}
+}
+
+func TestState(t *testing.T) {
+	dir := t.TempDir()
+	state, err := LoadState(dir)
+	if err != nil || len(state) != 0 {
+		t.Fatalf("LoadState() of a missing file = %v, %v", state, err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	state["owner/name#1"] = Synced{TaskUpdatedAt: now, IssueUpdatedAt: now.Add(time.Second)}
+	if err := state.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadState(dir)
+	if err != nil || !loaded["owner/name#1"].IssueUpdatedAt.Equal(now.Add(time.Second)) {
+		t.Errorf("LoadState() = %v, %v", loaded, err)
+	}
+}
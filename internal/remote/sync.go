@@ -0,0 +1,367 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/id"
+	"github.com/jackreid/task/internal/model"
+)
+
+// SyncFile holds the sync state within the task directory. It has no .jsonl
+// extension so it is never mistaken for a collection
+const SyncFile = "remote.sync"
+
+// Synced records the modification times of a task and its issue as of their
+// last sync
+type Synced struct {
+	TaskUpdatedAt  time.Time `json:"task_updated_at"`
+	IssueUpdatedAt time.Time `json:"issue_updated_at"`
+}
+
+// State maps issue references, as made by Ref, to their last sync
+type State map[string]Synced
+
+// LoadState reads the sync state from the task directory. A missing file is
+// an empty state
+func LoadState(taskDir string) (State, error) {
+	state := State{}
+	data, err := os.ReadFile(filepath.Join(taskDir, SyncFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("reading sync state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parsing sync state: %w", err)
+	}
+	return state, nil
+}
+
+// Save writes the sync state to the task directory
+func (s State) Save(taskDir string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(taskDir, SyncFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing sync state: %w", err)
+	}
+	return nil
+}
+
+// Action is what a sync did with a task
+type Action string
+
+const (
+	// ActionCreated is a task pushed as a new issue, or an issue pulled as a
+	// new task
+	ActionCreated Action = "created"
+	ActionPushed  Action = "pushed"
+	ActionPulled  Action = "pulled"
+	// ActionUnchanged is a task with nothing to sync in that direction
+	ActionUnchanged Action = "unchanged"
+	// ActionConflict is a task and issue that have both been edited since
+	// their last sync, and were left alone
+	ActionConflict Action = "conflict"
+	// ActionMissing is a task whose issue no longer exists
+	ActionMissing Action = "missing"
+)
+
+// Result describes the sync of one task
+type Result struct {
+	Action Action
+	// Task is the task after the sync
+	Task model.Task
+	// Ref is the reference to the task's issue
+	Ref string
+	// Changed reports that Task was modified and needs saving
+	Changed bool
+}
+
+// Syncer mirrors tasks to a remote. Titles and descriptions map to issue
+// titles and bodies, labels to labels, notes to comments, and open and
+// closed tasks to open and closed issues. Each task's issue is recorded in
+// its refs, and the State records when they were last in sync, so that
+// edits made to both since are reported as conflicts instead of one
+// overwriting the other
+type Syncer struct {
+	Remote Remote
+	State  State
+	// Force overwrites conflicting edits in the direction of the sync
+	Force bool
+}
+
+// Push sends a task to its issue, creating the issue if the task has none.
+// When the issue is created but finishing it fails, the created result is
+// returned along with the error so the task keeps its link; the sync state
+// is left unrecorded, so the next push finishes the issue
+func (s *Syncer) Push(t model.Task) (Result, error) {
+	number, linked := IssueNumber(s.Remote, t.Refs[s.Remote.Name()])
+	if !linked {
+		issue, err := s.Remote.Create(issueFor(t, 0))
+		if issue == nil {
+			return Result{}, err
+		}
+		ref := Ref(s.Remote, issue.Number)
+		t.SetRef(s.Remote.Name(), ref)
+		created := Result{Action: ActionCreated, Task: t, Ref: ref, Changed: true}
+		if err != nil {
+			return created, err
+		}
+		if len(t.Notes) > 0 {
+			if _, err := s.pushNotes(t, issue.Number); err != nil {
+				return created, err
+			}
+			if issue, err = s.Remote.Get(issue.Number); err != nil {
+				return created, err
+			}
+		}
+		s.record(ref, t, *issue)
+		return created, nil
+	}
+
+	ref := Ref(s.Remote, number)
+	issue, err := s.Remote.Get(number)
+	if errors.Is(err, ErrNotFound) {
+		return Result{Action: ActionMissing, Task: t, Ref: ref}, nil
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	local, remote := s.changes(ref, t, *issue)
+	if local && remote && !inSync(t, *issue) && !s.Force {
+		return Result{Action: ActionConflict, Task: t, Ref: ref}, nil
+	}
+	if !local && !s.Force {
+		return Result{Action: ActionUnchanged, Task: t, Ref: ref}, nil
+	}
+
+	pushed := false
+	if !inSync(t, *issue) {
+		if issue, err = s.Remote.Update(issueFor(t, number)); err != nil {
+			return Result{}, err
+		}
+		pushed = true
+	}
+	added, err := s.pushNotes(t, number)
+	if err != nil {
+		return Result{}, err
+	}
+	if added > 0 {
+		if issue, err = s.Remote.Get(number); err != nil {
+			return Result{}, err
+		}
+		pushed = true
+	}
+
+	s.record(ref, t, *issue)
+	if !pushed {
+		return Result{Action: ActionUnchanged, Task: t, Ref: ref}, nil
+	}
+	return Result{Action: ActionPushed, Task: t, Ref: ref}, nil
+}
+
+// Pull applies a task's issue to it. issue is nil when the task's issue no
+// longer exists
+func (s *Syncer) Pull(t model.Task, issue *Issue) (Result, error) {
+	ref := t.Refs[s.Remote.Name()]
+	if issue == nil {
+		return Result{Action: ActionMissing, Task: t, Ref: ref}, nil
+	}
+
+	local, remote := s.changes(ref, t, *issue)
+	if local && remote && !inSync(t, *issue) && !s.Force {
+		return Result{Action: ActionConflict, Task: t, Ref: ref}, nil
+	}
+	if !remote && !s.Force {
+		return Result{Action: ActionUnchanged, Task: t, Ref: ref}, nil
+	}
+
+	changed := applyIssue(&t, *issue)
+	comments, err := s.Remote.Comments(issue.Number)
+	if err != nil {
+		return Result{}, err
+	}
+	for _, c := range comments {
+		if hasNote(t, c.Body) {
+			continue
+		}
+		noteID, err := id.GenerateNoteID(t.ID)
+		if err != nil {
+			return Result{}, fmt.Errorf("generating note ID: %w", err)
+		}
+		t.Notes = append(t.Notes, model.Note{ID: noteID, CreatedAt: c.CreatedAt.UTC(), Content: strings.TrimSpace(c.Body)})
+		t.UpdatedAt = time.Now().UTC()
+		changed = true
+	}
+
+	s.record(ref, t, *issue)
+	if !changed {
+		return Result{Action: ActionUnchanged, Task: t, Ref: ref}, nil
+	}
+	return Result{Action: ActionPulled, Task: t, Ref: ref, Changed: true}, nil
+}
+
+// Import returns a new task for an issue no task is linked to. The task has
+// no ID, and neither do its notes
+func (s *Syncer) Import(issue Issue) (model.Task, error) {
+	t := model.NewTask("", issue.Title, model.TypeTask)
+	applyIssue(t, issue)
+	comments, err := s.Remote.Comments(issue.Number)
+	if err != nil {
+		return model.Task{}, err
+	}
+	for _, c := range comments {
+		if body := strings.TrimSpace(c.Body); body != "" {
+			t.Notes = append(t.Notes, model.Note{CreatedAt: c.CreatedAt.UTC(), Content: body})
+		}
+	}
+	ref := Ref(s.Remote, issue.Number)
+	t.SetRef(s.Remote.Name(), ref)
+	s.record(ref, *t, issue)
+	return *t, nil
+}
+
+// changes reports whether the task and the issue have changed since their
+// last sync. Both have when they have never been synced
+func (s *Syncer) changes(ref string, t model.Task, issue Issue) (local, remote bool) {
+	synced, ok := s.State[ref]
+	if !ok {
+		return true, true
+	}
+	return t.UpdatedAt.After(synced.TaskUpdatedAt), issue.UpdatedAt.After(synced.IssueUpdatedAt)
+}
+
+func (s *Syncer) record(ref string, t model.Task, issue Issue) {
+	s.State[ref] = Synced{TaskUpdatedAt: t.UpdatedAt, IssueUpdatedAt: issue.UpdatedAt}
+}
+
+// pushNotes adds the task's notes that aren't comments yet to its issue,
+// returning how many were added
+func (s *Syncer) pushNotes(t model.Task, number int) (int, error) {
+	comments, err := s.Remote.Comments(number)
+	if err != nil {
+		return 0, err
+	}
+	bodies := make(map[string]bool, len(comments))
+	for _, c := range comments {
+		bodies[strings.TrimSpace(c.Body)] = true
+	}
+	added := 0
+	for _, n := range t.Notes {
+		if bodies[strings.TrimSpace(n.Content)] {
+			continue
+		}
+		if _, err := s.Remote.AddComment(number, n.Content); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+// issueFor returns the issue a task maps to
+func issueFor(t model.Task, number int) Issue {
+	issue := Issue{
+		Number: number,
+		Title:  t.Title,
+		State:  StateOpen,
+		Labels: append([]string{}, t.Labels...),
+	}
+	if t.Description != nil {
+		issue.Body = *t.Description
+	}
+	switch t.Status {
+	case model.StatusDone:
+		issue.State, issue.StateReason = StateClosed, ReasonCompleted
+	case model.StatusAbandon:
+		issue.State, issue.StateReason = StateClosed, ReasonNotPlanned
+	}
+	return issue
+}
+
+// inSync reports whether a task and its issue already match
+func inSync(t model.Task, issue Issue) bool {
+	want := issueFor(t, issue.Number)
+	return want.Title == issue.Title &&
+		strings.TrimSpace(want.Body) == strings.TrimSpace(issue.Body) &&
+		want.State == issue.State &&
+		sameLabels(want.Labels, issue.Labels)
+}
+
+// applyIssue copies an issue onto a task, returning whether anything changed.
+// A closed issue closes an open task, as abandoned when it was not planned,
+// and an open issue reopens a closed one; open tasks otherwise keep their
+// status
+func applyIssue(t *model.Task, issue Issue) bool {
+	changed := false
+	if t.Title != issue.Title {
+		t.SetTitle(issue.Title)
+		changed = true
+	}
+	body := strings.TrimSpace(issue.Body)
+	if body == "" && t.Description != nil {
+		t.SetDescriptionValue(nil)
+		changed = true
+	} else if body != "" && (t.Description == nil || strings.TrimSpace(*t.Description) != body) {
+		t.SetDescription(body)
+		changed = true
+	}
+	if !sameLabels(t.Labels, issue.Labels) {
+		t.SetLabels(append([]string{}, issue.Labels...))
+		changed = true
+	}
+
+	status := t.Status
+	switch {
+	case issue.State == StateClosed && issue.StateReason == ReasonNotPlanned:
+		status = model.StatusAbandon
+	case issue.State == StateClosed && !t.Status.IsClosed():
+		status = model.StatusDone
+	case issue.State == StateOpen && t.Status.IsClosed():
+		status = model.StatusTodo
+	}
+	if status != t.Status {
+		t.SetStatus(status)
+		changed = true
+	}
+	return changed
+}
+
+func sameLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hasNote(t model.Task, content string) bool {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return true
+	}
+	for _, n := range t.Notes {
+		if strings.TrimSpace(n.Content) == content {
+			return true
+		}
+	}
+	return false
+}
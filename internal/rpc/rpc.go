@@ -0,0 +1,195 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jackreid/task/internal/config"
+	"github.com/jackreid/task/internal/store"
+	"github.com/jackreid/task/internal/version"
+)
+
+// protocolVersion is the MCP revision the server implements
+const protocolVersion = "2024-11-05"
+
+// JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is a JSON-RPC error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// invalidParams reports a problem with a call's arguments
+func invalidParams(format string, args ...interface{}) error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// request is an incoming JSON-RPC message. ID is nil for notifications
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// response is an outgoing JSON-RPC message
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Server answers line-delimited JSON-RPC 2.0 requests against a store.
+//
+// It speaks the tool subset of the Model Context Protocol (initialize,
+// tools/list and tools/call), so agents that support MCP can use it directly.
+// Each tool can also be called as a method of its own, e.g. "list_tasks",
+// which returns the structured result and reports failures as JSON-RPC errors
+type Server struct {
+	store  *store.Store
+	config *config.Config
+}
+
+// New creates a server for the store. cfg supplies the label allowlist
+func New(s *store.Store, cfg *config.Config) *Server {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	return &Server{store: s, config: cfg}
+}
+
+// Serve reads one request per line from r and writes one response per line
+// to w until r is exhausted. Notifications get no response
+func (srv *Server) Serve(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	enc := json.NewEncoder(w)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if resp := srv.handle(line); resp != nil {
+				if err := enc.Encode(resp); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle processes a single message and returns its response, or nil for a
+// notification
+func (srv *Server) handle(line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), &Error{Code: CodeParseError, Message: "parse error: " + err.Error()})
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		id := req.ID
+		if id == nil {
+			id = json.RawMessage("null")
+		}
+		return errorResponse(id, &Error{Code: CodeInvalidRequest, Message: "invalid request: expected jsonrpc 2.0 and a method"})
+	}
+
+	result, err := srv.dispatch(req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		rpcErr, ok := err.(*Error)
+		if !ok {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		return errorResponse(req.ID, rpcErr)
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+func errorResponse(id json.RawMessage, err *Error) *response {
+	return &response{JSONRPC: "2.0", ID: id, Error: err}
+}
+
+// dispatch runs a method and returns its result
+func (srv *Server) dispatch(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "initialize":
+		return map[string]interface{}{
+			"protocolVersion": protocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "task", "version": version.Version},
+			"instructions":    "Tools for the tasks tracked by `task` in this project. Use claim_next to pick up work and add_note to record findings.",
+		}, nil
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": Tools()}, nil
+	case "tools/call":
+		return srv.callTool(params)
+	}
+
+	if tool := findTool(method); tool != nil {
+		return tool.call(srv, params)
+	}
+	return nil, &Error{Code: CodeMethodNotFound, Message: "method not found: " + method}
+}
+
+// toolResult is the MCP result of tools/call
+type toolResult struct {
+	Content           []textContent `json:"content"`
+	StructuredContent interface{}   `json:"structuredContent,omitempty"`
+	IsError           bool          `json:"isError,omitempty"`
+}
+
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// callTool runs a tool for tools/call. Failures of the tool itself are
+// returned as an error result for the agent to read, not a protocol error
+func (srv *Server) callTool(params json.RawMessage) (interface{}, error) {
+	var call struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, invalidParams("invalid params: %v", err)
+	}
+	tool := findTool(call.Name)
+	if tool == nil {
+		return nil, invalidParams("unknown tool: %s", call.Name)
+	}
+
+	result, err := tool.call(srv, call.Arguments)
+	if err != nil {
+		return toolResult{Content: []textContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+
+	text, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return toolResult{Content: []textContent{{Type: "text", Text: string(text)}}, StructuredContent: result}, nil
+}
@@ -0,0 +1,301 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/config"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+type testResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+func newTestServer(t *testing.T, cfg *config.Config) (*Server, *store.Store) {
+	t.Helper()
+	s := store.New(t.TempDir())
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	return New(s, cfg), s
+}
+
+// exchange sends the request lines and returns one response per line of output
+func exchange(t *testing.T, srv *Server, lines ...string) []testResponse {
+	t.Helper()
+	var out bytes.Buffer
+	if err := srv.Serve(strings.NewReader(strings.Join(lines, "\n")), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	var responses []testResponse
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var resp testResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("response %q is not JSON: %v", line, err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+// call invokes a tool as a method and decodes its result
+func call(t *testing.T, srv *Server, method, params string, result interface{}) *Error {
+	t.Helper()
+	responses := exchange(t, srv, `{"jsonrpc":"2.0","id":1,"method":"`+method+`","params":`+params+`}`)
+	if len(responses) != 1 {
+		t.Fatalf("%s: got %d responses, want 1", method, len(responses))
+	}
+	if responses[0].Error != nil {
+		return responses[0].Error
+	}
+	if err := json.Unmarshal(responses[0].Result, result); err != nil {
+		t.Fatalf("%s: decoding result: %v", method, err)
+	}
+	return nil
+}
+
+func TestProtocol(t *testing.T) {
+	srv, _ := newTestServer(t, nil)
+
+	responses := exchange(t, srv,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		``,
+		`{"jsonrpc":"2.0","id":"two","method":"tools/list"}`,
+		`not json`,
+		`{"jsonrpc":"1.0","id":3,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"nope"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"ping"}`,
+	)
+
+	if len(responses) != 6 {
+		t.Fatalf("got %d responses, want 6 (notifications get none)", len(responses))
+	}
+
+	var init struct {
+		ProtocolVersion string                 `json:"protocolVersion"`
+		Capabilities    map[string]interface{} `json:"capabilities"`
+		ServerInfo      map[string]string      `json:"serverInfo"`
+	}
+	json.Unmarshal(responses[0].Result, &init)
+	if init.ProtocolVersion != protocolVersion || init.Capabilities["tools"] == nil || init.ServerInfo["name"] != "task" {
+		t.Errorf("initialize result = %s", responses[0].Result)
+	}
+
+	if string(responses[1].ID) != `"two"` {
+		t.Errorf("response ID = %s, want \"two\"", responses[1].ID)
+	}
+	var list struct {
+		Tools []struct {
+			Name        string                 `json:"name"`
+			InputSchema map[string]interface{} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	json.Unmarshal(responses[1].Result, &list)
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+		if tool.InputSchema["type"] != "object" {
+			t.Errorf("tool %s schema = %v", tool.Name, tool.InputSchema)
+		}
+	}
+	if strings.Join(names, ",") != "list_tasks,get_task,create_task,update_status,add_note,claim_next" {
+		t.Errorf("tools = %v", names)
+	}
+
+	wantCodes := []int{CodeParseError, CodeInvalidRequest, CodeMethodNotFound}
+	for i, code := range wantCodes {
+		resp := responses[i+2]
+		if resp.Error == nil || resp.Error.Code != code {
+			t.Errorf("response %d error = %+v, want code %d", i+2, resp.Error, code)
+		}
+	}
+	if string(responses[2].ID) != "null" {
+		t.Errorf("parse error ID = %s, want null", responses[2].ID)
+	}
+	if responses[5].Error != nil || string(responses[5].Result) != "{}" {
+		t.Errorf("ping response = %+v", responses[5])
+	}
+}
+
+func TestCreateAndGetTask(t *testing.T) {
+	srv, s := newTestServer(t, nil)
+
+	var created taskResult
+	if err := call(t, srv, "create_task", `{"title":"  Fix login  ","type":"bug","labels":["auth"],"description":"Broken"}`, &created); err != nil {
+		t.Fatalf("create_task error = %v", err)
+	}
+	task := created.Task
+	if task == nil || task.Title != "Fix login" || task.Type != model.TypeBug || task.Status != model.StatusTodo || !task.HasLabel("auth") {
+		t.Fatalf("created task = %+v", task)
+	}
+	if stored, _ := s.FindByID(task.ID); stored == nil {
+		t.Error("create_task should save the task")
+	}
+
+	var got taskResult
+	if err := call(t, srv, "get_task", `{"id":"`+task.ID+`"}`, &got); err != nil {
+		t.Fatalf("get_task error = %v", err)
+	}
+	if got.Task.ID != task.ID || *got.Task.Description != "Broken" {
+		t.Errorf("get_task = %+v", got.Task)
+	}
+}
+
+func TestArgumentErrors(t *testing.T) {
+	srv, _ := newTestServer(t, &config.Config{
+		RestrictLabels: true,
+		Labels:         []config.Label{{Name: "auth"}},
+	})
+
+	tests := []struct {
+		method string
+		params string
+		want   string
+	}{
+		{"create_task", `{}`, "title is required"},
+		{"create_task", `{"title":"x","type":"epic"}`, "invalid type: epic"},
+		{"create_task", `{"title":"x","labels":["ui"]}`, "unknown label: ui"},
+		{"create_task", `{"title":"x","priority":1}`, "unknown field"},
+		{"list_tasks", `{"scope":"nope"}`, "invalid scope"},
+		{"get_task", `{"id":"zzz"}`, "task not found: zzz"},
+		{"update_status", `{"id":"zzz","status":"done"}`, "task not found: zzz"},
+		{"update_status", `{"id":"zzz","status":"finished"}`, "invalid status: finished"},
+		{"add_note", `{"id":"zzz","content":" "}`, "content is required"},
+	}
+
+	for _, tt := range tests {
+		var result interface{}
+		err := call(t, srv, tt.method, tt.params, &result)
+		if err == nil {
+			t.Errorf("%s %s should fail", tt.method, tt.params)
+			continue
+		}
+		if err.Code != CodeInvalidParams || !strings.Contains(err.Message, tt.want) {
+			t.Errorf("%s %s error = %+v, want %q", tt.method, tt.params, err, tt.want)
+		}
+	}
+}
+
+func TestUpdateStatusAndAddNote(t *testing.T) {
+	srv, s := newTestServer(t, nil)
+	s.Add(model.NewTask("aaa", "Work", model.TypeTask))
+
+	var result taskResult
+	if err := call(t, srv, "update_status", `{"id":"aaa","status":"blocked","note":"Waiting on API keys"}`, &result); err != nil {
+		t.Fatalf("update_status error = %v", err)
+	}
+	if result.Task.Status != model.StatusBlocked || len(result.Task.Notes) != 1 {
+		t.Errorf("update_status result = %+v", result.Task)
+	}
+
+	if err := call(t, srv, "add_note", `{"id":"aaa","content":"Keys arrived"}`, &result); err != nil {
+		t.Fatalf("add_note error = %v", err)
+	}
+	stored, _ := s.FindByID("aaa")
+	if stored.Status != model.StatusBlocked || len(stored.Notes) != 2 || stored.Notes[1].Content != "Keys arrived" {
+		t.Errorf("stored task = %+v", stored)
+	}
+}
+
+func TestListTasks(t *testing.T) {
+	srv, s := newTestServer(t, nil)
+	bug := model.NewTask("aaa", "Login bug", model.TypeBug)
+	bug.Labels = []string{"auth"}
+	s.Add(bug)
+	s.Add(model.NewTask("bbb", "Write docs", model.TypeTask))
+
+	var result listResult
+	if err := call(t, srv, "list_tasks", `{"label":"auth"}`, &result); err != nil {
+		t.Fatalf("list_tasks error = %v", err)
+	}
+	if result.Count != 1 || result.Tasks[0].ID != "aaa" {
+		t.Errorf("list_tasks = %+v", result)
+	}
+
+	if err := call(t, srv, "list_tasks", `{"status":"done"}`, &result); err != nil {
+		t.Fatalf("list_tasks error = %v", err)
+	}
+	if result.Count != 0 || result.Tasks == nil {
+		t.Errorf("empty list_tasks = %+v, want an empty array", result)
+	}
+}
+
+func TestClaimNext(t *testing.T) {
+	srv, s := newTestServer(t, nil)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, spec := range []struct {
+		id    string
+		label string
+	}{{"new", "ui"}, {"old", "api"}, {"mid", "ui"}} {
+		task := model.NewTask(spec.id, spec.id, model.TypeTask)
+		task.Labels = []string{spec.label}
+		task.CreatedAt = base.Add(time.Duration([]int{3, 1, 2}[i]) * time.Hour)
+		s.Add(task)
+	}
+
+	var result taskResult
+	if err := call(t, srv, "claim_next", `{}`, &result); err != nil {
+		t.Fatalf("claim_next error = %v", err)
+	}
+	if result.Task == nil || result.Task.ID != "old" || result.Task.Status != model.StatusProgress {
+		t.Errorf("claim_next should take the oldest todo task, got %+v", result.Task)
+	}
+
+	if err := call(t, srv, "claim_next", `{"label":"ui","note":"Starting"}`, &result); err != nil {
+		t.Fatalf("claim_next error = %v", err)
+	}
+	if result.Task == nil || result.Task.ID != "mid" || len(result.Task.Notes) != 1 {
+		t.Errorf("claim_next with label = %+v", result.Task)
+	}
+
+	call(t, srv, "claim_next", `{}`, &result)
+	if err := call(t, srv, "claim_next", `{}`, &result); err != nil {
+		t.Fatalf("claim_next error = %v", err)
+	}
+	if result.Task != nil {
+		t.Errorf("claim_next with nothing to do = %+v, want null", result.Task)
+	}
+}
+
+func TestToolsCall(t *testing.T) {
+	srv, _ := newTestServer(t, nil)
+
+	responses := exchange(t, srv,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"create_task","arguments":{"title":"Via MCP"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"create_task","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"nope"}}`,
+	)
+
+	var ok toolResult
+	json.Unmarshal(responses[0].Result, &ok)
+	if ok.IsError || len(ok.Content) != 1 || !strings.Contains(ok.Content[0].Text, `"title": "Via MCP"`) {
+		t.Errorf("tools/call result = %s", responses[0].Result)
+	}
+	structured, _ := ok.StructuredContent.(map[string]interface{})
+	if task, _ := structured["task"].(map[string]interface{}); task["title"] != "Via MCP" {
+		t.Errorf("structuredContent = %v", ok.StructuredContent)
+	}
+
+	var failed toolResult
+	json.Unmarshal(responses[1].Result, &failed)
+	if !failed.IsError || failed.Content[0].Text != "title is required" {
+		t.Errorf("failed tool call should be an error result, got %s", responses[1].Result)
+	}
+
+	if responses[2].Error == nil || responses[2].Error.Code != CodeInvalidParams {
+		t.Errorf("unknown tool should be a protocol error, got %+v", responses[2])
+	}
+}
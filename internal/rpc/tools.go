@@ -0,0 +1,455 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackreid/task/internal/id"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+// Tool is an operation offered to agents, with a JSON schema for its arguments
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+
+	call func(srv *Server, args json.RawMessage) (interface{}, error)
+}
+
+// taskResult is the result of tools that return a single task. Task is nil
+// when there was no task to return
+type taskResult struct {
+	Task *model.Task `json:"task"`
+}
+
+// listResult is the result of list_tasks
+type listResult struct {
+	Tasks []model.Task `json:"tasks"`
+	Count int          `json:"count"`
+}
+
+var tools []Tool
+
+func init() {
+	statuses := make([]string, 0, len(model.AllStatuses()))
+	for _, s := range model.AllStatuses() {
+		statuses = append(statuses, string(s))
+	}
+	types := make([]string, 0, len(model.AllTaskTypes()))
+	for _, t := range model.AllTaskTypes() {
+		types = append(types, string(t))
+	}
+
+	taskID := property("string", "Task ID, e.g. \"9nk\"")
+	labels := map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"type": "string"},
+		"description": "Labels to add to the task",
+	}
+
+	tools = []Tool{
+		{
+			Name:        "list_tasks",
+			Description: "List tasks, most recently updated first. All filters are optional",
+			InputSchema: objectSchema(nil, map[string]interface{}{
+				"status": enumProperty("Only tasks with this status", statuses),
+				"type":   enumProperty("Only tasks of this type", types),
+				"label":  property("string", "Only tasks with this label"),
+				"query":  property("string", "Only tasks whose title, description or notes contain this text"),
+				"scope":  enumProperty("Live tasks (default), archived tasks or both", []string{"live", "archived", "all"}),
+			}),
+			call: (*Server).listTasks,
+		},
+		{
+			Name:        "get_task",
+			Description: "Get a task with its description and notes, including archived tasks",
+			InputSchema: objectSchema([]string{"id"}, map[string]interface{}{
+				"id": taskID,
+			}),
+			call: (*Server).getTask,
+		},
+		{
+			Name:        "create_task",
+			Description: "Create a task. New tasks start as todo unless a status is given",
+			InputSchema: objectSchema([]string{"title"}, map[string]interface{}{
+				"title":       property("string", "Task title"),
+				"description": property("string", "Task description"),
+				"type":        enumProperty("Task type (default task)", types),
+				"status":      enumProperty("Initial status (default todo)", statuses),
+				"labels":      labels,
+			}),
+			call: (*Server).createTask,
+		},
+		{
+			Name:        "update_status",
+			Description: "Change the status of a task, optionally recording why in a note",
+			InputSchema: objectSchema([]string{"id", "status"}, map[string]interface{}{
+				"id":     taskID,
+				"status": enumProperty("New status", statuses),
+				"note":   property("string", "Note to add with the change, e.g. what is blocking the task"),
+			}),
+			call: (*Server).updateStatus,
+		},
+		{
+			Name:        "add_note",
+			Description: "Add a note to a task to record findings, decisions or blockers",
+			InputSchema: objectSchema([]string{"id", "content"}, map[string]interface{}{
+				"id":      taskID,
+				"content": property("string", "Note content"),
+			}),
+			call: (*Server).addNote,
+		},
+		{
+			Name:        "claim_next",
+			Description: "Take the oldest todo task, optionally of a type or with a label, and set it to progress. Returns a null task when there is nothing to do",
+			InputSchema: objectSchema(nil, map[string]interface{}{
+				"type":  enumProperty("Only claim a task of this type", types),
+				"label": property("string", "Only claim a task with this label"),
+				"note":  property("string", "Note to add to the claimed task"),
+			}),
+			call: (*Server).claimNext,
+		},
+	}
+}
+
+// Tools returns the tools the server offers
+func Tools() []Tool {
+	return tools
+}
+
+// findTool looks up a tool by name
+func findTool(name string) *Tool {
+	for i := range tools {
+		if tools[i].Name == name {
+			return &tools[i]
+		}
+	}
+	return nil
+}
+
+func objectSchema(required []string, properties map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func property(typ, description string) map[string]interface{} {
+	return map[string]interface{}{"type": typ, "description": description}
+}
+
+func enumProperty(description string, values []string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "enum": values, "description": description}
+}
+
+// decodeArgs decodes tool arguments, rejecting unknown fields. Missing
+// arguments are treated as an empty object
+func decodeArgs(args json.RawMessage, v interface{}) error {
+	if len(bytes.TrimSpace(args)) == 0 || string(bytes.TrimSpace(args)) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return invalidParams("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+func parseStatus(s string) (model.Status, error) {
+	status, err := model.ParseStatus(s)
+	if err != nil {
+		return "", invalidParams("%v", err)
+	}
+	return status, nil
+}
+
+func parseType(s string) (model.TaskType, error) {
+	tt, err := model.ParseTaskType(s)
+	if err != nil {
+		return "", invalidParams("%v", err)
+	}
+	return tt, nil
+}
+
+func (srv *Server) listTasks(args json.RawMessage) (interface{}, error) {
+	var a struct {
+		Status string `json:"status"`
+		Type   string `json:"type"`
+		Label  string `json:"label"`
+		Query  string `json:"query"`
+		Scope  string `json:"scope"`
+	}
+	if err := decodeArgs(args, &a); err != nil {
+		return nil, err
+	}
+
+	filter := store.Filter{}
+	if a.Status != "" {
+		status, err := parseStatus(a.Status)
+		if err != nil {
+			return nil, err
+		}
+		filter.Status = &status
+	}
+	if a.Type != "" {
+		tt, err := parseType(a.Type)
+		if err != nil {
+			return nil, err
+		}
+		filter.Type = &tt
+	}
+	if a.Label != "" {
+		filter.Label = &a.Label
+	}
+	if a.Query != "" {
+		filter.Query = &a.Query
+	}
+	switch a.Scope {
+	case "", "live":
+	case "archived":
+		filter.Scope = store.ScopeArchived
+	case "all":
+		filter.Scope = store.ScopeAll
+	default:
+		return nil, invalidParams("invalid scope: %s (must be live, archived or all)", a.Scope)
+	}
+
+	tasks, err := srv.store.ListFiltered(filter)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []model.Task{}
+	}
+	return listResult{Tasks: tasks, Count: len(tasks)}, nil
+}
+
+func (srv *Server) getTask(args json.RawMessage) (interface{}, error) {
+	var a struct {
+		ID string `json:"id"`
+	}
+	if err := decodeArgs(args, &a); err != nil {
+		return nil, err
+	}
+	if a.ID == "" {
+		return nil, invalidParams("id is required")
+	}
+
+	task, err := srv.store.FindByID(a.ID)
+	if err == nil && task == nil {
+		task, err = srv.store.FindArchivedByID(a.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, invalidParams("task not found: %s", a.ID)
+	}
+	return taskResult{Task: task}, nil
+}
+
+func (srv *Server) createTask(args json.RawMessage) (interface{}, error) {
+	var a struct {
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Type        string   `json:"type"`
+		Status      string   `json:"status"`
+		Labels      []string `json:"labels"`
+	}
+	if err := decodeArgs(args, &a); err != nil {
+		return nil, err
+	}
+
+	title := strings.TrimSpace(a.Title)
+	if title == "" {
+		return nil, invalidParams("title is required")
+	}
+	taskType := model.TypeTask
+	if a.Type != "" {
+		tt, err := parseType(a.Type)
+		if err != nil {
+			return nil, err
+		}
+		taskType = tt
+	}
+	status := model.StatusTodo
+	if a.Status != "" {
+		st, err := parseStatus(a.Status)
+		if err != nil {
+			return nil, err
+		}
+		status = st
+	}
+	if err := srv.config.ValidateLabels(a.Labels); err != nil {
+		return nil, invalidParams("%v", err)
+	}
+
+	existingIDs, err := srv.store.GetExistingIDs()
+	if err != nil {
+		return nil, err
+	}
+	taskID, err := id.GenerateUnique(existingIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	task := model.NewTask(taskID, title, taskType)
+	if a.Description != "" {
+		task.SetDescription(a.Description)
+	}
+	task.SetStatus(status)
+	for _, label := range a.Labels {
+		task.AddLabel(label)
+	}
+
+	if err := srv.store.Add(task); err != nil {
+		return nil, err
+	}
+	return taskResult{Task: task}, nil
+}
+
+func (srv *Server) updateStatus(args json.RawMessage) (interface{}, error) {
+	var a struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := decodeArgs(args, &a); err != nil {
+		return nil, err
+	}
+	status, err := parseStatus(a.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := srv.findLive(a.ID)
+	if err != nil {
+		return nil, err
+	}
+	task.SetStatus(status)
+	if err := addNote(task, a.Note); err != nil {
+		return nil, err
+	}
+	if err := srv.store.Update(task); err != nil {
+		return nil, err
+	}
+	return taskResult{Task: task}, nil
+}
+
+func (srv *Server) addNote(args json.RawMessage) (interface{}, error) {
+	var a struct {
+		ID      string `json:"id"`
+		Content string `json:"content"`
+	}
+	if err := decodeArgs(args, &a); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(a.Content) == "" {
+		return nil, invalidParams("content is required")
+	}
+
+	task, err := srv.findLive(a.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := addNote(task, a.Content); err != nil {
+		return nil, err
+	}
+	if err := srv.store.Update(task); err != nil {
+		return nil, err
+	}
+	return taskResult{Task: task}, nil
+}
+
+func (srv *Server) claimNext(args json.RawMessage) (interface{}, error) {
+	var a struct {
+		Type  string `json:"type"`
+		Label string `json:"label"`
+		Note  string `json:"note"`
+	}
+	if err := decodeArgs(args, &a); err != nil {
+		return nil, err
+	}
+
+	todo := model.StatusTodo
+	filter := store.Filter{Status: &todo}
+	if a.Type != "" {
+		tt, err := parseType(a.Type)
+		if err != nil {
+			return nil, err
+		}
+		filter.Type = &tt
+	}
+	if a.Label != "" {
+		filter.Label = &a.Label
+	}
+
+	tasks, err := srv.store.ListFiltered(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return taskResult{}, nil
+	}
+
+	// Oldest first, so work is picked up in the order it was added
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+
+	task := &tasks[0]
+	task.SetStatus(model.StatusProgress)
+	if err := addNote(task, a.Note); err != nil {
+		return nil, err
+	}
+	if err := srv.store.Update(task); err != nil {
+		return nil, err
+	}
+	return taskResult{Task: task}, nil
+}
+
+// findLive finds a task that can be changed. Archived tasks are read-only
+func (srv *Server) findLive(taskID string) (*model.Task, error) {
+	if taskID == "" {
+		return nil, invalidParams("id is required")
+	}
+	task, err := srv.store.FindByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		if archived, err := srv.store.FindArchivedByID(taskID); err == nil && archived != nil {
+			return nil, invalidParams("task is archived: %s (restore it with 'task restore %s')", taskID, taskID)
+		}
+		return nil, invalidParams("task not found: %s", taskID)
+	}
+	return task, nil
+}
+
+// addNote appends a note to the task unless content is blank
+func addNote(task *model.Task, content string) error {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil
+	}
+	noteID, err := id.GenerateNoteID(task.ID)
+	if err != nil {
+		return fmt.Errorf("generating note ID: %w", err)
+	}
+	task.AddNote(noteID, content)
+	return nil
+}
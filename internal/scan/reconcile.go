@@ -0,0 +1,74 @@
+package scan
+
+import (
+	"path"
+	"strings"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// Changes is what a scan changes in the tasks
+type Changes struct {
+	// Create holds the comments that have no task yet
+	Create []Comment
+	// Moved holds tasks whose comment moved, with their source updated
+	Moved []model.Task
+	// Removed holds open tasks whose comment is gone
+	Removed []model.Task
+}
+
+// Reconcile compares the comments found under paths with the tasks created
+// by earlier scans. Archived tasks count as existing so their comments don't
+// come back as new tasks. Tasks for files outside paths are left alone, as
+// are closed tasks whose comment is gone
+func Reconcile(tasks, archived []model.Task, comments []Comment, paths []string) Changes {
+	var changes Changes
+
+	found := make(map[string]Comment, len(comments))
+	for _, c := range comments {
+		found[c.Fingerprint] = c
+	}
+
+	known := make(map[string]bool)
+	for _, t := range archived {
+		if t.Source != nil {
+			known[t.Source.Fingerprint] = true
+		}
+	}
+	for _, t := range tasks {
+		if t.Source == nil {
+			continue
+		}
+		known[t.Source.Fingerprint] = true
+
+		c, ok := found[t.Source.Fingerprint]
+		switch {
+		case ok && (c.File != t.Source.File || c.Line != t.Source.Line):
+			t.SetSource(c.Source())
+			changes.Moved = append(changes.Moved, t)
+		case !ok && !t.Status.IsClosed() && inScope(t.Source.File, paths):
+			changes.Removed = append(changes.Removed, t)
+		}
+	}
+
+	for _, c := range comments {
+		if !known[c.Fingerprint] {
+			changes.Create = append(changes.Create, c)
+		}
+	}
+	return changes
+}
+
+// inScope reports whether a file is under one of the scanned paths
+func inScope(file string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		p = path.Clean(p)
+		if p == "." || file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+	return false
+}
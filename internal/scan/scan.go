@@ -0,0 +1,269 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jackreid/task/internal/git"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+// maxFileSize skips files too large to be source code worth scanning
+const maxFileSize = 1 << 20
+
+// maxTitleLength truncates long comments used as task titles
+const maxTitleLength = 100
+
+// commentPattern matches a TODO, FIXME or HACK marker at the start of a line
+// or end-of-line comment in most languages: //, #, /* and *, --, ; and <!--.
+// The marker may be followed by an owner in parentheses and a colon
+var commentPattern = regexp.MustCompile(`(?:^|\s)(?://+|#+|/\*+|\*|--|;+|<!--)\s*(TODO|FIXME|HACK)\b(?:\([^)]*\))?:?\s*(.*)$`)
+
+// Comment is a TODO-style comment found in a file
+type Comment struct {
+	// Tag is TODO, FIXME or HACK
+	Tag  string
+	Text string
+	// File is slash separated and relative to the scanned root
+	File string
+	Line int
+	// Fingerprint identifies the comment by its file, tag and text (and which
+	// of several identical comments in the file it is), so it survives the
+	// comment moving to another line
+	Fingerprint string
+}
+
+// Title returns the task title for the comment
+func (c Comment) Title() string {
+	title := c.Text
+	if title == "" {
+		title = fmt.Sprintf("%s in %s", c.Tag, c.File)
+	}
+	if len(title) > maxTitleLength {
+		title = strings.TrimSpace(title[:maxTitleLength-3]) + "..."
+	}
+	return title
+}
+
+// TaskType returns the type of task for the comment: a bug for FIXME
+func (c Comment) TaskType() model.TaskType {
+	if c.Tag == "FIXME" {
+		return model.TypeBug
+	}
+	return model.TypeTask
+}
+
+// Source returns the comment's location for recording on its task
+func (c Comment) Source() *model.Source {
+	return &model.Source{File: c.File, Line: c.Line, Fingerprint: c.Fingerprint}
+}
+
+// Scan finds the comments in the files under paths, relative to root. An
+// empty paths scans all of root. It returns the comments and how many files
+// were read
+func Scan(root string, paths []string) ([]Comment, int, error) {
+	files, err := Files(root, paths)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var comments []Comment
+	scanned := 0
+	for _, file := range files {
+		// The task files would turn every task titled TODO into another one
+		if strings.HasPrefix(file, store.TaskDir+"/") {
+			continue
+		}
+		found, ok, err := scanFile(root, file)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			scanned++
+			comments = append(comments, found...)
+		}
+	}
+	return comments, scanned, nil
+}
+
+// scanFile reads the comments from a file, skipping files that are missing,
+// too large or binary
+func scanFile(root, file string) ([]Comment, bool, error) {
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(file)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxFileSize {
+		return nil, false, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, false, fmt.Errorf("reading %s: %w", file, err)
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil, false, nil
+	}
+
+	comments, err := Parse(file, bytes.NewReader(data))
+	return comments, true, err
+}
+
+// Parse returns the comments in a file's contents
+func Parse(file string, r io.Reader) ([]Comment, error) {
+	var comments []Comment
+	seen := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFileSize)
+	for line := 1; scanner.Scan(); line++ {
+		m := commentPattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		text := strings.TrimSpace(m[2])
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(text, "*/"), "-->"))
+
+		key := m[1] + "\x00" + text
+		seen[key]++
+		comments = append(comments, Comment{
+			Tag:         m[1],
+			Text:        text,
+			File:        file,
+			Line:        line,
+			Fingerprint: fingerprint(file, m[1], text, seen[key]),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", file, err)
+	}
+	return comments, nil
+}
+
+// fingerprint hashes what identifies a comment; occurrence tells identical
+// comments in the same file apart
+func fingerprint(file, tag, text string, occurrence int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d", file, tag, text, occurrence)))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Files lists the files under paths, relative to root and slash separated.
+// In a git work tree these are the files git tracks or would track, so
+// .gitignore is respected; elsewhere the tree is walked, skipping hidden
+// directories and the patterns in root's .gitignore
+func Files(root string, paths []string) ([]string, error) {
+	args := append([]string{"ls-files", "-z", "--cached", "--others", "--exclude-standard", "--"}, paths...)
+	out, err := git.Run(root, args...)
+	if err == nil {
+		var files []string
+		seen := make(map[string]bool)
+		for _, file := range strings.Split(out, "\x00") {
+			if file != "" && !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+		return files, nil
+	}
+	var execErr *exec.Error
+	if !errors.Is(err, git.ErrNotRepository) && !errors.As(err, &execErr) {
+		return nil, err
+	}
+	return walk(root, paths)
+}
+
+// walk lists files without git's help
+func walk(root string, paths []string) ([]string, error) {
+	ignore := readGitignore(filepath.Join(root, ".gitignore"))
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var files []string
+	for _, p := range paths {
+		err := filepath.WalkDir(filepath.Join(root, p), func(full string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, full)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if rel == "." {
+				return nil
+			}
+			if d.IsDir() {
+				if strings.HasPrefix(d.Name(), ".") || ignore.match(rel, true) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !ignore.match(rel, false) {
+				files = append(files, rel)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// gitignore holds simple .gitignore patterns: globs matched against the
+// name or, when they contain a slash, the path. Negation is not supported
+type gitignore []string
+
+func readGitignore(file string) gitignore {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var patterns gitignore
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+func (g gitignore) match(rel string, dir bool) bool {
+	for _, pattern := range g {
+		if strings.HasSuffix(pattern, "/") {
+			if !dir {
+				continue
+			}
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+		target := path.Base(rel)
+		if strings.Contains(pattern, "/") {
+			target = rel
+			pattern = strings.TrimPrefix(pattern, "/")
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
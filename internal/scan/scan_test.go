@@ -0,0 +1,199 @@
+package scan
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jackreid/task/internal/git"
+	"github.com/jackreid/task/internal/model"
+)
+
+// writeFiles creates files, given as path and contents pairs, under dir
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for i := 0; i < len(files); i += 2 {
+		path := filepath.Join(dir, filepath.FromSlash(files[i]))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(files[i+1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	src := `package main
+
+// TODO: handle errors
+func main() {
+	x := 1 // FIXME(jack): off by one
+	/* HACK: work around the API */
+	url := "http://example.com/TODO"
+	// TODOS are not markers
+	// TODO: handle errors
+}
+# TODO shell style
+-- TODO sql style
+<!-- TODO html style -->
+ * TODO inside a block comment
+`
+	comments, err := Parse("main.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range comments {
+		got = append(got, c.Tag+"|"+c.Text)
+	}
+	want := []string{
+		"TODO|handle errors",
+		"FIXME|off by one",
+		"HACK|work around the API",
+		"TODO|handle errors",
+		"TODO|shell style",
+		"TODO|sql style",
+		"TODO|html style",
+		"TODO|inside a block comment",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() =\n%v\nwant\n%v", got, want)
+	}
+
+	if comments[0].Line != 3 || comments[1].Line != 5 {
+		t.Errorf("lines = %d, %d", comments[0].Line, comments[1].Line)
+	}
+	if comments[0].Fingerprint == comments[3].Fingerprint {
+		t.Error("identical comments in a file should have different fingerprints")
+	}
+	if comments[1].TaskType() != model.TypeBug || comments[0].TaskType() != model.TypeTask {
+		t.Error("FIXME should be a bug and TODO a task")
+	}
+
+	// Moving a comment keeps its fingerprint
+	moved, _ := Parse("main.go", strings.NewReader("\n\n\n// TODO: handle errors\n"))
+	if moved[0].Fingerprint != comments[0].Fingerprint {
+		t.Error("fingerprint should not depend on the line")
+	}
+	other, _ := Parse("other.go", strings.NewReader("// TODO: handle errors\n"))
+	if other[0].Fingerprint == comments[0].Fingerprint {
+		t.Error("fingerprint should depend on the file")
+	}
+}
+
+func TestTitle(t *testing.T) {
+	if got := (Comment{Tag: "TODO", File: "a.go"}).Title(); got != "TODO in a.go" {
+		t.Errorf("Title() without text = %q", got)
+	}
+	long := Comment{Tag: "TODO", Text: strings.Repeat("word ", 40)}
+	if got := long.Title(); len(got) > maxTitleLength || !strings.HasSuffix(got, "...") {
+		t.Errorf("Title() of a long comment = %q", got)
+	}
+}
+
+func TestFilesWithoutGit(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		".gitignore", "build/\n*.log\n/generated.go\n",
+		"main.go", "",
+		"app.log", "",
+		"generated.go", "",
+		"build/out.go", "",
+		".hidden/secret.go", "",
+		"pkg/lib.go", "",
+		"pkg/generated.go", "",
+	)
+
+	files, err := Files(dir, nil)
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	sort.Strings(files)
+	want := []string{".gitignore", "main.go", "pkg/generated.go", "pkg/lib.go"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Files() = %v, want %v", files, want)
+	}
+
+	files, _ = Files(dir, []string{"pkg"})
+	if len(files) != 2 {
+		t.Errorf("Files(pkg) = %v", files)
+	}
+}
+
+func TestFilesWithGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	if _, err := git.Run(dir, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir,
+		".gitignore", "vendor/\n",
+		"main.go", "// TODO: tracked\n",
+		"vendor/dep.go", "// TODO: ignored\n",
+		"image.png", "\x00\x01// TODO: binary\n",
+		".task/task.json", `{"title":"// TODO: a task"}`+"\n",
+	)
+
+	comments, scanned, err := Scan(dir, nil)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(comments) != 1 || comments[0].File != "main.go" || comments[0].Text != "tracked" {
+		t.Errorf("Scan() = %+v", comments)
+	}
+	if scanned != 2 {
+		t.Errorf("scanned %d files, want .gitignore and main.go", scanned)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	parse := func(file, src string) []Comment {
+		comments, err := Parse(file, strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return comments
+	}
+	newTask := func(id string, c Comment) model.Task {
+		task := model.NewTask(id, c.Title(), c.TaskType())
+		task.Source = c.Source()
+		return *task
+	}
+
+	before := append(parse("a.go", "// TODO: keep\n// TODO: move\n// TODO: remove\n"), parse("b.go", "// TODO: elsewhere\n")...)
+	closed := newTask("cls", parse("a.go", "// TODO: closed\n")[0])
+	closed.Status = model.StatusDone
+	tasks := []model.Task{
+		newTask("kep", before[0]),
+		newTask("mov", before[1]),
+		newTask("rem", before[2]),
+		newTask("els", before[3]),
+		closed,
+		*model.NewTask("man", "Made by hand", model.TypeTask),
+	}
+	archived := []model.Task{newTask("arc", parse("a.go", "// TODO: archived\n")[0])}
+
+	after := parse("a.go", "// TODO: keep\n// TODO: new\n\n// TODO: move\n// TODO: archived\n")
+	changes := Reconcile(tasks, archived, after, []string{"a.go"})
+
+	if len(changes.Create) != 1 || changes.Create[0].Text != "new" {
+		t.Errorf("Create = %+v", changes.Create)
+	}
+	if len(changes.Moved) != 1 || changes.Moved[0].ID != "mov" || changes.Moved[0].Source.Line != 4 {
+		t.Errorf("Moved = %+v", changes.Moved)
+	}
+	if len(changes.Removed) != 1 || changes.Removed[0].ID != "rem" {
+		t.Errorf("Removed = %+v, want only rem (els is outside the scanned path)", changes.Removed)
+	}
+	if tasks[1].Source.Line != 2 {
+		t.Error("Reconcile should not modify the tasks it is given")
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/jackreid/task/internal/exchange"
+	"github.com/jackreid/task/internal/store"
+)
+
+// CalendarPath is where task serve publishes the calendar feed
+const CalendarPath = "/calendar.ics"
+
+// CalendarHandler serves the store's tasks as an iCalendar feed for calendar
+// apps to subscribe to. It takes the same filters as GET /api/tasks, and
+// ?events=true adds an event on each due date as ICSOptions.Events does
+func CalendarHandler(s *store.Store, opts exchange.ICSOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			methodNotAllowed(w, http.MethodGet, http.MethodHead)
+			return
+		}
+		filter, err := parseFilter(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		feedOpts := opts
+		if v := r.URL.Query().Get("events"); v != "" {
+			events, err := strconv.ParseBool(v)
+			if err != nil {
+				writeError(w, errorStatus(http.StatusBadRequest, "invalid events: %s (must be true or false)", v))
+				return
+			}
+			feedOpts.Events = events
+		}
+
+		tasks, err := s.ListFiltered(filter)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var buf bytes.Buffer
+		if err := exchange.WriteICS(&buf, tasks, feedOpts); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", `inline; filename="calendar.ics"`)
+		w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,561 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/jackreid/task/internal/config"
+	"github.com/jackreid/task/internal/hooks"
+	"github.com/jackreid/task/internal/id"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+	"github.com/jackreid/task/internal/watch"
+)
+
+// maxBodySize limits the size of request bodies
+const maxBodySize = 1 << 20
+
+// Server serves a REST API for the tasks in a store
+//
+//	GET    /api/tasks             list tasks (?status=&type=&label=&q=&scope=live|archived|all)
+//	POST   /api/tasks             create a task
+//	GET    /api/tasks/{id}        get a task, live or archived
+//	PATCH  /api/tasks/{id}        update a task
+//	DELETE /api/tasks/{id}        delete a task
+//	POST   /api/tasks/{id}/notes  add a note to a task
+//	GET    /api/events            stream task changes as server-sent events
+//
+// Task responses carry an ETag derived from the task's UpdatedAt. Sending it
+// back in If-Match makes PATCH, DELETE and note requests fail with 412 if the
+// task was changed in the meantime.
+//
+// There is no authentication, so to keep web pages from using the API through
+// the browser, POST, PATCH and DELETE must be sent as application/json and the
+// Host header must be localhost, an IP address or a host added with AllowHost.
+// Hostnames are checked to stop DNS rebinding
+type Server struct {
+	store  *store.Store
+	config *config.Config
+	mux    *http.ServeMux
+	hosts  map[string]bool
+	// mu serialises mutations so concurrent requests don't overwrite each other
+	mu sync.Mutex
+}
+
+// New creates a server for the store. cfg supplies the label allowlist
+func New(s *store.Store, cfg *config.Config) *Server {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	srv := &Server{store: s, config: cfg, mux: http.NewServeMux(), hosts: map[string]bool{"localhost": true}}
+	srv.mux.HandleFunc("/api/tasks", srv.handleTasks)
+	srv.mux.HandleFunc("/api/tasks/", srv.handleTask)
+	srv.mux.Handle("/api/events", watch.Handler(s, watch.DefaultInterval))
+	return srv
+}
+
+// Handle registers an additional handler, e.g. for feeds built on the same store
+func (srv *Server) Handle(pattern string, handler http.Handler) {
+	srv.mux.Handle(pattern, handler)
+}
+
+// AllowHost accepts requests for a hostname, such as the one the server is
+// listening on
+func (srv *Server) AllowHost(host string) {
+	srv.hosts[strings.ToLower(host)] = true
+}
+
+// ServeHTTP implements http.Handler
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !srv.allowedHost(r.Host) {
+		writeError(w, errorStatus(http.StatusForbidden, "host not allowed: %s", r.Host))
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/") && (r.Method == http.MethodPost || r.Method == http.MethodPatch || r.Method == http.MethodDelete) {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			writeError(w, errorStatus(http.StatusUnsupportedMediaType, "Content-Type must be application/json"))
+			return
+		}
+	}
+	srv.mux.ServeHTTP(w, r)
+}
+
+// allowedHost reports whether a request's Host header names this server. IP
+// addresses can't be rebound, so only hostnames need to be known
+func (srv *Server) allowedHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	host = strings.Trim(host, "[]")
+	return net.ParseIP(host) != nil || srv.hosts[strings.ToLower(host)]
+}
+
+// apiError is an error with the HTTP status it should be reported with
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+func errorStatus(status int, format string, args ...interface{}) error {
+	return &apiError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+// createRequest is the body of POST /api/tasks
+type createRequest struct {
+	Title       string   `json:"title"`
+	Description *string  `json:"description"`
+	Type        string   `json:"type"`
+	Status      string   `json:"status"`
+	Labels      []string `json:"labels"`
+}
+
+// patchRequest is the body of PATCH /api/tasks/{id}. Absent fields are left
+// unchanged; a null description clears it
+type patchRequest struct {
+	Title        *string        `json:"title"`
+	Description  optionalString `json:"description"`
+	Type         *string        `json:"type"`
+	Status       *string        `json:"status"`
+	Labels       *[]string      `json:"labels"`
+	AddLabels    []string       `json:"add_labels"`
+	RemoveLabels []string       `json:"remove_labels"`
+}
+
+// noteRequest is the body of POST /api/tasks/{id}/notes
+type noteRequest struct {
+	Content string `json:"content"`
+}
+
+// optionalString tells an absent JSON field apart from an explicit null
+type optionalString struct {
+	Set   bool
+	Value *string
+}
+
+func (o *optionalString) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+func (srv *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		srv.list(w, r)
+	case http.MethodPost:
+		srv.create(w, r)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (srv *Server) handleTask(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/tasks/"), "/")
+	taskID, sub, _ := strings.Cut(rest, "/")
+	if taskID == "" {
+		srv.handleTasks(w, r)
+		return
+	}
+
+	switch {
+	case sub == "notes" && r.Method == http.MethodPost:
+		srv.addNote(w, r, taskID)
+	case sub == "notes":
+		methodNotAllowed(w, http.MethodPost)
+	case sub != "":
+		writeError(w, errorStatus(http.StatusNotFound, "not found: %s", r.URL.Path))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		srv.get(w, r, taskID)
+	case r.Method == http.MethodPatch:
+		srv.patch(w, r, taskID)
+	case r.Method == http.MethodDelete:
+		srv.delete(w, r, taskID)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPatch, http.MethodDelete)
+	}
+}
+
+func (srv *Server) list(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	tasks, err := srv.store.ListFiltered(filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if tasks == nil {
+		tasks = []model.Task{}
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+func (srv *Server) create(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		writeError(w, errorStatus(http.StatusBadRequest, "title is required"))
+		return
+	}
+
+	taskType := model.TypeTask
+	if req.Type != "" {
+		tt, err := model.ParseTaskType(req.Type)
+		if err != nil {
+			writeError(w, errorStatus(http.StatusBadRequest, "%v", err))
+			return
+		}
+		taskType = tt
+	}
+
+	status := model.StatusTodo
+	if req.Status != "" {
+		st, err := model.ParseStatus(req.Status)
+		if err != nil {
+			writeError(w, errorStatus(http.StatusBadRequest, "%v", err))
+			return
+		}
+		status = st
+	}
+
+	if err := srv.config.ValidateLabels(req.Labels); err != nil {
+		writeError(w, errorStatus(http.StatusBadRequest, "%v", err))
+		return
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	existingIDs, err := srv.store.GetExistingIDs()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	taskID, err := id.GenerateUnique(existingIDs)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	task := model.NewTask(taskID, title, taskType)
+	if req.Description != nil && *req.Description != "" {
+		task.SetDescription(*req.Description)
+	}
+	task.SetStatus(status)
+	for _, label := range req.Labels {
+		task.AddLabel(label)
+	}
+
+	if err := srv.store.Add(task); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/tasks/"+task.ID)
+	writeTask(w, http.StatusCreated, task)
+}
+
+func (srv *Server) get(w http.ResponseWriter, r *http.Request, taskID string) {
+	task, err := srv.store.FindByID(taskID)
+	if err == nil && task == nil {
+		task, err = srv.store.FindArchivedByID(taskID)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if task == nil {
+		writeError(w, errorStatus(http.StatusNotFound, "task not found: %s", taskID))
+		return
+	}
+
+	if match := r.Header.Get("If-None-Match"); match != "" && match == ETag(task) {
+		w.Header().Set("ETag", ETag(task))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+func (srv *Server) patch(w http.ResponseWriter, r *http.Request, taskID string) {
+	var req patchRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Validate everything before touching the task, as the CLI does
+	var taskType model.TaskType
+	if req.Type != nil {
+		tt, err := model.ParseTaskType(*req.Type)
+		if err != nil {
+			writeError(w, errorStatus(http.StatusBadRequest, "%v", err))
+			return
+		}
+		taskType = tt
+	}
+	var status model.Status
+	if req.Status != nil {
+		st, err := model.ParseStatus(*req.Status)
+		if err != nil {
+			writeError(w, errorStatus(http.StatusBadRequest, "%v", err))
+			return
+		}
+		status = st
+	}
+	if req.Title != nil && strings.TrimSpace(*req.Title) == "" {
+		writeError(w, errorStatus(http.StatusBadRequest, "title cannot be empty"))
+		return
+	}
+	var newLabels []string
+	if req.Labels != nil {
+		newLabels = *req.Labels
+	}
+	if err := srv.config.ValidateLabels(append(append([]string{}, newLabels...), req.AddLabels...)); err != nil {
+		writeError(w, errorStatus(http.StatusBadRequest, "%v", err))
+		return
+	}
+
+	srv.mutate(w, r, taskID, http.StatusOK, func(task *model.Task) error {
+		if req.Title != nil {
+			task.SetTitle(strings.TrimSpace(*req.Title))
+		}
+		if req.Description.Set {
+			task.SetDescriptionValue(req.Description.Value)
+		}
+		if req.Labels != nil {
+			labels := []string{}
+			for _, l := range *req.Labels {
+				if !containsString(labels, l) {
+					labels = append(labels, l)
+				}
+			}
+			task.SetLabels(labels)
+		}
+		for _, label := range req.AddLabels {
+			task.AddLabel(label)
+		}
+		for _, label := range req.RemoveLabels {
+			task.RemoveLabel(label)
+		}
+		if taskType != "" {
+			if err := task.SetType(taskType); err != nil {
+				return err
+			}
+		}
+		if status != "" {
+			if err := task.SetStatus(status); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (srv *Server) addNote(w http.ResponseWriter, r *http.Request, taskID string) {
+	var req noteRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		writeError(w, errorStatus(http.StatusBadRequest, "content is required"))
+		return
+	}
+
+	srv.mutate(w, r, taskID, http.StatusCreated, func(task *model.Task) error {
+		noteID, err := id.GenerateNoteID(task.ID)
+		if err != nil {
+			return err
+		}
+		task.AddNote(noteID, content)
+		return nil
+	})
+}
+
+func (srv *Server) delete(w http.ResponseWriter, r *http.Request, taskID string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if _, err := srv.findForWrite(r, taskID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := srv.store.Delete(taskID); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mutate loads a live task, checks If-Match, applies fn and saves the task
+func (srv *Server) mutate(w http.ResponseWriter, r *http.Request, taskID string, status int, fn func(*model.Task) error) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	task, err := srv.findForWrite(r, taskID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := fn(task); err != nil {
+		writeError(w, errorStatus(http.StatusBadRequest, "%v", err))
+		return
+	}
+	if err := srv.store.Update(task); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeTask(w, status, task)
+}
+
+// findForWrite finds a live task and checks the request's If-Match header
+// against it. Archived tasks are read-only
+func (srv *Server) findForWrite(r *http.Request, taskID string) (*model.Task, error) {
+	task, err := srv.store.FindByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		if archived, err := srv.store.FindArchivedByID(taskID); err == nil && archived != nil {
+			return nil, errorStatus(http.StatusConflict, "task is archived: %s", taskID)
+		}
+		return nil, errorStatus(http.StatusNotFound, "task not found: %s", taskID)
+	}
+
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" && match != ETag(task) {
+		return nil, errorStatus(http.StatusPreconditionFailed, "task %s was modified (current ETag %s)", taskID, ETag(task))
+	}
+	return task, nil
+}
+
+// ETag returns the entity tag of a task. It changes whenever the task is
+// updated: UpdatedAt is combined with a hash of the task, so edits made
+// within the same second still get a new tag
+func ETag(task *model.Task) string {
+	h := fnv.New32a()
+	data, _ := json.Marshal(task)
+	h.Write(data)
+	return fmt.Sprintf(`"%d-%08x"`, task.UpdatedAt.Unix(), h.Sum32())
+}
+
+// parseFilter builds a store filter from list query parameters
+func parseFilter(r *http.Request) (store.Filter, error) {
+	q := r.URL.Query()
+	filter := store.Filter{}
+
+	if v := q.Get("status"); v != "" {
+		status, err := model.ParseStatus(v)
+		if err != nil {
+			return filter, errorStatus(http.StatusBadRequest, "%v", err)
+		}
+		filter.Status = &status
+	}
+	if v := q.Get("type"); v != "" {
+		tt, err := model.ParseTaskType(v)
+		if err != nil {
+			return filter, errorStatus(http.StatusBadRequest, "%v", err)
+		}
+		filter.Type = &tt
+	}
+	if v := q.Get("label"); v != "" {
+		filter.Label = &v
+	}
+	if v := q.Get("q"); v != "" {
+		filter.Query = &v
+	}
+
+	switch q.Get("scope") {
+	case "", "live":
+	case "archived":
+		filter.Scope = store.ScopeArchived
+	case "all":
+		filter.Scope = store.ScopeAll
+	default:
+		return filter, errorStatus(http.StatusBadRequest, "invalid scope: %s (must be live, archived or all)", q.Get("scope"))
+	}
+	return filter, nil
+}
+
+// decodeBody decodes a JSON request body, rejecting unknown fields
+func decodeBody(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errorStatus(http.StatusBadRequest, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+func writeTask(w http.ResponseWriter, status int, task *model.Task) {
+	w.Header().Set("ETag", ETag(task))
+	writeJSON(w, status, task)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// writeError reports err as {"error": "..."}, using its status when it is an
+// apiError, 422 when a hook rejected the change and 500 otherwise
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *apiError
+	var hookErr *hooks.Error
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.status
+	case errors.As(err, &hookErr):
+		status = http.StatusUnprocessableEntity
+	}
+
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, errorStatus(http.StatusMethodNotAllowed, "method not allowed"))
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
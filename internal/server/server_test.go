@@ -0,0 +1,449 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/config"
+	"github.com/jackreid/task/internal/exchange"
+	"github.com/jackreid/task/internal/hooks"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+// newTestServer starts a server over a fresh store
+func newTestServer(t *testing.T, cfg *config.Config) (*httptest.Server, *store.Store) {
+	t.Helper()
+	s := store.New(t.TempDir())
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	ts := httptest.NewServer(New(s, cfg))
+	t.Cleanup(ts.Close)
+	return ts, s
+}
+
+// do sends a request with an optional JSON body and headers. Writes are sent
+// as application/json unless headers say otherwise
+func do(t *testing.T, method, url, body string, headers ...string) *http.Response {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// decode reads a JSON response body into v
+func decode(t *testing.T, resp *http.Response, v interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+}
+
+func expectStatus(t *testing.T, resp *http.Response, want int) {
+	t.Helper()
+	if resp.StatusCode != want {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("%s %s status = %d, want %d: %s", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, want, body)
+	}
+}
+
+func TestCreateAndGet(t *testing.T) {
+	ts, s := newTestServer(t, nil)
+
+	resp := do(t, "POST", ts.URL+"/api/tasks", `{"title": "Fix login", "type": "bug", "labels": ["auth"], "description": "Broken"}`)
+	expectStatus(t, resp, http.StatusCreated)
+
+	var created model.Task
+	decode(t, resp, &created)
+	if created.ID == "" || created.Title != "Fix login" || created.Type != model.TypeBug || created.Status != model.StatusTodo {
+		t.Errorf("created task = %+v", created)
+	}
+	if resp.Header.Get("Location") != "/api/tasks/"+created.ID {
+		t.Errorf("Location = %q", resp.Header.Get("Location"))
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Error("create should return an ETag")
+	}
+
+	stored, _ := s.FindByID(created.ID)
+	if stored == nil || *stored.Description != "Broken" {
+		t.Errorf("stored task = %+v", stored)
+	}
+
+	resp = do(t, "GET", ts.URL+"/api/tasks/"+created.ID, "")
+	expectStatus(t, resp, http.StatusOK)
+	if resp.Header.Get("ETag") != etag {
+		t.Errorf("GET ETag = %q, want %q", resp.Header.Get("ETag"), etag)
+	}
+
+	resp = do(t, "GET", ts.URL+"/api/tasks/"+created.ID, "", "If-None-Match", etag)
+	expectStatus(t, resp, http.StatusNotModified)
+
+	resp = do(t, "GET", ts.URL+"/api/tasks/zzz", "")
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestCreateValidation(t *testing.T) {
+	ts, _ := newTestServer(t, &config.Config{
+		RestrictLabels: true,
+		Labels:         []config.Label{{Name: "auth"}},
+	})
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"missing title", `{"type": "bug"}`, "title is required"},
+		{"invalid type", `{"title": "x", "type": "epic"}`, "invalid type: epic"},
+		{"invalid status", `{"title": "x", "status": "finished"}`, "invalid status: finished"},
+		{"unknown label", `{"title": "x", "labels": ["ui"]}`, "unknown label: ui"},
+		{"unknown field", `{"title": "x", "priority": 1}`, "unknown field"},
+		{"malformed", `{"title": `, "invalid request body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := do(t, "POST", ts.URL+"/api/tasks", tt.body)
+			expectStatus(t, resp, http.StatusBadRequest)
+			var body map[string]string
+			decode(t, resp, &body)
+			if !strings.Contains(body["error"], tt.want) {
+				t.Errorf("error = %q, want it to contain %q", body["error"], tt.want)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	ts, s := newTestServer(t, nil)
+
+	bug := model.NewTask("aaa", "Login bug", model.TypeBug)
+	bug.Labels = []string{"auth"}
+	s.Add(bug)
+	s.Add(model.NewTask("bbb", "Write docs", model.TypeTask))
+	done := model.NewTask("ccc", "Old work", model.TypeTask)
+	done.SetStatus(model.StatusDone)
+	s.Add(done)
+	s.Archive(done.UpdatedAt.Add(1))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"aaa", "bbb"}},
+		{"?type=bug", []string{"aaa"}},
+		{"?label=auth", []string{"aaa"}},
+		{"?q=docs", []string{"bbb"}},
+		{"?scope=archived", []string{"ccc"}},
+		{"?scope=all&status=done", []string{"ccc"}},
+	}
+
+	for _, tt := range tests {
+		resp := do(t, "GET", ts.URL+"/api/tasks"+tt.query, "")
+		expectStatus(t, resp, http.StatusOK)
+		var tasks []model.Task
+		decode(t, resp, &tasks)
+
+		var ids []string
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		if strings.Join(sortedCopy(ids), ",") != strings.Join(tt.want, ",") {
+			t.Errorf("GET /api/tasks%s = %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+
+	resp := do(t, "GET", ts.URL+"/api/tasks?status=blocked", "")
+	expectStatus(t, resp, http.StatusOK)
+	if body, _ := io.ReadAll(resp.Body); strings.TrimSpace(string(body)) != "[]" {
+		t.Errorf("GET /api/tasks with no matches = %s, want []", body)
+	}
+
+	resp = do(t, "GET", ts.URL+"/api/tasks?status=nope", "")
+	expectStatus(t, resp, http.StatusBadRequest)
+	resp = do(t, "GET", ts.URL+"/api/tasks?scope=nope", "")
+	expectStatus(t, resp, http.StatusBadRequest)
+}
+
+// rejectHook rejects every mutation as a failing hook script would
+type rejectHook struct{}
+
+func (rejectHook) BeforeMutation(m store.Mutation) error {
+	return &hooks.Error{Hook: "pre-" + string(m.Event), Stderr: "not today"}
+}
+
+func (rejectHook) AfterMutation(m store.Mutation) {}
+
+func TestHookRejection(t *testing.T) {
+	ts, s := newTestServer(t, nil)
+	s.Add(model.NewTask("aaa", "Existing", model.TypeTask))
+	s.AddHook(rejectHook{})
+
+	resp := do(t, "POST", ts.URL+"/api/tasks", `{"title": "New"}`)
+	expectStatus(t, resp, http.StatusUnprocessableEntity)
+	var body map[string]string
+	decode(t, resp, &body)
+	if body["error"] != "pre-create hook failed: not today" {
+		t.Errorf("error = %q", body["error"])
+	}
+
+	resp = do(t, "PATCH", ts.URL+"/api/tasks/aaa", `{"title": "x"}`)
+	expectStatus(t, resp, http.StatusUnprocessableEntity)
+	resp = do(t, "DELETE", ts.URL+"/api/tasks/aaa", "")
+	expectStatus(t, resp, http.StatusUnprocessableEntity)
+}
+
+func TestPatch(t *testing.T) {
+	ts, s := newTestServer(t, nil)
+
+	task := model.NewTask("aaa", "Original", model.TypeTask)
+	task.Labels = []string{"keep", "drop"}
+	task.SetDescription("Some text")
+	s.Add(task)
+
+	resp := do(t, "PATCH", ts.URL+"/api/tasks/aaa",
+		`{"title": "Renamed", "status": "progress", "description": null, "add_labels": ["new"], "remove_labels": ["drop"]}`)
+	expectStatus(t, resp, http.StatusOK)
+
+	var updated model.Task
+	decode(t, resp, &updated)
+	if updated.Title != "Renamed" || updated.Status != model.StatusProgress || updated.Description != nil {
+		t.Errorf("patched task = %+v", updated)
+	}
+	if strings.Join(updated.Labels, ",") != "keep,new" {
+		t.Errorf("patched labels = %v, want [keep new]", updated.Labels)
+	}
+
+	stored, _ := s.FindByID("aaa")
+	if stored.Title != "Renamed" {
+		t.Error("PATCH should save the task")
+	}
+
+	// Invalid values leave the task unchanged
+	resp = do(t, "PATCH", ts.URL+"/api/tasks/aaa", `{"title": "Again", "status": "finished"}`)
+	expectStatus(t, resp, http.StatusBadRequest)
+	stored, _ = s.FindByID("aaa")
+	if stored.Title != "Renamed" {
+		t.Error("a rejected PATCH should not change the task")
+	}
+
+	resp = do(t, "PATCH", ts.URL+"/api/tasks/zzz", `{"title": "x"}`)
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	ts, s := newTestServer(t, nil)
+	s.Add(model.NewTask("aaa", "Shared", model.TypeTask))
+
+	resp := do(t, "GET", ts.URL+"/api/tasks/aaa", "")
+	etag := resp.Header.Get("ETag")
+
+	// First writer succeeds and gets a new ETag
+	resp = do(t, "PATCH", ts.URL+"/api/tasks/aaa", `{"status": "progress"}`, "If-Match", etag)
+	expectStatus(t, resp, http.StatusOK)
+	newTag := resp.Header.Get("ETag")
+	if newTag == etag {
+		t.Fatal("ETag should change when the task changes, even within the same second")
+	}
+
+	// Second writer with the stale ETag is rejected
+	resp = do(t, "PATCH", ts.URL+"/api/tasks/aaa", `{"status": "blocked"}`, "If-Match", etag)
+	expectStatus(t, resp, http.StatusPreconditionFailed)
+	resp = do(t, "POST", ts.URL+"/api/tasks/aaa/notes", `{"content": "late"}`, "If-Match", etag)
+	expectStatus(t, resp, http.StatusPreconditionFailed)
+	resp = do(t, "DELETE", ts.URL+"/api/tasks/aaa", "", "If-Match", etag)
+	expectStatus(t, resp, http.StatusPreconditionFailed)
+
+	stored, _ := s.FindByID("aaa")
+	if stored == nil || stored.Status != model.StatusProgress || len(stored.Notes) != 0 {
+		t.Errorf("task after rejected writes = %+v", stored)
+	}
+
+	resp = do(t, "PATCH", ts.URL+"/api/tasks/aaa", `{"status": "done"}`, "If-Match", newTag)
+	expectStatus(t, resp, http.StatusOK)
+}
+
+func TestAddNoteAndDelete(t *testing.T) {
+	ts, s := newTestServer(t, nil)
+	s.Add(model.NewTask("aaa", "Noted", model.TypeTask))
+
+	resp := do(t, "POST", ts.URL+"/api/tasks/aaa/notes", `{"content": "  Progress update  "}`)
+	expectStatus(t, resp, http.StatusCreated)
+	var task model.Task
+	decode(t, resp, &task)
+	if len(task.Notes) != 1 || task.Notes[0].Content != "Progress update" || !strings.HasPrefix(task.Notes[0].ID, "aaa-") {
+		t.Errorf("notes after POST = %+v", task.Notes)
+	}
+
+	resp = do(t, "POST", ts.URL+"/api/tasks/aaa/notes", `{"content": ""}`)
+	expectStatus(t, resp, http.StatusBadRequest)
+
+	resp = do(t, "DELETE", ts.URL+"/api/tasks/aaa", "")
+	expectStatus(t, resp, http.StatusNoContent)
+	if found, _ := s.FindByID("aaa"); found != nil {
+		t.Error("DELETE should remove the task")
+	}
+
+	resp = do(t, "DELETE", ts.URL+"/api/tasks/aaa", "")
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestArchivedTasksAreReadOnly(t *testing.T) {
+	ts, s := newTestServer(t, nil)
+	task := model.NewTask("aaa", "Finished", model.TypeTask)
+	task.SetStatus(model.StatusDone)
+	s.Add(task)
+	s.Archive(task.UpdatedAt.Add(1))
+
+	resp := do(t, "GET", ts.URL+"/api/tasks/aaa", "")
+	expectStatus(t, resp, http.StatusOK)
+
+	resp = do(t, "PATCH", ts.URL+"/api/tasks/aaa", `{"title": "x"}`)
+	expectStatus(t, resp, http.StatusConflict)
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	ts, _ := newTestServer(t, nil)
+
+	resp := do(t, "PUT", ts.URL+"/api/tasks", "")
+	expectStatus(t, resp, http.StatusMethodNotAllowed)
+	if resp.Header.Get("Allow") != "GET, POST" {
+		t.Errorf("Allow = %q", resp.Header.Get("Allow"))
+	}
+
+	resp = do(t, "GET", ts.URL+"/api/tasks/aaa/notes", "")
+	expectStatus(t, resp, http.StatusMethodNotAllowed)
+}
+
+func sortedCopy(items []string) []string {
+	out := append([]string{}, items...)
+	for i := range out {
+		for j := i + 1; j < len(out); j++ {
+			if out[j] < out[i] {
+				out[i], out[j] = out[j], out[i]
+			}
+		}
+	}
+	return out
+}
+
+func TestCrossSiteRequests(t *testing.T) {
+	s := store.New(t.TempDir())
+	s.Init()
+	srv := New(s, nil)
+	srv.AllowHost("tasks.internal")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	// A web page can POST text/plain cross-site without a preflight
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+		resp := do(t, "POST", ts.URL+"/api/tasks", `{"title": "Forged"}`, "Content-Type", contentType)
+		expectStatus(t, resp, http.StatusUnsupportedMediaType)
+	}
+	resp := do(t, "DELETE", ts.URL+"/api/tasks/aaa", "", "Content-Type", "")
+	expectStatus(t, resp, http.StatusUnsupportedMediaType)
+	if tasks, _ := s.Load(); len(tasks) != 0 {
+		t.Errorf("requests without a JSON Content-Type created %v", tasks)
+	}
+	resp = do(t, "POST", ts.URL+"/api/tasks", `{"title": "Real"}`, "Content-Type", "application/json; charset=utf-8")
+	expectStatus(t, resp, http.StatusCreated)
+
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"evil.example", http.StatusForbidden},
+		{"evil.example:8080", http.StatusForbidden},
+		{"localhost:8080", http.StatusOK},
+		{"127.0.0.1:8080", http.StatusOK},
+		{"[::1]:8080", http.StatusOK},
+		{"Tasks.Internal:8080", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/tasks", nil)
+		req.Host = tt.host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET with Host %s error = %v", tt.host, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("GET with Host %s = %d, want %d", tt.host, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestCalendarFeed(t *testing.T) {
+	s := store.New(t.TempDir())
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	due := model.NewTask("aaa", "Ship it", model.TypeTask)
+	due.Due = time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	due.Labels = []string{"release"}
+	done := model.NewTask("bbb", "Plan it", model.TypeTask)
+	done.SetStatus(model.StatusDone)
+	if err := s.AddMany([]model.Task{*due, *done}); err != nil {
+		t.Fatalf("AddMany() error = %v", err)
+	}
+	srv := New(s, nil)
+	srv.Handle(CalendarPath, CalendarHandler(s, exchange.ICSOptions{Name: "Demo", Domain: "demo"}))
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	resp := do(t, "GET", ts.URL+CalendarPath, "")
+	expectStatus(t, resp, http.StatusOK)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/calendar; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{"X-WR-CALNAME:Demo", "UID:aaa@demo", "UID:bbb@demo", "STATUS:COMPLETED", "CATEGORIES:release"} {
+		if !strings.Contains(string(body), want+"\r\n") {
+			t.Errorf("feed missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(string(body), "BEGIN:VEVENT") {
+		t.Errorf("feed has events without ?events:\n%s", body)
+	}
+
+	resp = do(t, "GET", ts.URL+CalendarPath+"?label=release&events=true", "")
+	expectStatus(t, resp, http.StatusOK)
+	body, _ = io.ReadAll(resp.Body)
+	if strings.Contains(string(body), "UID:bbb@demo") {
+		t.Errorf("filtered feed has bbb:\n%s", body)
+	}
+	if !strings.Contains(string(body), "UID:aaa-due@demo\r\n") {
+		t.Errorf("feed missing due event:\n%s", body)
+	}
+
+	resp = do(t, "GET", ts.URL+CalendarPath+"?events=maybe", "")
+	expectStatus(t, resp, http.StatusBadRequest)
+
+	resp = do(t, "POST", ts.URL+CalendarPath, "")
+	expectStatus(t, resp, http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,99 @@
+// Filters the board and list views using the task data embedded in the page
+(function () {
+  var data = document.getElementById("task-data");
+  if (!data) {
+    return;
+  }
+
+  var tasks = {};
+  JSON.parse(data.textContent).forEach(function (task) {
+    tasks[task.id] = task;
+  });
+
+  var text = document.getElementById("filter-text");
+  var status = document.getElementById("filter-status");
+  var type = document.getElementById("filter-type");
+  var label = document.getElementById("filter-label");
+  var noMatch = document.getElementById("no-match");
+
+  function searchText(task) {
+    var parts = [task.id, task.title, task.description || ""].concat(task.labels || []);
+    (task.notes || []).forEach(function (note) {
+      parts.push(note.content);
+    });
+    return parts.join("\n").toLowerCase();
+  }
+
+  function matches(task) {
+    if (!task) {
+      return false;
+    }
+    if (status.value && task.status !== status.value) {
+      return false;
+    }
+    if (type.value && task.type !== type.value) {
+      return false;
+    }
+    if (label.value && (task.labels || []).indexOf(label.value) < 0) {
+      return false;
+    }
+    var query = text.value.trim().toLowerCase();
+    return !query || searchText(task).indexOf(query) >= 0;
+  }
+
+  function apply() {
+    var shown = {};
+    document.querySelectorAll(".task[data-id]").forEach(function (el) {
+      var visible = matches(tasks[el.dataset.id]);
+      el.hidden = !visible;
+      if (visible) {
+        shown[el.dataset.id] = true;
+      }
+    });
+
+    document.querySelectorAll(".column").forEach(function (column) {
+      var count = column.querySelectorAll(".task:not([hidden])").length;
+      column.querySelector(".count").textContent = count;
+    });
+
+    noMatch.hidden = Object.keys(shown).length > 0;
+
+    var params = new URLSearchParams();
+    if (text.value) params.set("q", text.value);
+    if (status.value) params.set("status", status.value);
+    if (type.value) params.set("type", type.value);
+    if (label.value) params.set("label", label.value);
+    var view = document.querySelector(".views .active").dataset.view;
+    if (view !== "board") params.set("view", view);
+    history.replaceState(null, "", params.toString() ? "?" + params : location.pathname);
+  }
+
+  function showView(name) {
+    document.querySelectorAll(".view").forEach(function (el) {
+      el.hidden = el.id !== name;
+    });
+    document.querySelectorAll(".views button").forEach(function (button) {
+      button.classList.toggle("active", button.dataset.view === name);
+    });
+  }
+
+  // Restore filters from the URL so filtered views can be shared
+  var params = new URLSearchParams(location.search);
+  text.value = params.get("q") || "";
+  status.value = params.get("status") || "";
+  type.value = params.get("type") || "";
+  label.value = params.get("label") || "";
+  showView(params.get("view") === "list" ? "list" : "board");
+
+  [text, status, type, label].forEach(function (el) {
+    el.addEventListener("input", apply);
+  });
+  document.querySelectorAll(".views button").forEach(function (button) {
+    button.addEventListener("click", function () {
+      showView(button.dataset.view);
+      apply();
+    });
+  });
+
+  apply();
+})();
@@ -0,0 +1,157 @@
+package site
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	headingPattern    = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletPattern     = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	orderedPattern    = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	linkPattern       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldPattern       = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	italicPattern     = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	underscorePattern = regexp.MustCompile(`(^|[^\w])_([^_\s][^_]*)_([^\w]|$)`)
+)
+
+// headingOffset shifts Markdown heading levels so a description's "# Heading"
+// sits below the page's own h1 title and h2 sections
+const headingOffset = 2
+
+// renderMarkdown converts the common subset of Markdown used in task
+// descriptions to HTML: paragraphs, headings, bullet and numbered lists,
+// block quotes, fenced code blocks, inline code, bold, italics and links.
+// All text is escaped, so the result is safe to embed in a page
+func renderMarkdown(src string) template.HTML {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	var b strings.Builder
+	var paragraph, quote []string
+	listTag := ""
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + renderInline(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	flushQuote := func() {
+		if len(quote) > 0 {
+			b.WriteString("<blockquote><p>" + renderInline(strings.Join(quote, "\n")) + "</p></blockquote>\n")
+			quote = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			b.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+	flushAll := func() {
+		flushParagraph()
+		flushQuote()
+		closeList()
+	}
+	openList := func(tag string) {
+		if listTag != tag {
+			flushAll()
+			b.WriteString("<" + tag + ">\n")
+			listTag = tag
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			flushAll()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flushAll()
+		case headingPattern.MatchString(trimmed):
+			flushAll()
+			m := headingPattern.FindStringSubmatch(trimmed)
+			level := len(m[1]) + headingOffset
+			if level > 6 {
+				level = 6
+			}
+			tag := "h" + strconv.Itoa(level)
+			b.WriteString("<" + tag + ">" + renderInline(m[2]) + "</" + tag + ">\n")
+		case bulletPattern.MatchString(trimmed):
+			openList("ul")
+			b.WriteString("<li>" + renderInline(bulletPattern.FindStringSubmatch(trimmed)[1]) + "</li>\n")
+		case orderedPattern.MatchString(trimmed):
+			openList("ol")
+			b.WriteString("<li>" + renderInline(orderedPattern.FindStringSubmatch(trimmed)[1]) + "</li>\n")
+		case strings.HasPrefix(trimmed, ">"):
+			flushParagraph()
+			closeList()
+			quote = append(quote, strings.TrimSpace(strings.TrimPrefix(trimmed, ">")))
+		default:
+			flushQuote()
+			closeList()
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flushAll()
+
+	return template.HTML(b.String())
+}
+
+// renderInline escapes text and applies code spans, bold, italics and links
+func renderInline(s string) string {
+	var b strings.Builder
+	parts := strings.Split(s, "`")
+	for i, part := range parts {
+		switch {
+		case i%2 == 1 && i < len(parts)-1:
+			b.WriteString("<code>" + html.EscapeString(part) + "</code>")
+		case i%2 == 1:
+			// Unmatched backtick
+			b.WriteString("`" + formatSpans(html.EscapeString(part)))
+		default:
+			b.WriteString(formatSpans(html.EscapeString(part)))
+		}
+	}
+	return b.String()
+}
+
+// formatSpans applies emphasis and links to already escaped text
+func formatSpans(s string) string {
+	s = linkPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := linkPattern.FindStringSubmatch(m)
+		if !safeURL(html.UnescapeString(parts[2])) {
+			return parts[1]
+		}
+		return `<a href="` + parts[2] + `">` + parts[1] + `</a>`
+	})
+	s = boldPattern.ReplaceAllString(s, "<strong>$1</strong>")
+	s = italicPattern.ReplaceAllString(s, "<em>$1</em>")
+	s = underscorePattern.ReplaceAllString(s, "$1<em>$2</em>$3")
+	return s
+}
+
+// safeURL allows http(s), mailto and relative links
+func safeURL(u string) bool {
+	lower := strings.ToLower(u)
+	for _, scheme := range []string{"http://", "https://", "mailto:"} {
+		if strings.HasPrefix(lower, scheme) {
+			return true
+		}
+	}
+	colon := strings.Index(u, ":")
+	return colon < 0 || (strings.ContainsAny(u[:colon], "/?#"))
+}
@@ -0,0 +1,67 @@
+package site
+
+import (
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "paragraphs",
+			in:   "First line\nsame paragraph\n\nSecond",
+			want: "<p>First line\nsame paragraph</p>\n<p>Second</p>\n",
+		},
+		{
+			name: "heading levels are shifted",
+			in:   "# Plan\n###### Deep",
+			want: "<h3>Plan</h3>\n<h6>Deep</h6>\n",
+		},
+		{
+			name: "lists",
+			in:   "- one\n* two\n1. first\n2) second",
+			want: "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n",
+		},
+		{
+			name: "code block is escaped and not formatted",
+			in:   "```go\nif a < b && *p {\n```",
+			want: "<pre><code>if a &lt; b &amp;&amp; *p {</code></pre>\n",
+		},
+		{
+			name: "inline formatting",
+			in:   "Use `a<b` with **care**, *really* and _truly_ snake_case_name",
+			want: "<p>Use <code>a&lt;b</code> with <strong>care</strong>, <em>really</em> and <em>truly</em> snake_case_name</p>\n",
+		},
+		{
+			name: "quote",
+			in:   "> quoted\n> text",
+			want: "<blockquote><p>quoted\ntext</p></blockquote>\n",
+		},
+		{
+			name: "html is escaped",
+			in:   "<script>alert(1)</script>",
+			want: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n",
+		},
+		{
+			name: "safe links",
+			in:   "[docs](https://example.com/a_b?x=1&y=2) and [rel](../x.html)",
+			want: "<p><a href=\"https://example.com/a_b?x=1&amp;y=2\">docs</a> and <a href=\"../x.html\">rel</a></p>\n",
+		},
+		{
+			name: "unsafe links are dropped",
+			in:   "[click](javascript:alert)",
+			want: "<p>click</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(renderMarkdown(tt.in)); got != tt.want {
+				t.Errorf("renderMarkdown(%q) =\n%q\nwant\n%q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package site
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+//go:embed assets
+var assetFS embed.FS
+
+// Generated page directories within the output directory
+const (
+	tasksDir  = "tasks"
+	labelsDir = "labels"
+)
+
+// dataFile holds the site's tasks as JSON for scripts and other tools
+const dataFile = "tasks.json"
+
+// boardStatuses is the column order of the board view
+var boardStatuses = []model.Status{
+	model.StatusTodo,
+	model.StatusProgress,
+	model.StatusBlocked,
+	model.StatusDone,
+	model.StatusAbandon,
+}
+
+// Options controls site generation
+type Options struct {
+	// Title is shown in the page headers; empty uses "Tasks"
+	Title string
+	// Generated is the time shown in page footers; zero uses the current time
+	Generated time.Time
+}
+
+// Column is one status column of the board view
+type Column struct {
+	Status model.Status
+	Tasks  []model.Task
+}
+
+// Label is a label with the tasks that carry it
+type Label struct {
+	Name  string
+	Slug  string
+	Tasks []model.Task
+}
+
+// page is the data passed to every page template
+type page struct {
+	Title     string
+	Root      string
+	Generated time.Time
+	Labels    []*Label
+	Statuses  []model.Status
+	Types     []model.TaskType
+
+	// Tasks is shown by the index and label pages
+	Tasks []model.Task
+	// Columns is the board view of the index page
+	Columns []Column
+
+	// Task page
+	Task *model.Task
+
+	// Label page
+	Label *Label
+}
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Render returns every file of the site, keyed by slash separated path
+// relative to the site root: the index with board and list views, a page
+// per task and per label, the stylesheet and script, and tasks.json
+func Render(tasks []model.Task, opts Options) (map[string][]byte, error) {
+	tmpl, err := template.New("site").Funcs(funcs).ParseFS(templateFS, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("parsing templates: %w", err)
+	}
+
+	if opts.Title == "" {
+		opts.Title = "Tasks"
+	}
+	if opts.Generated.IsZero() {
+		opts.Generated = time.Now()
+	}
+	if tasks == nil {
+		tasks = []model.Task{}
+	}
+
+	labels := groupLabels(tasks)
+	base := page{
+		Title:     opts.Title,
+		Generated: opts.Generated,
+		Labels:    labels,
+		Statuses:  boardStatuses,
+		Types:     model.AllTaskTypes(),
+	}
+
+	files := make(map[string][]byte)
+	execute := func(name, file string, data page) error {
+		var b strings.Builder
+		if err := tmpl.ExecuteTemplate(&b, name, data); err != nil {
+			return fmt.Errorf("rendering %s: %w", file, err)
+		}
+		files[file] = []byte(b.String())
+		return nil
+	}
+
+	index := base
+	index.Tasks = tasks
+	index.Columns = groupColumns(tasks)
+	if err := execute("index.html", "index.html", index); err != nil {
+		return nil, err
+	}
+
+	for i := range tasks {
+		p := base
+		p.Root = "../"
+		p.Task = &tasks[i]
+		if err := execute("task.html", path.Join(tasksDir, tasks[i].ID+".html"), p); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, label := range labels {
+		p := base
+		p.Root = "../"
+		p.Label = label
+		p.Tasks = label.Tasks
+		if err := execute("label.html", path.Join(labelsDir, label.Slug+".html"), p); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files[dataFile] = append(data, '\n')
+
+	err = fs.WalkDir(assetFS, "assets", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := assetFS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		files[name] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Generate renders the site into dir. Task and label pages left over from a
+// previous run whose task or label no longer exists are removed
+func Generate(dir string, tasks []model.Task, opts Options) (int, error) {
+	files, err := Render(tasks, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	for name, data := range files {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return 0, fmt.Errorf("creating directory: %w", err)
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return 0, fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+
+	for _, sub := range []string{tasksDir, labelsDir} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := path.Join(sub, e.Name())
+			if _, ok := files[name]; !ok && strings.HasSuffix(name, ".html") {
+				if err := os.Remove(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+					return 0, fmt.Errorf("removing stale page: %w", err)
+				}
+			}
+		}
+	}
+
+	return len(files), nil
+}
+
+// groupColumns splits tasks into the board's status columns
+func groupColumns(tasks []model.Task) []Column {
+	columns := make([]Column, len(boardStatuses))
+	for i, status := range boardStatuses {
+		columns[i].Status = status
+		for _, t := range tasks {
+			if t.Status == status {
+				columns[i].Tasks = append(columns[i].Tasks, t)
+			}
+		}
+	}
+	return columns
+}
+
+// groupLabels returns every label in use, sorted by name, with a unique slug
+// for its page
+func groupLabels(tasks []model.Task) []*Label {
+	byName := make(map[string]*Label)
+	for _, t := range tasks {
+		for _, name := range t.Labels {
+			if byName[name] == nil {
+				byName[name] = &Label{Name: name}
+			}
+			byName[name].Tasks = append(byName[name].Tasks, t)
+		}
+	}
+
+	labels := make([]*Label, 0, len(byName))
+	for _, l := range byName {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+	used := make(map[string]bool)
+	for _, l := range labels {
+		slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(l.Name), "-"), "-")
+		if slug == "" {
+			slug = "label"
+		}
+		unique := slug
+		for n := 2; used[unique]; n++ {
+			unique = slug + "-" + strconv.Itoa(n)
+		}
+		used[unique] = true
+		l.Slug = unique
+	}
+	return labels
+}
+
+// labelSlug finds the page slug of a label
+func labelSlug(labels []*Label, name string) string {
+	for _, l := range labels {
+		if l.Name == name {
+			return l.Slug
+		}
+	}
+	return ""
+}
+
+var funcs = template.FuncMap{
+	"markdown":  renderMarkdown,
+	"labelSlug": labelSlug,
+	"date": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04 UTC")
+	},
+	"isoDate": func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+	"symbol": func(s model.Status) string {
+		switch s {
+		case model.StatusTodo:
+			return "○"
+		case model.StatusProgress:
+			return "◐"
+		case model.StatusBlocked:
+			return "✕"
+		case model.StatusAbandon:
+			return "⊘"
+		case model.StatusDone:
+			return "●"
+		}
+		return "?"
+	},
+	"icon": func(t model.TaskType) string {
+		switch t {
+		case model.TypeBug:
+			return "🐛"
+		case model.TypeFeature:
+			return "✨"
+		}
+		return "📋"
+	},
+	"description": func(t *model.Task) string {
+		if t.Description == nil {
+			return ""
+		}
+		return *t.Description
+	},
+}
@@ -0,0 +1,115 @@
+package site
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+func sampleTasks() []model.Task {
+	first := model.NewTask("aaa", "Fix <login>", model.TypeBug)
+	first.Labels = []string{"auth", "Front End"}
+	desc := "Steps:\n\n1. Open the page\n2. **Crash**"
+	first.Description = &desc
+	first.Notes = []model.Note{{ID: "aaa-n01", CreatedAt: time.Now().UTC(), Content: "Seen on `staging`"}}
+
+	second := model.NewTask("bbb", "Write docs", model.TypeTask)
+	second.SetStatus(model.StatusDone)
+	second.Labels = []string{"front-end"}
+
+	return []model.Task{*first, *second}
+}
+
+func TestRender(t *testing.T) {
+	files, err := Render(sampleTasks(), Options{Title: "My Project"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	for _, name := range []string{
+		"index.html", "tasks/aaa.html", "tasks/bbb.html",
+		"labels/auth.html", "labels/front-end.html", "labels/front-end-2.html",
+		"assets/style.css", "assets/app.js", "tasks.json",
+	} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Render() is missing %s", name)
+		}
+	}
+
+	index := string(files["index.html"])
+	for _, want := range []string{
+		"<title>My Project</title>",
+		`<div class="column" data-status="todo">`,
+		`href="tasks/aaa.html"`,
+		"Fix &lt;login&gt;",
+		`<script type="application/json" id="task-data">`,
+		`href="labels/front-end-2.html"`,
+	} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.html should contain %q", want)
+		}
+	}
+	if strings.Contains(index, "Fix <login>") {
+		t.Error("index.html should escape task titles")
+	}
+
+	// The embedded JSON must parse and hold every task
+	start := strings.Index(index, `id="task-data">`) + len(`id="task-data">`)
+	end := strings.Index(index[start:], "</script>")
+	var embedded []map[string]interface{}
+	if err := json.Unmarshal([]byte(index[start:start+end]), &embedded); err != nil {
+		t.Fatalf("embedded task data is not valid JSON: %v\n%s", err, index[start:start+end])
+	}
+	if len(embedded) != 2 || embedded[0]["title"] != "Fix <login>" {
+		t.Errorf("embedded task data = %v", embedded)
+	}
+
+	task := string(files["tasks/aaa.html"])
+	for _, want := range []string{
+		`href="../assets/style.css"`,
+		"<ol>\n<li>Open the page</li>\n<li><strong>Crash</strong></li>\n</ol>",
+		"Seen on <code>staging</code>",
+		`href="../labels/front-end.html"`,
+	} {
+		if !strings.Contains(task, want) {
+			t.Errorf("tasks/aaa.html should contain %q, got:\n%s", want, task)
+		}
+	}
+
+	label := string(files["labels/front-end-2.html"])
+	if !strings.Contains(label, "Write docs") || strings.Contains(label, "Fix &lt;login&gt;") {
+		t.Errorf("label page should only list its tasks, got:\n%s", label)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	tasks := sampleTasks()
+
+	if _, err := Generate(dir, tasks, Options{}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tasks", "bbb.html")); err != nil {
+		t.Fatalf("Generate() should write task pages: %v", err)
+	}
+
+	// Regenerating without a task removes its stale pages but keeps other files
+	os.WriteFile(filepath.Join(dir, "CNAME"), []byte("tasks.example.com"), 0644)
+	if _, err := Generate(dir, tasks[:1], Options{}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tasks", "bbb.html")); !os.IsNotExist(err) {
+		t.Error("Generate() should remove pages of tasks that no longer exist")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "labels", "front-end-2.html")); !os.IsNotExist(err) {
+		t.Error("Generate() should remove pages of labels that no longer exist")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "CNAME")); err != nil {
+		t.Error("Generate() should keep files it did not create")
+	}
+}
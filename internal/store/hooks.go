@@ -0,0 +1,108 @@
+package store
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/jackreid/task/internal/model"
+)
+
+// Event names the kind of change a mutation makes to a task
+type Event string
+
+const (
+	EventCreate Event = "create"
+	EventUpdate Event = "update"
+	EventDelete Event = "delete"
+	// EventArchive and EventRestore move a task out of and back into the
+	// live tasks, keeping it in the archive in between
+	EventArchive Event = "archive"
+	EventRestore Event = "restore"
+)
+
+// Mutation describes a change to a single live task. Before is nil for a
+// create or restore and After is nil for a delete or archive
+type Mutation struct {
+	Event      Event
+	Collection string
+	Before     *model.Task
+	After      *model.Task
+}
+
+// Task returns the task the mutation is about: After, or Before for a delete
+// or archive
+func (m Mutation) Task() *model.Task {
+	if m.After != nil {
+		return m.After
+	}
+	return m.Before
+}
+
+// Hook observes changes made through the store
+type Hook interface {
+	// BeforeMutation is called before anything is written. Returning an
+	// error aborts the whole operation, including any other tasks it changes
+	BeforeMutation(m Mutation) error
+	// AfterMutation is called once the change has been saved
+	AfterMutation(m Mutation)
+}
+
+// AddHook registers a hook to run around every mutation of live tasks
+func (s *Store) AddHook(h Hook) {
+	s.hooks = append(s.hooks, h)
+}
+
+// commit saves a set of mutations. Every hook must accept every mutation
+// before save is called; hooks are told about the mutations once it succeeds
+func (s *Store) commit(mutations []Mutation, save func() error) error {
+	if err := s.beforeMutations(mutations); err != nil {
+		return err
+	}
+	if err := save(); err != nil {
+		return err
+	}
+	s.afterMutations(mutations)
+	return nil
+}
+
+// beforeMutations stamps mutations with the store's collection and asks
+// every hook to accept them
+func (s *Store) beforeMutations(mutations []Mutation) error {
+	for i := range mutations {
+		mutations[i].Collection = s.collection
+	}
+	for _, h := range s.hooks {
+		for _, m := range mutations {
+			if err := h.BeforeMutation(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterMutations tells every hook about saved mutations
+func (s *Store) afterMutations(mutations []Mutation) {
+	for _, h := range s.hooks {
+		for _, m := range mutations {
+			h.AfterMutation(m)
+		}
+	}
+}
+
+// snapshot returns a pointer to a deep copy of a task, so hooks never see
+// later changes made to the original and can't change it themselves
+func snapshot(t model.Task) *model.Task {
+	if t.Description != nil {
+		description := *t.Description
+		t.Description = &description
+	}
+	if t.Source != nil {
+		source := *t.Source
+		t.Source = &source
+	}
+	t.Labels = slices.Clone(t.Labels)
+	t.Notes = slices.Clone(t.Notes)
+	t.Refs = maps.Clone(t.Refs)
+	return &t
+}
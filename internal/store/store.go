@@ -8,9 +8,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/jackreid/task/internal/id"
 	"github.com/jackreid/task/internal/model"
 )
 
@@ -19,11 +22,25 @@ const (
 	TaskDir = ".task"
 	// TaskFile is the filename for task storage within TaskDir
 	TaskFile = "task.json"
+	// ArchiveFile is the filename for archived tasks within TaskDir
+	ArchiveFile = "archive.jsonl"
+	// DefaultCollection is the name of the collection stored in TaskFile
+	DefaultCollection = "default"
+
+	// collectionExt is the file extension for named collections
+	collectionExt = ".jsonl"
+	// archiveSuffix is appended to a named collection's file for its archive
+	archiveSuffix = ".archive.jsonl"
 )
 
+// collectionNamePattern matches valid collection names
+var collectionNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
 // Store handles persistence of tasks to the filesystem
 type Store struct {
-	dir string
+	dir        string
+	collection string
+	hooks      []Hook
 }
 
 // New creates a new Store with the given base directory
@@ -32,7 +49,41 @@ func New(dir string) *Store {
 	if dir == "" {
 		dir = "."
 	}
-	return &Store{dir: dir}
+	return &Store{dir: dir, collection: DefaultCollection}
+}
+
+// NewCollection creates a new Store for a named collection in the given base directory
+// An empty name selects the default collection
+func NewCollection(dir, name string) *Store {
+	s := New(dir)
+	if name != "" {
+		s.collection = name
+	}
+	return s
+}
+
+// ValidateCollectionName checks that name can be used as a collection file name
+func ValidateCollectionName(name string) error {
+	if name == DefaultCollection {
+		return nil
+	}
+	if !collectionNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid collection name: %s (use lowercase letters, digits, - and _)", name)
+	}
+	if name == strings.TrimSuffix(ArchiveFile, collectionExt) || strings.HasSuffix(name, ".archive") {
+		return fmt.Errorf("invalid collection name: %s (reserved)", name)
+	}
+	return nil
+}
+
+// Collection returns the name of the collection this store reads and writes
+func (s *Store) Collection() string {
+	return s.collection
+}
+
+// Dir returns the full path to the .task directory
+func (s *Store) Dir() string {
+	return s.taskDir()
 }
 
 // taskDir returns the full path to the .task directory
@@ -40,52 +91,152 @@ func (s *Store) taskDir() string {
 	return filepath.Join(s.dir, TaskDir)
 }
 
-// taskFile returns the full path to the task.json file
+// taskFile returns the full path to the collection's task file
+// The default collection uses task.json, named collections use <name>.jsonl
 func (s *Store) taskFile() string {
-	return filepath.Join(s.taskDir(), TaskFile)
+	if s.collection == DefaultCollection {
+		return filepath.Join(s.taskDir(), TaskFile)
+	}
+	return filepath.Join(s.taskDir(), s.collection+collectionExt)
+}
+
+// archiveFile returns the full path to the collection's archive file
+func (s *Store) archiveFile() string {
+	if s.collection == DefaultCollection {
+		return filepath.Join(s.taskDir(), ArchiveFile)
+	}
+	return filepath.Join(s.taskDir(), s.collection+archiveSuffix)
 }
 
-// Init creates the .task directory and empty task file
+// Init creates the .task directory (if needed) and an empty task file for the collection
 func (s *Store) Init() error {
+	if err := ValidateCollectionName(s.collection); err != nil {
+		return err
+	}
+
 	taskDir := s.taskDir()
 
 	// Check if already initialized
-	if _, err := os.Stat(taskDir); err == nil {
-		return fmt.Errorf("task directory already exists: %s", taskDir)
+	if s.IsInitialized() {
+		if s.collection == DefaultCollection {
+			return fmt.Errorf("task directory already exists: %s", taskDir)
+		}
+		return fmt.Errorf("collection already exists: %s", s.collection)
 	}
 
+	_, statErr := os.Stat(taskDir)
+	created := os.IsNotExist(statErr)
+
 	// Create the .task directory
 	if err := os.MkdirAll(taskDir, 0755); err != nil {
 		return fmt.Errorf("creating task directory: %w", err)
 	}
 
-	// Create empty task.json with empty array
+	// Create empty task file
 	if err := s.Save([]model.Task{}); err != nil {
-		// Clean up the directory if we fail to create the file
-		os.RemoveAll(taskDir)
+		// Clean up the directory if we created it and fail to create the file
+		if created {
+			os.RemoveAll(taskDir)
+		}
 		return fmt.Errorf("creating task file: %w", err)
 	}
 
 	return nil
 }
 
+// Collections returns the names of all collections in the .task directory
+// The default collection is listed first, the rest alphabetically
+func (s *Store) Collections() ([]string, error) {
+	entries, err := os.ReadDir(s.taskDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("task not initialized, run 'task init' first")
+		}
+		return nil, fmt.Errorf("reading task directory: %w", err)
+	}
+
+	var names []string
+	hasDefault := false
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		if name == TaskFile {
+			hasDefault = true
+			continue
+		}
+		if name == ArchiveFile || strings.HasSuffix(name, archiveSuffix) || !strings.HasSuffix(name, collectionExt) {
+			continue
+		}
+		collection := strings.TrimSuffix(name, collectionExt)
+		if ValidateCollectionName(collection) != nil {
+			continue
+		}
+		names = append(names, collection)
+	}
+	sort.Strings(names)
+
+	if hasDefault {
+		names = append([]string{DefaultCollection}, names...)
+	}
+	return names, nil
+}
+
 // IsInitialized checks if the task directory has been initialized
 func (s *Store) IsInitialized() bool {
 	_, err := os.Stat(s.taskFile())
 	return err == nil
 }
 
+// ModTime returns the last modification time of the collection's task file
+func (s *Store) ModTime() (time.Time, error) {
+	info, err := os.Stat(s.taskFile())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
 // Load reads and returns all tasks from the store
 // Supports both JSONL format (one task per line) and legacy JSON array format
 func (s *Store) Load() ([]model.Task, error) {
 	data, err := os.ReadFile(s.taskFile())
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, errors.New("task not initialized, run 'task init' first")
+			return nil, s.notInitialized()
 		}
 		return nil, fmt.Errorf("reading task file: %w", err)
 	}
+	return parseTasks(data)
+}
+
+// notInitialized returns the error for a collection whose task file is missing
+func (s *Store) notInitialized() error {
+	if s.collection != DefaultCollection {
+		return fmt.Errorf("collection not found: %s, run 'task init --collection %s' first", s.collection, s.collection)
+	}
+	return errors.New("task not initialized, run 'task init' first")
+}
+
+// LoadArchive reads and returns all archived tasks
+// A missing archive file is treated as an empty archive
+func (s *Store) LoadArchive() ([]model.Task, error) {
+	if !s.IsInitialized() {
+		return nil, s.notInitialized()
+	}
+	data, err := os.ReadFile(s.archiveFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []model.Task{}, nil
+		}
+		return nil, fmt.Errorf("reading archive file: %w", err)
+	}
+	return parseTasks(data)
+}
 
+// parseTasks decodes task file contents in JSONL or legacy JSON array format
+func parseTasks(data []byte) ([]model.Task, error) {
 	// Empty file
 	if len(bytes.TrimSpace(data)) == 0 {
 		return []model.Task{}, nil
@@ -140,6 +291,22 @@ func (s *Store) Load() ([]model.Task, error) {
 
 // Save writes all tasks to the store in JSONL format (one task per line)
 func (s *Store) Save(tasks []model.Task) error {
+	if err := writeTasks(s.taskFile(), tasks); err != nil {
+		return fmt.Errorf("writing task file: %w", err)
+	}
+	return nil
+}
+
+// SaveArchive writes all archived tasks in JSONL format
+func (s *Store) SaveArchive(tasks []model.Task) error {
+	if err := writeTasks(s.archiveFile(), tasks); err != nil {
+		return fmt.Errorf("writing archive file: %w", err)
+	}
+	return nil
+}
+
+// writeTasks encodes tasks as JSONL and writes them to path
+func writeTasks(path string, tasks []model.Task) error {
 	var buf bytes.Buffer
 
 	for _, task := range tasks {
@@ -151,11 +318,7 @@ func (s *Store) Save(tasks []model.Task) error {
 		buf.WriteByte('\n')
 	}
 
-	if err := os.WriteFile(s.taskFile(), buf.Bytes(), 0644); err != nil {
-		return fmt.Errorf("writing task file: %w", err)
-	}
-
-	return nil
+	return os.WriteFile(path, buf.Bytes(), 0644)
 }
 
 // FindByID finds a task by its ID, returns nil if not found
@@ -182,7 +345,32 @@ func (s *Store) Add(task *model.Task) error {
 	}
 
 	tasks = append(tasks, *task)
-	return s.Save(tasks)
+	mutations := []Mutation{{Event: EventCreate, After: snapshot(*task)}}
+	return s.commit(mutations, func() error { return s.Save(tasks) })
+}
+
+// AddMany adds several tasks in a single save
+// No task is added if any of their IDs is already in use
+func (s *Store) AddMany(added []model.Task) error {
+	tasks, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		existing[t.ID] = true
+	}
+	mutations := make([]Mutation, 0, len(added))
+	for _, t := range added {
+		if existing[t.ID] {
+			return fmt.Errorf("task ID already in use: %s", t.ID)
+		}
+		existing[t.ID] = true
+		mutations = append(mutations, Mutation{Event: EventCreate, After: snapshot(t)})
+	}
+
+	return s.commit(mutations, func() error { return s.Save(append(tasks, added...)) })
 }
 
 // Update updates an existing task in the store
@@ -192,33 +380,67 @@ func (s *Store) Update(task *model.Task) error {
 		return err
 	}
 
-	found := false
+	var mutations []Mutation
 	for i := range tasks {
 		if tasks[i].ID == task.ID {
+			mutations = append(mutations, Mutation{Event: EventUpdate, Before: snapshot(tasks[i]), After: snapshot(*task)})
 			tasks[i] = *task
-			found = true
 			break
 		}
 	}
 
-	if !found {
+	if mutations == nil {
 		return fmt.Errorf("task not found: %s", task.ID)
 	}
 
-	return s.Save(tasks)
+	return s.commit(mutations, func() error { return s.Save(tasks) })
+}
+
+// UpdateMany replaces several existing tasks in a single load and save
+// No changes are written if any task is not found
+func (s *Store) UpdateMany(updated []model.Task) error {
+	tasks, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	index := make(map[string]int, len(tasks))
+	for i := range tasks {
+		index[tasks[i].ID] = i
+	}
+
+	mutations := make([]Mutation, 0, len(updated))
+	for _, task := range updated {
+		i, ok := index[task.ID]
+		if !ok {
+			return fmt.Errorf("task not found: %s", task.ID)
+		}
+		mutations = append(mutations, Mutation{Event: EventUpdate, Before: snapshot(tasks[i]), After: snapshot(task)})
+		tasks[i] = task
+	}
+
+	return s.commit(mutations, func() error { return s.Save(tasks) })
 }
 
 // GetExistingIDs returns a map of all existing task IDs
+// Archived task IDs are included so that restoring them never collides
 func (s *Store) GetExistingIDs() (map[string]bool, error) {
 	tasks, err := s.Load()
 	if err != nil {
 		return nil, err
 	}
+	archived, err := s.LoadArchive()
+	if err != nil {
+		return nil, err
+	}
 
 	ids := make(map[string]bool)
 	for _, t := range tasks {
 		ids[t.ID] = true
 	}
+	for _, t := range archived {
+		ids[t.ID] = true
+	}
 	return ids, nil
 }
 
@@ -236,21 +458,59 @@ func (s *Store) ListSorted() ([]model.Task, error) {
 	return tasks, nil
 }
 
+// Scope selects which task sets a listing draws from
+type Scope int
+
+const (
+	// ScopeLive includes only tasks in the live task file
+	ScopeLive Scope = iota
+	// ScopeArchived includes only archived tasks
+	ScopeArchived
+	// ScopeAll includes both live and archived tasks
+	ScopeAll
+)
+
 // Filter represents filtering options for listing tasks
 type Filter struct {
 	Status *model.Status
 	Type   *model.TaskType
 	Label  *string
+	Query  *string
+	Scope  Scope
+}
+
+// loadScope returns the tasks in the given scope
+func (s *Store) loadScope(scope Scope) ([]model.Task, error) {
+	switch scope {
+	case ScopeArchived:
+		return s.LoadArchive()
+	case ScopeAll:
+		tasks, err := s.Load()
+		if err != nil {
+			return nil, err
+		}
+		archived, err := s.LoadArchive()
+		if err != nil {
+			return nil, err
+		}
+		return append(tasks, archived...), nil
+	default:
+		return s.Load()
+	}
 }
 
 // ListFiltered returns tasks matching the given filter, sorted by UpdatedAt descending
 func (s *Store) ListFiltered(filter Filter) ([]model.Task, error) {
-	tasks, err := s.ListSorted()
+	tasks, err := s.loadScope(filter.Scope)
 	if err != nil {
 		return nil, err
 	}
 
-	if filter.Status == nil && filter.Type == nil && filter.Label == nil {
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].UpdatedAt.After(tasks[j].UpdatedAt)
+	})
+
+	if filter.Status == nil && filter.Type == nil && filter.Label == nil && filter.Query == nil {
 		return tasks, nil
 	}
 
@@ -265,6 +525,9 @@ func (s *Store) ListFiltered(filter Filter) ([]model.Task, error) {
 		if filter.Label != nil && !t.HasLabel(*filter.Label) {
 			continue
 		}
+		if filter.Query != nil && !t.MatchesQuery(*filter.Query) {
+			continue
+		}
 		result = append(result, t)
 	}
 
@@ -278,21 +541,95 @@ func (s *Store) Delete(id string) error {
 		return err
 	}
 
-	found := false
-	newTasks := make([]model.Task, 0, len(tasks)-1)
+	var mutations []Mutation
+	newTasks := make([]model.Task, 0, len(tasks))
 	for i := range tasks {
 		if tasks[i].ID == id {
-			found = true
+			mutations = append(mutations, Mutation{Event: EventDelete, Before: snapshot(tasks[i])})
 			continue
 		}
 		newTasks = append(newTasks, tasks[i])
 	}
 
-	if !found {
+	if mutations == nil {
 		return fmt.Errorf("task not found: %s", id)
 	}
 
-	return s.Save(newTasks)
+	return s.commit(mutations, func() error { return s.Save(newTasks) })
+}
+
+// DeleteMany removes several tasks from the store in a single load and save
+// No changes are written if any task is not found
+func (s *Store) DeleteMany(ids []string) error {
+	tasks, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+
+	var mutations []Mutation
+	newTasks := make([]model.Task, 0, len(tasks))
+	for i := range tasks {
+		if remove[tasks[i].ID] {
+			delete(remove, tasks[i].ID)
+			mutations = append(mutations, Mutation{Event: EventDelete, Before: snapshot(tasks[i])})
+			continue
+		}
+		newTasks = append(newTasks, tasks[i])
+	}
+
+	for _, id := range ids {
+		if remove[id] {
+			return fmt.Errorf("task not found: %s", id)
+		}
+	}
+
+	return s.commit(mutations, func() error { return s.Save(newTasks) })
+}
+
+// ReplaceLabels replaces any of the from labels with the to label on every
+// live and archived task, or removes them when to is empty. Returns the
+// number of tasks changed
+func (s *Store) ReplaceLabels(from []string, to string) (int, error) {
+	tasks, err := s.Load()
+	if err != nil {
+		return 0, err
+	}
+	archived, err := s.LoadArchive()
+	if err != nil {
+		return 0, err
+	}
+
+	var mutations []Mutation
+	for i := range tasks {
+		before := snapshot(tasks[i])
+		if tasks[i].ReplaceLabels(from, to) {
+			mutations = append(mutations, Mutation{Event: EventUpdate, Before: before, After: snapshot(tasks[i])})
+		}
+	}
+	changedLive := len(mutations)
+	changedArchived := 0
+	for i := range archived {
+		if archived[i].ReplaceLabels(from, to) {
+			changedArchived++
+		}
+	}
+
+	if changedLive > 0 {
+		if err := s.commit(mutations, func() error { return s.Save(tasks) }); err != nil {
+			return 0, err
+		}
+	}
+	if changedArchived > 0 {
+		if err := s.SaveArchive(archived); err != nil {
+			return changedLive, err
+		}
+	}
+	return changedLive + changedArchived, nil
 }
 
 // Clean removes all closed tasks from the store
@@ -304,19 +641,193 @@ func (s *Store) Clean() (int, error) {
 		return 0, err
 	}
 
-	deleted := 0
+	var mutations []Mutation
 	newTasks := make([]model.Task, 0, len(tasks))
 	for i := range tasks {
-		if tasks[i].Status == model.StatusDone || tasks[i].Status == model.StatusAbandon {
-			deleted++
+		if tasks[i].Status.IsClosed() {
+			mutations = append(mutations, Mutation{Event: EventDelete, Before: snapshot(tasks[i])})
 			continue
 		}
 		newTasks = append(newTasks, tasks[i])
 	}
 
-	if deleted == 0 {
+	if len(mutations) == 0 {
+		return 0, nil
+	}
+
+	if err := s.commit(mutations, func() error { return s.Save(newTasks) }); err != nil {
+		return 0, err
+	}
+	return len(mutations), nil
+}
+
+// FindArchivedByID finds an archived task by its ID, returns nil if not found
+func (s *Store) FindArchivedByID(id string) (*model.Task, error) {
+	archived, err := s.LoadArchive()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range archived {
+		if archived[i].ID == id {
+			return &archived[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Archive moves closed tasks from the live task file into the archive
+// Only tasks last updated before the cutoff are moved; a zero cutoff archives
+// every closed task. Returns the number of tasks archived
+func (s *Store) Archive(before time.Time) (int, error) {
+	tasks, err := s.Load()
+	if err != nil {
+		return 0, err
+	}
+	archived, err := s.LoadArchive()
+	if err != nil {
+		return 0, err
+	}
+
+	var mutations []Mutation
+	remaining := make([]model.Task, 0, len(tasks))
+	for i := range tasks {
+		if tasks[i].Status.IsClosed() && (before.IsZero() || tasks[i].UpdatedAt.Before(before)) {
+			archived = append(archived, tasks[i])
+			mutations = append(mutations, Mutation{Event: EventArchive, Before: snapshot(tasks[i])})
+			continue
+		}
+		remaining = append(remaining, tasks[i])
+	}
+
+	if mutations == nil {
 		return 0, nil
 	}
 
-	return deleted, s.Save(newTasks)
+	err = s.commit(mutations, func() error {
+		// Write the archive first so a failure never loses tasks
+		if err := s.SaveArchive(archived); err != nil {
+			return err
+		}
+		return s.Save(remaining)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(mutations), nil
+}
+
+// Restore moves an archived task back into the live task file
+func (s *Store) Restore(id string) (*model.Task, error) {
+	archived, err := s.LoadArchive()
+	if err != nil {
+		return nil, err
+	}
+
+	var restored *model.Task
+	remaining := make([]model.Task, 0, len(archived))
+	for i := range archived {
+		if archived[i].ID == id && restored == nil {
+			task := archived[i]
+			restored = &task
+			continue
+		}
+		remaining = append(remaining, archived[i])
+	}
+
+	if restored == nil {
+		return nil, fmt.Errorf("archived task not found: %s", id)
+	}
+
+	tasks, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		if tasks[i].ID == id {
+			return nil, fmt.Errorf("task already exists: %s", id)
+		}
+	}
+
+	mutations := []Mutation{{Event: EventRestore, After: snapshot(*restored)}}
+	err = s.commit(mutations, func() error {
+		// Write the live file first so a failure never loses tasks
+		if err := s.Save(append(tasks, *restored)); err != nil {
+			return err
+		}
+		return s.SaveArchive(remaining)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// Move transfers a task from this store's collection into another collection
+// Notes and timestamps are preserved; the task is given a fresh ID only when
+// its current ID is already taken in the target collection
+func (s *Store) Move(taskID string, to *Store) (*model.Task, error) {
+	if s.collection == to.collection && s.dir == to.dir {
+		return nil, fmt.Errorf("task %s is already in collection %s", taskID, to.collection)
+	}
+
+	tasks, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	var task *model.Task
+	remaining := make([]model.Task, 0, len(tasks))
+	for i := range tasks {
+		if tasks[i].ID == taskID {
+			task = snapshot(tasks[i])
+			continue
+		}
+		remaining = append(remaining, tasks[i])
+	}
+	if task == nil {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	removed := []Mutation{{Event: EventDelete, Before: snapshot(*task)}}
+
+	targetTasks, err := to.Load()
+	if err != nil {
+		return nil, err
+	}
+	existingIDs, err := to.GetExistingIDs()
+	if err != nil {
+		return nil, err
+	}
+	if existingIDs[task.ID] {
+		newID, err := id.GenerateUnique(existingIDs)
+		if err != nil {
+			return nil, fmt.Errorf("generating ID: %w", err)
+		}
+		task.ID = newID
+	}
+	added := []Mutation{{Event: EventCreate, After: snapshot(*task)}}
+
+	// Both collections' hooks must accept the move before either is written
+	if err := to.beforeMutations(added); err != nil {
+		return nil, err
+	}
+	if err := s.beforeMutations(removed); err != nil {
+		return nil, err
+	}
+
+	// Add to the target first so a failure never loses the task, and take
+	// it out again if it can't be removed from the source
+	if err := to.Save(append(append([]model.Task{}, targetTasks...), *task)); err != nil {
+		return nil, err
+	}
+	if err := s.Save(remaining); err != nil {
+		if rollbackErr := to.Save(targetTasks); rollbackErr != nil {
+			return nil, fmt.Errorf("%w (and restoring collection %s: %v)", err, to.collection, rollbackErr)
+		}
+		return nil, err
+	}
+
+	to.afterMutations(added)
+	s.afterMutations(removed)
+	return task, nil
 }
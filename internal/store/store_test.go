@@ -3,8 +3,10 @@ package store
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -755,3 +757,689 @@ func TestStoreLoadJSONLFormat(t *testing.T) {
 			tasks[1].ID, tasks[1].Title, "def", "JSONL Task 2")
 	}
 }
+
+func TestStoreArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+	s.Init()
+
+	task1 := model.NewTask("aaa", "Todo Task", model.TypeTask)
+
+	task2 := model.NewTask("bbb", "Done Task", model.TypeTask)
+	task2.SetStatus(model.StatusDone)
+
+	task3 := model.NewTask("ccc", "Abandon Task", model.TypeTask)
+	task3.SetStatus(model.StatusAbandon)
+
+	s.Add(task1)
+	s.Add(task2)
+	s.Add(task3)
+
+	archived, err := s.Archive(time.Time{})
+	if err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	if archived != 2 {
+		t.Errorf("Archive() archived %d tasks, want 2", archived)
+	}
+
+	tasks, _ := s.Load()
+	if len(tasks) != 1 || tasks[0].ID != "aaa" {
+		t.Errorf("After Archive(), Load() = %v, want only aaa", tasks)
+	}
+
+	archive, err := s.LoadArchive()
+	if err != nil {
+		t.Fatalf("LoadArchive() error = %v", err)
+	}
+	if len(archive) != 2 {
+		t.Errorf("LoadArchive() returned %d tasks, want 2", len(archive))
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, TaskDir, ArchiveFile)); err != nil {
+		t.Errorf("Archive() did not create %s: %v", ArchiveFile, err)
+	}
+}
+
+func TestStoreLoadArchiveInvalidTaskFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+	s.Init()
+	done := model.NewTask("aaa", "Done Task", model.TypeTask)
+	done.SetStatus(model.StatusDone)
+	s.Add(done)
+	s.Archive(time.Time{})
+
+	// The archive doesn't depend on the live task file parsing
+	os.WriteFile(filepath.Join(tmpDir, TaskDir, TaskFile), []byte("{not json"), 0644)
+	archive, err := s.LoadArchive()
+	if err != nil || len(archive) != 1 {
+		t.Errorf("LoadArchive() = %v, %v, want the archived task", archive, err)
+	}
+
+	if _, err := NewCollection(tmpDir, "api").LoadArchive(); err == nil || !strings.Contains(err.Error(), "collection not found: api") {
+		t.Errorf("LoadArchive() of a missing collection error = %v", err)
+	}
+}
+
+func TestStoreArchiveOlderThan(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+	s.Init()
+
+	old := model.NewTask("aaa", "Old Done", model.TypeTask)
+	old.SetStatus(model.StatusDone)
+	old.UpdatedAt = time.Now().UTC().AddDate(0, 0, -10)
+
+	recent := model.NewTask("bbb", "Recent Done", model.TypeTask)
+	recent.SetStatus(model.StatusDone)
+
+	s.Save([]model.Task{*old, *recent})
+
+	archived, err := s.Archive(time.Now().UTC().AddDate(0, 0, -5))
+	if err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	if archived != 1 {
+		t.Errorf("Archive() archived %d tasks, want 1", archived)
+	}
+
+	found, _ := s.FindArchivedByID("aaa")
+	if found == nil {
+		t.Error("FindArchivedByID(aaa) = nil, want old task archived")
+	}
+	live, _ := s.FindByID("bbb")
+	if live == nil {
+		t.Error("FindByID(bbb) = nil, recent task should stay live")
+	}
+}
+
+func TestStoreLoadArchiveMissing(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+
+	if _, err := s.LoadArchive(); err == nil {
+		t.Error("LoadArchive() should return error when not initialized")
+	}
+
+	s.Init()
+	archive, err := s.LoadArchive()
+	if err != nil {
+		t.Fatalf("LoadArchive() error = %v", err)
+	}
+	if len(archive) != 0 {
+		t.Errorf("LoadArchive() returned %d tasks, want 0", len(archive))
+	}
+}
+
+func TestStoreRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+	s.Init()
+
+	task := model.NewTask("aaa", "Done Task", model.TypeTask)
+	task.SetStatus(model.StatusDone)
+	s.Add(task)
+	s.Archive(time.Time{})
+
+	restored, err := s.Restore("aaa")
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if restored.ID != "aaa" {
+		t.Errorf("Restore() returned %s, want aaa", restored.ID)
+	}
+
+	if found, _ := s.FindByID("aaa"); found == nil {
+		t.Error("Restore() did not add task back to the live file")
+	}
+	if found, _ := s.FindArchivedByID("aaa"); found != nil {
+		t.Error("Restore() did not remove task from the archive")
+	}
+
+	if _, err := s.Restore("aaa"); err == nil {
+		t.Error("Restore() of a non-archived task should return error")
+	}
+}
+
+func TestStoreListFilteredScope(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+	s.Init()
+
+	live := model.NewTask("aaa", "Live Task", model.TypeTask)
+	closed := model.NewTask("bbb", "Closed Task", model.TypeTask)
+	closed.SetStatus(model.StatusDone)
+	s.Add(live)
+	s.Add(closed)
+	s.Archive(time.Time{})
+
+	tests := []struct {
+		scope Scope
+		want  int
+	}{
+		{ScopeLive, 1},
+		{ScopeArchived, 1},
+		{ScopeAll, 2},
+	}
+
+	for _, tt := range tests {
+		tasks, err := s.ListFiltered(Filter{Scope: tt.scope})
+		if err != nil {
+			t.Fatalf("ListFiltered(scope %d) error = %v", tt.scope, err)
+		}
+		if len(tasks) != tt.want {
+			t.Errorf("ListFiltered(scope %d) returned %d tasks, want %d", tt.scope, len(tasks), tt.want)
+		}
+	}
+
+	ids, _ := s.GetExistingIDs()
+	if !ids["bbb"] {
+		t.Error("GetExistingIDs() should include archived task IDs")
+	}
+}
+
+func TestStoreCollectionFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := NewCollection(tmpDir, "api")
+
+	if s.Collection() != "api" {
+		t.Errorf("Collection() = %q, want %q", s.Collection(), "api")
+	}
+
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, TaskDir, "api.jsonl")); err != nil {
+		t.Errorf("Init() did not create api.jsonl: %v", err)
+	}
+
+	// The default collection can still be initialized alongside
+	if err := New(tmpDir).Init(); err != nil {
+		t.Fatalf("default Init() error = %v", err)
+	}
+	if err := s.Init(); err == nil {
+		t.Error("second Init() of a collection should return error")
+	}
+
+	s.Add(model.NewTask("aaa", "API Task", model.TypeTask))
+	if tasks, _ := New(tmpDir).Load(); len(tasks) != 0 {
+		t.Errorf("default collection has %d tasks, want 0", len(tasks))
+	}
+
+	if NewCollection(tmpDir, "").Collection() != DefaultCollection {
+		t.Error("NewCollection with empty name should use the default collection")
+	}
+}
+
+func TestStoreCollections(t *testing.T) {
+	tmpDir := t.TempDir()
+	New(tmpDir).Init()
+	NewCollection(tmpDir, "web").Init()
+	NewCollection(tmpDir, "api").Init()
+
+	done := model.NewTask("aaa", "Done", model.TypeTask)
+	done.SetStatus(model.StatusDone)
+	api := NewCollection(tmpDir, "api")
+	api.Add(done)
+	api.Archive(time.Time{})
+
+	names, err := New(tmpDir).Collections()
+	if err != nil {
+		t.Fatalf("Collections() error = %v", err)
+	}
+
+	want := []string{DefaultCollection, "api", "web"}
+	if len(names) != len(want) {
+		t.Fatalf("Collections() = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("Collections()[%d] = %q, want %q", i, names[i], want[i])
+		}
+	}
+}
+
+func TestValidateCollectionName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"api", true},
+		{"web-app", true},
+		{"svc_2", true},
+		{DefaultCollection, true},
+		{"", false},
+		{"API", false},
+		{"../etc", false},
+		{"archive", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateCollectionName(tt.name)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateCollectionName(%q) error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestStoreMove(t *testing.T) {
+	tmpDir := t.TempDir()
+	from := New(tmpDir)
+	from.Init()
+	to := NewCollection(tmpDir, "api")
+	to.Init()
+
+	task := model.NewTask("aaa", "Moving Task", model.TypeTask)
+	task.AddNote("aaa-n01", "history")
+	from.Add(task)
+
+	moved, err := from.Move("aaa", to)
+	if err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if moved.ID != "aaa" {
+		t.Errorf("Move() changed ID to %s without a collision", moved.ID)
+	}
+
+	if found, _ := from.FindByID("aaa"); found != nil {
+		t.Error("Move() did not remove task from source collection")
+	}
+	found, _ := to.FindByID("aaa")
+	if found == nil {
+		t.Fatal("Move() did not add task to target collection")
+	}
+	if len(found.Notes) != 1 || found.Notes[0].Content != "history" {
+		t.Errorf("Move() did not preserve notes: %v", found.Notes)
+	}
+}
+
+func TestStoreMoveCollision(t *testing.T) {
+	tmpDir := t.TempDir()
+	from := New(tmpDir)
+	from.Init()
+	to := NewCollection(tmpDir, "api")
+	to.Init()
+
+	from.Add(model.NewTask("aaa", "Source Task", model.TypeTask))
+	to.Add(model.NewTask("aaa", "Existing Task", model.TypeTask))
+
+	moved, err := from.Move("aaa", to)
+	if err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if moved.ID == "aaa" {
+		t.Error("Move() should regenerate the ID on collision")
+	}
+
+	tasks, _ := to.Load()
+	if len(tasks) != 2 {
+		t.Errorf("target has %d tasks, want 2", len(tasks))
+	}
+
+	if _, err := from.Move("zzz", to); err == nil {
+		t.Error("Move() of a missing task should return error")
+	}
+}
+
+func TestStoreMoveHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	from := New(tmpDir)
+	from.Init()
+	to := NewCollection(tmpDir, "api")
+	to.Init()
+	from.Add(model.NewTask("aaa", "Moving Task", model.TypeTask))
+
+	fromHook, toHook := &recordingHook{veto: "aaa"}, &recordingHook{}
+	from.AddHook(fromHook)
+	to.AddHook(toHook)
+
+	// A source hook rejecting the delete leaves the task where it was
+	if _, err := from.Move("aaa", to); err == nil {
+		t.Fatal("Move() vetoed by a hook should return error")
+	}
+	if found, _ := from.FindByID("aaa"); found == nil {
+		t.Error("vetoed Move() removed the task from the source")
+	}
+	if found, _ := to.FindByID("aaa"); found != nil {
+		t.Error("vetoed Move() added the task to the target")
+	}
+	if len(toHook.after) != 0 {
+		t.Errorf("vetoed Move() ran after hooks: %v", toHook.after)
+	}
+
+	fromHook.veto = ""
+	if _, err := from.Move("aaa", to); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if got := strings.Join(fromHook.after, ","); got != "delete aaa" {
+		t.Errorf("source after hooks = %s", got)
+	}
+	if got := strings.Join(toHook.after, ","); got != "create aaa" {
+		t.Errorf("target after hooks = %s", got)
+	}
+}
+
+func TestStoreArchiveHooks(t *testing.T) {
+	s := New(t.TempDir())
+	s.Init()
+	done := model.NewTask("aaa", "Done Task", model.TypeTask)
+	done.SetStatus(model.StatusDone)
+	s.Add(done)
+	s.Add(model.NewTask("bbb", "Todo Task", model.TypeTask))
+
+	hook := &recordingHook{veto: "aaa"}
+	s.AddHook(hook)
+
+	// A hook rejecting the delete leaves the task in the live file
+	if _, err := s.Archive(time.Time{}); err == nil {
+		t.Fatal("Archive() vetoed by a hook should return error")
+	}
+	if found, _ := s.FindByID("aaa"); found == nil {
+		t.Error("vetoed Archive() removed the task")
+	}
+	if archive, _ := s.LoadArchive(); len(archive) != 0 {
+		t.Errorf("vetoed Archive() wrote the archive: %v", archive)
+	}
+
+	hook.veto = ""
+	if n, err := s.Archive(time.Time{}); err != nil || n != 1 {
+		t.Fatalf("Archive() = %d, %v, want 1", n, err)
+	}
+	if got := strings.Join(hook.after, ","); got != "archive aaa" {
+		t.Errorf("Archive() after hooks = %s", got)
+	}
+
+	hook.after = nil
+	if _, err := s.Restore("aaa"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := strings.Join(hook.after, ","); got != "restore aaa" {
+		t.Errorf("Restore() after hooks = %s", got)
+	}
+
+	// A hook rejecting the restore leaves the task in the archive
+	s.Archive(time.Time{})
+	hook.veto = "aaa"
+	if _, err := s.Restore("aaa"); err == nil {
+		t.Fatal("Restore() vetoed by a hook should return error")
+	}
+	if archived, _ := s.FindArchivedByID("aaa"); archived == nil {
+		t.Error("vetoed Restore() removed the task from the archive")
+	}
+	if found, _ := s.FindByID("aaa"); found != nil {
+		t.Error("vetoed Restore() added the task")
+	}
+}
+
+func TestStoreListFilteredQuery(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+	s.Init()
+
+	s.Add(model.NewTask("aaa", "Login page", model.TypeTask))
+	s.Add(model.NewTask("bbb", "Logout button", model.TypeTask))
+
+	query := "login"
+	tasks, err := s.ListFiltered(Filter{Query: &query})
+	if err != nil {
+		t.Fatalf("ListFiltered() error = %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != "aaa" {
+		t.Errorf("ListFiltered(query login) = %v, want only aaa", tasks)
+	}
+}
+
+func TestStoreUpdateMany(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+	s.Init()
+
+	task1 := model.NewTask("aaa", "First", model.TypeTask)
+	task2 := model.NewTask("bbb", "Second", model.TypeTask)
+	s.Add(task1)
+	s.Add(task2)
+
+	task1.SetStatus(model.StatusDone)
+	task2.SetStatus(model.StatusDone)
+	if err := s.UpdateMany([]model.Task{*task1, *task2}); err != nil {
+		t.Fatalf("UpdateMany() error = %v", err)
+	}
+
+	tasks, _ := s.Load()
+	for _, task := range tasks {
+		if task.Status != model.StatusDone {
+			t.Errorf("task %s status = %s, want done", task.ID, task.Status)
+		}
+	}
+
+	missing := model.NewTask("zzz", "Missing", model.TypeTask)
+	task1.SetTitle("Changed")
+	if err := s.UpdateMany([]model.Task{*task1, *missing}); err == nil {
+		t.Error("UpdateMany() with a missing task should return error")
+	}
+	found, _ := s.FindByID("aaa")
+	if found.Title != "First" {
+		t.Error("UpdateMany() should not write changes when a task is missing")
+	}
+}
+
+func TestStoreDeleteMany(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+	s.Init()
+
+	s.Add(model.NewTask("aaa", "First", model.TypeTask))
+	s.Add(model.NewTask("bbb", "Second", model.TypeTask))
+	s.Add(model.NewTask("ccc", "Third", model.TypeTask))
+
+	if err := s.DeleteMany([]string{"aaa", "zzz"}); err == nil {
+		t.Error("DeleteMany() with a missing ID should return error")
+	}
+	if tasks, _ := s.Load(); len(tasks) != 3 {
+		t.Errorf("DeleteMany() should not write changes when an ID is missing, have %d tasks", len(tasks))
+	}
+
+	if err := s.DeleteMany([]string{"aaa", "ccc"}); err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+	tasks, _ := s.Load()
+	if len(tasks) != 1 || tasks[0].ID != "bbb" {
+		t.Errorf("After DeleteMany(), Load() = %v, want only bbb", tasks)
+	}
+}
+
+func TestStoreReplaceLabels(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+	s.Init()
+
+	live := model.NewTask("aaa", "Live", model.TypeTask)
+	live.SetLabels([]string{"front-end"})
+	closed := model.NewTask("bbb", "Closed", model.TypeTask)
+	closed.SetLabels([]string{"frontend-ui"})
+	closed.SetStatus(model.StatusDone)
+	other := model.NewTask("ccc", "Other", model.TypeTask)
+	other.SetLabels([]string{"backend"})
+	s.Add(live)
+	s.Add(closed)
+	s.Add(other)
+	s.Archive(time.Time{})
+
+	changed, err := s.ReplaceLabels([]string{"front-end", "frontend-ui"}, "frontend")
+	if err != nil {
+		t.Fatalf("ReplaceLabels() error = %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("ReplaceLabels() changed %d tasks, want 2", changed)
+	}
+
+	found, _ := s.FindByID("aaa")
+	if !found.HasLabel("frontend") {
+		t.Errorf("live task labels = %v, want frontend", found.Labels)
+	}
+	archived, _ := s.FindArchivedByID("bbb")
+	if !archived.HasLabel("frontend") {
+		t.Errorf("archived task labels = %v, want frontend", archived.Labels)
+	}
+
+	// An empty target removes the labels
+	changed, err = s.ReplaceLabels([]string{"frontend"}, "")
+	if err != nil || changed != 2 {
+		t.Fatalf("ReplaceLabels() to remove = %d, %v, want 2", changed, err)
+	}
+	found, _ = s.FindByID("aaa")
+	archived, _ = s.FindArchivedByID("bbb")
+	if len(found.Labels) != 0 || len(archived.Labels) != 0 {
+		t.Errorf("labels after removing = %v, %v", found.Labels, archived.Labels)
+	}
+}
+
+func TestStoreModTime(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+
+	if _, err := s.ModTime(); err == nil {
+		t.Error("ModTime() should return error when not initialized")
+	}
+
+	s.Init()
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(tmpDir, TaskDir, TaskFile), past, past)
+
+	before, err := s.ModTime()
+	if err != nil {
+		t.Fatalf("ModTime() error = %v", err)
+	}
+
+	s.Add(model.NewTask("aaa", "Task", model.TypeTask))
+	after, _ := s.ModTime()
+	if !after.After(before) {
+		t.Errorf("ModTime() after Add = %v, want later than %v", after, before)
+	}
+}
+
+func TestStoreAddMany(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := New(tmpDir)
+	s.Init()
+
+	s.Add(model.NewTask("aaa", "First", model.TypeTask))
+
+	added := []model.Task{
+		*model.NewTask("bbb", "Second", model.TypeTask),
+		*model.NewTask("ccc", "Third", model.TypeBug),
+	}
+	if err := s.AddMany(added); err != nil {
+		t.Fatalf("AddMany() error = %v", err)
+	}
+
+	tasks, _ := s.Load()
+	if len(tasks) != 3 {
+		t.Errorf("Load() after AddMany() returned %d tasks, want 3", len(tasks))
+	}
+
+	duplicate := []model.Task{
+		*model.NewTask("ddd", "Fourth", model.TypeTask),
+		*model.NewTask("aaa", "Clash", model.TypeTask),
+	}
+	if err := s.AddMany(duplicate); err == nil {
+		t.Error("AddMany() with a used ID should return error")
+	}
+	if found, _ := s.FindByID("ddd"); found != nil {
+		t.Error("AddMany() should not add any task when an ID is in use")
+	}
+}
+
+// recordingHook records mutations and can veto them
+type recordingHook struct {
+	before []string
+	after  []string
+	veto   string
+}
+
+func describe(m Mutation) string {
+	return string(m.Event) + " " + m.Task().ID
+}
+
+func (h *recordingHook) BeforeMutation(m Mutation) error {
+	h.before = append(h.before, describe(m))
+	if m.Task().ID == h.veto {
+		return errors.New("vetoed " + h.veto)
+	}
+	return nil
+}
+
+func (h *recordingHook) AfterMutation(m Mutation) {
+	h.after = append(h.after, describe(m))
+}
+
+func TestSnapshotIsDeep(t *testing.T) {
+	task := model.NewTask("aaa", "Task", model.TypeTask)
+	task.SetDescription("original")
+	task.AddLabel("ui")
+	task.Notes = []model.Note{{ID: "aaa-1", Content: "first"}}
+	task.Refs = map[string]string{"github": "1"}
+	task.Source = &model.Source{File: "main.go", Line: 1}
+
+	copied := snapshot(*task)
+	*copied.Description = "changed"
+	copied.Labels[0] = "changed"
+	copied.Notes[0].Content = "changed"
+	copied.Refs["github"] = "changed"
+	copied.Source.Line = 2
+
+	if *task.Description != "original" || task.Labels[0] != "ui" || task.Notes[0].Content != "first" ||
+		task.Refs["github"] != "1" || task.Source.Line != 1 {
+		t.Errorf("changing a snapshot changed the original: %+v", task)
+	}
+}
+
+func TestStoreHooks(t *testing.T) {
+	s := New(t.TempDir())
+	s.Init()
+	hook := &recordingHook{}
+	s.AddHook(hook)
+
+	task := model.NewTask("aaa", "Hooked", model.TypeTask)
+	s.Add(task)
+	s.AddMany([]model.Task{*model.NewTask("bbb", "Second", model.TypeTask)})
+
+	var seen Mutation
+	s.AddHook(mutationFunc(func(m Mutation) { seen = m }))
+	task.SetStatus(model.StatusDone)
+	s.Update(task)
+	if seen.Collection != DefaultCollection || seen.Before.Status != model.StatusTodo || seen.After.Status != model.StatusDone {
+		t.Errorf("update mutation = %+v", seen)
+	}
+
+	s.Clean()
+	s.Delete("bbb")
+
+	want := "create aaa,create bbb,update aaa,delete aaa,delete bbb"
+	if got := strings.Join(hook.after, ","); got != want {
+		t.Errorf("after hooks = %s, want %s", got, want)
+	}
+
+	// A vetoed mutation aborts the whole operation
+	hook.veto = "ddd"
+	err := s.AddMany([]model.Task{
+		*model.NewTask("ccc", "Allowed", model.TypeTask),
+		*model.NewTask("ddd", "Vetoed", model.TypeTask),
+	})
+	if err == nil || err.Error() != "vetoed ddd" {
+		t.Fatalf("AddMany() error = %v, want the hook's error", err)
+	}
+	if tasks, _ := s.Load(); len(tasks) != 0 {
+		t.Errorf("vetoed AddMany() should save nothing, got %d tasks", len(tasks))
+	}
+	if last := hook.after[len(hook.after)-1]; last != "delete bbb" {
+		t.Errorf("after hooks should not run for a vetoed operation, got %s", last)
+	}
+}
+
+// mutationFunc adapts a function to a Hook that never vetoes
+type mutationFunc func(Mutation)
+
+func (f mutationFunc) BeforeMutation(Mutation) error { return nil }
+func (f mutationFunc) AfterMutation(m Mutation)      { f(m) }
@@ -0,0 +1,59 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jackreid/task/internal/store"
+)
+
+// Handler streams the store's events as server-sent events. Each event is
+// sent with its type as the SSE event name and the JSON event as its data.
+// Every client gets its own watcher, so it only sees changes made after it
+// connected
+func Handler(s *store.Store, interval time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		watcher, err := New(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		// A comment line lets the client know the stream is open
+		fmt.Fprint(w, ": watching\n\n")
+		flusher.Flush()
+
+		watcher.OnError = func(err error) {
+			data, _ := json.Marshal(map[string]string{"error": err.Error()})
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+		watcher.Run(r.Context(), interval, func(e Event) error {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return err
+			}
+			flusher.Flush()
+			return nil
+		})
+	})
+}
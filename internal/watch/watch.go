@@ -0,0 +1,256 @@
+package watch
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+// DefaultInterval is how often the task file is checked for changes
+const DefaultInterval = time.Second
+
+// settle is how long after a write the file's modification time is trusted
+// to have caught it. Timestamps are coarse on some filesystems, so a second
+// write soon after a load can leave the time unchanged
+const settle = time.Second
+
+// EventType identifies what happened to a task
+type EventType string
+
+const (
+	EventCreated       EventType = "created"
+	EventUpdated       EventType = "updated"
+	EventStatusChanged EventType = "status_changed"
+	EventNoteAdded     EventType = "note_added"
+	EventDeleted       EventType = "deleted"
+	EventArchived      EventType = "archived"
+)
+
+// Event is a change to a single task
+type Event struct {
+	Type EventType `json:"type"`
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// Fields lists the changed fields of an updated event
+	Fields []string `json:"fields,omitempty"`
+	// From and To are the old and new status of a status_changed event
+	From model.Status `json:"from,omitempty"`
+	To   model.Status `json:"to,omitempty"`
+	// Note is the added note of a note_added event
+	Note *model.Note `json:"note,omitempty"`
+	// Task is the task after the change, or as it was last seen if it was
+	// deleted or archived
+	Task model.Task `json:"task"`
+}
+
+// Diff compares two snapshots of a task set by ID and returns the events
+// that turn old into new, ordered by task ID. A task whose status changed
+// and that gained notes produces one event for each
+func Diff(old, new []model.Task, now time.Time) []Event {
+	before := make(map[string]model.Task, len(old))
+	for _, t := range old {
+		before[t.ID] = t
+	}
+	after := make(map[string]model.Task, len(new))
+	for _, t := range new {
+		after[t.ID] = t
+	}
+
+	ids := make([]string, 0, len(before)+len(after))
+	for id := range after {
+		ids = append(ids, id)
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var events []Event
+	for _, id := range ids {
+		prev, existed := before[id]
+		cur, exists := after[id]
+
+		switch {
+		case !existed:
+			events = append(events, Event{Type: EventCreated, ID: id, Time: now, Task: cur})
+		case !exists:
+			events = append(events, Event{Type: EventDeleted, ID: id, Time: now, Task: prev})
+		default:
+			events = append(events, diffTask(prev, cur, now)...)
+		}
+	}
+	return events
+}
+
+// diffTask returns the events for a task present in both snapshots
+func diffTask(prev, cur model.Task, now time.Time) []Event {
+	var events []Event
+
+	if fields := changedFields(prev, cur); len(fields) > 0 {
+		events = append(events, Event{Type: EventUpdated, ID: cur.ID, Time: now, Fields: fields, Task: cur})
+	}
+	if prev.Status != cur.Status {
+		events = append(events, Event{Type: EventStatusChanged, ID: cur.ID, Time: now, From: prev.Status, To: cur.Status, Task: cur})
+	}
+
+	seen := make(map[string]bool, len(prev.Notes))
+	for _, n := range prev.Notes {
+		seen[n.ID] = true
+	}
+	for i := range cur.Notes {
+		if !seen[cur.Notes[i].ID] {
+			note := cur.Notes[i]
+			events = append(events, Event{Type: EventNoteAdded, ID: cur.ID, Time: now, Note: &note, Task: cur})
+		}
+	}
+	return events
+}
+
+// changedFields lists the fields other than status and notes that differ
+func changedFields(prev, cur model.Task) []string {
+	var fields []string
+	if prev.Title != cur.Title {
+		fields = append(fields, "title")
+	}
+	if description(prev) != description(cur) || (prev.Description == nil) != (cur.Description == nil) {
+		fields = append(fields, "description")
+	}
+	if prev.Type != cur.Type {
+		fields = append(fields, "type")
+	}
+	if !equalStrings(prev.Labels, cur.Labels) {
+		fields = append(fields, "labels")
+	}
+	if !prev.Due.Equal(cur.Due) {
+		fields = append(fields, "due")
+	}
+	if prev.Branch != cur.Branch {
+		fields = append(fields, "branch")
+	}
+	if (prev.Source == nil) != (cur.Source == nil) || (prev.Source != nil && *prev.Source != *cur.Source) {
+		fields = append(fields, "source")
+	}
+	if !maps.Equal(prev.Refs, cur.Refs) {
+		fields = append(fields, "refs")
+	}
+	return fields
+}
+
+func description(t model.Task) string {
+	if t.Description == nil {
+		return ""
+	}
+	return *t.Description
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Watcher polls a store for changes. Polling needs no platform specific
+// file notification support and copes with editors and git replacing the
+// task file rather than writing to it
+type Watcher struct {
+	// OnError is called by Run when a poll fails, once for each different
+	// error in a row. Run keeps polling either way
+	OnError func(error)
+
+	store    *store.Store
+	tasks    []model.Task
+	modTime  time.Time
+	loadedAt time.Time
+}
+
+// New creates a watcher, taking the store's current tasks as the baseline
+// that later changes are reported against
+func New(s *store.Store) (*Watcher, error) {
+	w := &Watcher{store: s}
+	modTime, err := s.ModTime()
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	w.tasks, w.modTime, w.loadedAt = tasks, modTime, time.Now()
+	return w, nil
+}
+
+// Poll reloads the tasks if the task file changed since the last poll and
+// returns the events since then. Removed tasks that are now in the archive
+// are reported as archived rather than deleted
+func (w *Watcher) Poll() ([]Event, error) {
+	modTime, err := w.store.ModTime()
+	if err != nil {
+		return nil, err
+	}
+	if modTime.Equal(w.modTime) && w.loadedAt.Sub(modTime) > settle {
+		return nil, nil
+	}
+
+	now := time.Now()
+	tasks, err := w.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	events := Diff(w.tasks, tasks, now.UTC())
+	w.tasks, w.modTime, w.loadedAt = tasks, modTime, now
+
+	for i := range events {
+		if events[i].Type != EventDeleted {
+			continue
+		}
+		if archived, err := w.store.FindArchivedByID(events[i].ID); err == nil && archived != nil {
+			events[i].Type = EventArchived
+			events[i].Task = *archived
+		}
+	}
+	return events, nil
+}
+
+// Run polls every interval and calls fn with each event until ctx is done or
+// fn returns an error. A failed poll is retried on the next tick, since the
+// task file can be caught mid-write or briefly missing while git replaces it
+func (w *Watcher) Run(ctx context.Context, interval time.Duration, fn func(Event) error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr string
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		events, err := w.Poll()
+		if err != nil {
+			if err.Error() != lastErr && w.OnError != nil {
+				w.OnError(err)
+			}
+			lastErr = err.Error()
+			continue
+		}
+		lastErr = ""
+		for _, e := range events {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package watch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+func newTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	s := store.New(t.TempDir())
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	return s
+}
+
+// summary renders events compactly for comparison
+func summary(events []Event) []string {
+	var out []string
+	for _, e := range events {
+		s := string(e.Type) + " " + e.ID
+		switch e.Type {
+		case EventUpdated:
+			s += " " + strings.Join(e.Fields, ",")
+		case EventStatusChanged:
+			s += " " + string(e.From) + "->" + string(e.To)
+		case EventNoteAdded:
+			s += " " + e.Note.Content
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestDiff(t *testing.T) {
+	now := time.Now()
+
+	kept := *model.NewTask("aaa", "Kept", model.TypeTask)
+	changed := *model.NewTask("bbb", "Changed", model.TypeTask)
+	removed := *model.NewTask("ccc", "Removed", model.TypeTask)
+	old := []model.Task{kept, changed, removed}
+
+	changed2 := changed
+	changed2.Labels = []string{"ui"}
+	changed2.Notes = []model.Note{{ID: "bbb-1", Content: "First"}, {ID: "bbb-2", Content: "Second"}}
+	changed2.SetTitle("Renamed")
+	changed2.SetDescription("Now described")
+	changed2.SetStatus(model.StatusProgress)
+	added := *model.NewTask("ddd", "Added", model.TypeBug)
+
+	events := Diff(old, []model.Task{added, kept, changed2}, now)
+
+	got := strings.Join(summary(events), "\n")
+	want := strings.Join([]string{
+		"updated bbb title,description,labels",
+		"status_changed bbb todo->progress",
+		"note_added bbb First",
+		"note_added bbb Second",
+		"deleted ccc",
+		"created ddd",
+	}, "\n")
+	if got != want {
+		t.Errorf("Diff() =\n%s\nwant\n%s", got, want)
+	}
+
+	for _, e := range events {
+		if !e.Time.Equal(now) {
+			t.Errorf("event time = %v, want %v", e.Time, now)
+		}
+	}
+	if events[4].Task.Title != "Removed" {
+		t.Error("deleted event should carry the task as last seen")
+	}
+
+	if events := Diff(old, old, now); len(events) != 0 {
+		t.Errorf("Diff() of identical sets = %v", summary(events))
+	}
+}
+
+func TestWatcherPoll(t *testing.T) {
+	s := newTestStore(t)
+	s.Add(model.NewTask("aaa", "Existing", model.TypeTask))
+
+	w, err := New(s)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	events, err := w.Poll()
+	if err != nil || len(events) != 0 {
+		t.Fatalf("Poll() with no changes = %v, %v", summary(events), err)
+	}
+
+	task, _ := s.FindByID("aaa")
+	task.SetStatus(model.StatusDone)
+	s.Update(task)
+	s.Add(model.NewTask("bbb", "New", model.TypeTask))
+
+	events, err = w.Poll()
+	if err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if got := strings.Join(summary(events), "; "); got != "status_changed aaa todo->done; created bbb" {
+		t.Errorf("Poll() = %s", got)
+	}
+
+	// Changes are only reported once
+	if events, _ := w.Poll(); len(events) != 0 {
+		t.Errorf("second Poll() = %v", summary(events))
+	}
+
+	s.Archive(time.Now().Add(time.Hour))
+	s.Delete("bbb")
+	events, _ = w.Poll()
+	if got := strings.Join(summary(events), "; "); got != "archived aaa; deleted bbb" {
+		t.Errorf("Poll() after archive and delete = %s", got)
+	}
+}
+
+func TestWatcherRunRetries(t *testing.T) {
+	s := newTestStore(t)
+	w, err := New(s)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	errs := make(chan error, 10)
+	w.OnError = func(err error) { errs <- err }
+
+	events := make(chan Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, 10*time.Millisecond, func(e Event) error {
+			events <- e
+			return nil
+		})
+	}()
+
+	// The task file goes missing for a moment, as during a git checkout
+	file := filepath.Join(s.Dir(), store.TaskFile)
+	os.Rename(file, file+".tmp")
+	select {
+	case <-errs:
+	case err := <-done:
+		t.Fatalf("Run() stopped on a missing file: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not report the missing file")
+	}
+	os.Rename(file+".tmp", file)
+
+	s.Add(model.NewTask("aaa", "After the checkout", model.TypeTask))
+	select {
+	case e := <-events:
+		if e.Type != EventCreated || e.ID != "aaa" {
+			t.Errorf("event after retry = %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not carry on after the error")
+	}
+	if n := len(errs); n != 0 {
+		t.Errorf("the same error was reported %d more time(s)", n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s := newTestStore(t)
+	ts := httptest.NewServer(Handler(s, 10*time.Millisecond))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	// Wait for the stream to open before changing anything
+	if line, err := reader.ReadString('\n'); err != nil || !strings.HasPrefix(line, ":") {
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+
+	s.Add(model.NewTask("aaa", "Streamed", model.TypeTask))
+
+	var name, data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		line = strings.TrimSpace(line)
+		if v, ok := strings.CutPrefix(line, "event: "); ok {
+			name = v
+		}
+		if v, ok := strings.CutPrefix(line, "data: "); ok {
+			data = v
+		}
+	}
+
+	if name != "created" {
+		t.Errorf("event name = %q, want created", name)
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		t.Fatalf("event data %q is not JSON: %v", data, err)
+	}
+	if e.Type != EventCreated || e.ID != "aaa" || e.Task.Title != "Streamed" {
+		t.Errorf("event = %+v", e)
+	}
+}
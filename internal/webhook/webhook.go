@@ -0,0 +1,446 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jackreid/task/internal/config"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+	"github.com/jackreid/task/internal/version"
+)
+
+// OutboxFile holds undelivered payloads within the task directory. It has no
+// .jsonl extension so it is never mistaken for a collection
+const OutboxFile = "webhooks.outbox"
+
+// Request headers sent with every delivery
+const (
+	EventHeader     = "X-Task-Event"
+	DeliveryHeader  = "X-Task-Delivery"
+	SignatureHeader = "X-Task-Signature"
+)
+
+// EventStatusChange selects updates that change a task's status
+const EventStatusChange = "status_change"
+
+const (
+	// DefaultTimeout limits each delivery attempt
+	DefaultTimeout = 5 * time.Second
+	// MaxAttempts is the number of failed attempts after which a delivery is
+	// only retried by an explicit flush
+	MaxAttempts = 10
+
+	retryBase = 30 * time.Second
+	retryMax  = time.Hour
+)
+
+// Payload is the JSON body POSTed for a task change
+type Payload struct {
+	Event        string        `json:"event"`
+	Collection   string        `json:"collection"`
+	TaskID       string        `json:"task_id"`
+	Time         time.Time     `json:"time"`
+	StatusChange *StatusChange `json:"status_change,omitempty"`
+	Before       *model.Task   `json:"before"`
+	After        *model.Task   `json:"after"`
+}
+
+// StatusChange describes the status change of an update
+type StatusChange struct {
+	From model.Status `json:"from"`
+	To   model.Status `json:"to"`
+}
+
+// Delivery is a payload waiting in the outbox to be sent to a URL
+type Delivery struct {
+	ID          string          `json:"id"`
+	URL         string          `json:"url"`
+	Event       string          `json:"event"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// FlushResult counts the outcome of a flush
+type FlushResult struct {
+	Delivered int
+	Failed    int
+	// Dropped counts deliveries to URLs that are no longer configured
+	Dropped int
+	// Pending is what is left in the outbox
+	Pending int
+}
+
+// Dispatcher sends task changes to the configured webhooks. Every payload is
+// written to the outbox as the change is made and removed once a webhook
+// accepts it, so payloads that can't be delivered are retried later rather
+// than lost. Sending is left to Send, once per command, or to Run in the
+// background, so a bulk change never waits on a slow webhook per task
+type Dispatcher struct {
+	webhooks []config.Webhook
+	outbox   string
+	out      io.Writer
+	client   *http.Client
+	now      func() time.Time
+
+	// outboxMu guards reading and rewriting the outbox, and flushMu makes
+	// flushes take turns. Deliveries are sent without holding outboxMu so
+	// changes can be queued meanwhile
+	outboxMu sync.Mutex
+	flushMu  sync.Mutex
+	// queued wakes Run when changes are queued
+	queued chan struct{}
+}
+
+// New creates a dispatcher for the webhooks with its outbox in taskDir.
+// Delivery failures during mutations are reported to out
+func New(taskDir string, webhooks []config.Webhook, out io.Writer) *Dispatcher {
+	return &Dispatcher{
+		webhooks: webhooks,
+		outbox:   filepath.Join(taskDir, OutboxFile),
+		out:      out,
+		client:   &http.Client{Timeout: DefaultTimeout},
+		now:      time.Now,
+		queued:   make(chan struct{}, 1),
+	}
+}
+
+// BeforeMutation implements store.Hook. Webhooks can't reject changes
+func (d *Dispatcher) BeforeMutation(store.Mutation) error {
+	return nil
+}
+
+// AfterMutation queues the change for every interested webhook. It is sent
+// by the next Send, or straight away while Run is running
+func (d *Dispatcher) AfterMutation(m store.Mutation) {
+	queued, err := d.Enqueue(m)
+	if err != nil {
+		fmt.Fprintf(d.out, "Warning: queueing webhook: %v\n", err)
+		return
+	}
+	if queued == 0 {
+		return
+	}
+	select {
+	case d.queued <- struct{}{}:
+	default:
+	}
+}
+
+// Send attempts the deliveries that are due, reporting failures to out
+func (d *Dispatcher) Send() {
+	result, err := d.Flush(false)
+	if err != nil {
+		fmt.Fprintf(d.out, "Warning: sending webhooks: %v\n", err)
+		return
+	}
+	if result.Failed > 0 {
+		fmt.Fprintf(d.out, "Warning: %d webhook delivery(s) failed, will retry later (see 'task webhooks')\n", result.Failed)
+	}
+}
+
+// Run sends changes in the background as they are queued, until ctx is
+// done. It is for long-running commands such as task serve
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.queued:
+			d.Send()
+		}
+	}
+}
+
+// Enqueue adds a delivery to the outbox for each webhook interested in the
+// mutation and returns how many were added
+func (d *Dispatcher) Enqueue(m store.Mutation) (int, error) {
+	payload := newPayload(m, d.now().UTC())
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	var added []Delivery
+	for _, w := range d.webhooks {
+		if !wants(w, payload) {
+			continue
+		}
+		id, err := deliveryID()
+		if err != nil {
+			return 0, err
+		}
+		added = append(added, Delivery{
+			ID:          id,
+			URL:         w.URL,
+			Event:       payload.Event,
+			Payload:     data,
+			CreatedAt:   payload.Time,
+			NextAttempt: payload.Time,
+		})
+	}
+	if len(added) == 0 {
+		return 0, nil
+	}
+
+	d.outboxMu.Lock()
+	defer d.outboxMu.Unlock()
+	pending, err := d.Pending()
+	if err != nil {
+		return 0, err
+	}
+	return len(added), d.save(append(pending, added...))
+}
+
+// Flush attempts the deliveries that are due, oldest first. With all set,
+// every pending delivery is attempted regardless of backoff. Deliveries
+// queued while it runs are kept for the next flush
+func (d *Dispatcher) Flush(all bool) (FlushResult, error) {
+	d.flushMu.Lock()
+	defer d.flushMu.Unlock()
+
+	var result FlushResult
+	d.outboxMu.Lock()
+	pending, err := d.Pending()
+	d.outboxMu.Unlock()
+	if err != nil || len(pending) == 0 {
+		return result, err
+	}
+
+	now := d.now()
+	// done holds the deliveries that leave the outbox, and retry the failed
+	// ones with their next attempt
+	done := make(map[string]bool)
+	retry := make(map[string]Delivery)
+	for _, delivery := range pending {
+		w := d.find(delivery.URL)
+		if w == nil {
+			done[delivery.ID] = true
+			result.Dropped++
+			continue
+		}
+		due := !now.Before(delivery.NextAttempt) && delivery.Attempts < MaxAttempts
+		if !all && !due {
+			continue
+		}
+
+		if err := d.send(*w, delivery); err != nil {
+			delivery.Attempts++
+			delivery.LastError = err.Error()
+			delivery.NextAttempt = now.Add(backoff(delivery.Attempts))
+			retry[delivery.ID] = delivery
+			result.Failed++
+			continue
+		}
+		done[delivery.ID] = true
+		result.Delivered++
+	}
+
+	d.outboxMu.Lock()
+	defer d.outboxMu.Unlock()
+	current, err := d.Pending()
+	if err != nil {
+		return result, err
+	}
+	remaining := make([]Delivery, 0, len(current))
+	for _, delivery := range current {
+		if done[delivery.ID] {
+			continue
+		}
+		if failed, ok := retry[delivery.ID]; ok {
+			delivery = failed
+		}
+		remaining = append(remaining, delivery)
+	}
+	result.Pending = len(remaining)
+	return result, d.save(remaining)
+}
+
+// Pending returns the deliveries waiting in the outbox
+func (d *Dispatcher) Pending() ([]Delivery, error) {
+	f, err := os.Open(d.outbox)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading webhook outbox: %w", err)
+	}
+	defer f.Close()
+
+	var deliveries []Delivery
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var delivery Delivery
+		if err := json.Unmarshal(line, &delivery); err != nil {
+			return nil, fmt.Errorf("parsing webhook outbox: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading webhook outbox: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Clear discards every pending delivery and returns how many there were
+func (d *Dispatcher) Clear() (int, error) {
+	d.outboxMu.Lock()
+	defer d.outboxMu.Unlock()
+	pending, err := d.Pending()
+	if err != nil {
+		return 0, err
+	}
+	return len(pending), d.save(nil)
+}
+
+// save rewrites the outbox, removing it when nothing is pending. The new
+// contents are written to a temporary file first so a crash never leaves a
+// truncated outbox
+func (d *Dispatcher) save(deliveries []Delivery) error {
+	if len(deliveries) == 0 {
+		if err := os.Remove(d.outbox); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing webhook outbox: %w", err)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, delivery := range deliveries {
+		data, err := json.Marshal(delivery)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	tmp := d.outbox + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("writing webhook outbox: %w", err)
+	}
+	if err := os.Rename(tmp, d.outbox); err != nil {
+		return fmt.Errorf("writing webhook outbox: %w", err)
+	}
+	return nil
+}
+
+// send POSTs a delivery, treating any 2xx response as success
+func (d *Dispatcher) send(w config.Webhook, delivery Delivery) error {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "task/"+version.Version)
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	if secret := w.SigningSecret(); secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, delivery.Payload))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	return nil
+}
+
+// find returns the configured webhook for a URL
+func (d *Dispatcher) find(url string) *config.Webhook {
+	for i := range d.webhooks {
+		if d.webhooks[i].URL == url {
+			return &d.webhooks[i]
+		}
+	}
+	return nil
+}
+
+// Sign returns the signature header value for a body: "sha256=" followed by
+// the hex HMAC-SHA256 of the body keyed with the secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for body, for use by receivers
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// newPayload describes a mutation
+func newPayload(m store.Mutation, now time.Time) Payload {
+	p := Payload{
+		Event:      string(m.Event),
+		Collection: m.Collection,
+		TaskID:     m.Task().ID,
+		Time:       now,
+		Before:     m.Before,
+		After:      m.After,
+	}
+	if m.Before != nil && m.After != nil && m.Before.Status != m.After.Status {
+		p.StatusChange = &StatusChange{From: m.Before.Status, To: m.After.Status}
+	}
+	return p
+}
+
+// wants reports whether a webhook subscribes to a payload's event
+func wants(w config.Webhook, p Payload) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == p.Event || (e == EventStatusChange && p.StatusChange != nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the next attempt after a number of
+// failed attempts
+func backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	if delay > retryMax {
+		delay = retryMax
+	}
+	return delay
+}
+
+func deliveryID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating delivery ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,264 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackreid/task/internal/config"
+	"github.com/jackreid/task/internal/model"
+	"github.com/jackreid/task/internal/store"
+)
+
+// receiver is a webhook endpoint that records what it is sent
+type receiver struct {
+	mu       sync.Mutex
+	fail     bool
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	if rc.fail {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}
+}
+
+func (rc *receiver) setFail(fail bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.fail = fail
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+// setup creates a store whose changes are sent to the webhooks
+func setup(t *testing.T, webhooks ...config.Webhook) (*store.Store, *Dispatcher, *bytes.Buffer) {
+	t.Helper()
+	s := store.New(t.TempDir())
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	var out bytes.Buffer
+	d := New(s.Dir(), webhooks, &out)
+	s.AddHook(d)
+	return s, d, &out
+}
+
+func TestDeliverySigned(t *testing.T) {
+	rc := &receiver{}
+	ts := httptest.NewServer(rc)
+	defer ts.Close()
+
+	s, d, out := setup(t, config.Webhook{URL: ts.URL, Secret: "s3cret"})
+
+	task := model.NewTask("aaa", "Hooked", model.TypeTask)
+	s.Add(task)
+	task.SetStatus(model.StatusDone)
+	s.Update(task)
+	if rc.count() != 0 {
+		t.Fatalf("changes should wait for Send, got %d requests", rc.count())
+	}
+	d.Send()
+
+	if rc.count() != 2 {
+		t.Fatalf("receiver got %d requests, want 2", rc.count())
+	}
+	if out.Len() != 0 {
+		t.Errorf("unexpected warnings: %s", out.String())
+	}
+
+	req, body := rc.requests[1], rc.bodies[1]
+	if req.Header.Get(EventHeader) != "update" || req.Header.Get(DeliveryHeader) == "" {
+		t.Errorf("headers = %v", req.Header)
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", req.Header.Get("Content-Type"))
+	}
+	if !Verify("s3cret", body, req.Header.Get(SignatureHeader)) {
+		t.Errorf("signature %q does not verify", req.Header.Get(SignatureHeader))
+	}
+	if Verify("wrong", body, req.Header.Get(SignatureHeader)) {
+		t.Error("signature should not verify with another secret")
+	}
+
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if p.Event != "update" || p.TaskID != "aaa" || p.Collection != store.DefaultCollection {
+		t.Errorf("payload = %+v", p)
+	}
+	if p.StatusChange == nil || p.StatusChange.From != model.StatusTodo || p.StatusChange.To != model.StatusDone {
+		t.Errorf("status_change = %+v", p.StatusChange)
+	}
+	if p.Before == nil || p.After == nil || p.Before.Status != model.StatusTodo {
+		t.Errorf("before/after = %+v / %+v", p.Before, p.After)
+	}
+
+	if pending, _ := d.Pending(); len(pending) != 0 {
+		t.Errorf("delivered payloads should leave the outbox, got %d", len(pending))
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	rc := &receiver{}
+	ts := httptest.NewServer(rc)
+	defer ts.Close()
+
+	s, d, _ := setup(t, config.Webhook{URL: ts.URL, Events: []string{EventStatusChange, "delete"}})
+
+	task := model.NewTask("aaa", "Filtered", model.TypeTask)
+	s.Add(task)
+	task.SetTitle("Renamed")
+	s.Update(task)
+	task.SetStatus(model.StatusProgress)
+	s.Update(task)
+	s.Delete("aaa")
+	d.Send()
+
+	if rc.count() != 2 {
+		t.Fatalf("receiver got %d requests, want the status change and delete", rc.count())
+	}
+	if rc.requests[0].Header.Get(EventHeader) != "update" || rc.requests[1].Header.Get(EventHeader) != "delete" {
+		t.Errorf("events = %s, %s", rc.requests[0].Header.Get(EventHeader), rc.requests[1].Header.Get(EventHeader))
+	}
+}
+
+func TestRetryFromOutbox(t *testing.T) {
+	rc := &receiver{fail: true}
+	ts := httptest.NewServer(rc)
+	defer ts.Close()
+
+	s, d, out := setup(t, config.Webhook{URL: ts.URL})
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	s.Add(model.NewTask("aaa", "Queued", model.TypeTask))
+	d.Send()
+	if !bytes.Contains(out.Bytes(), []byte("1 webhook delivery(s) failed")) {
+		t.Errorf("failed delivery should warn, got %q", out.String())
+	}
+
+	pending, err := d.Pending()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("Pending() = %v, %v, want 1 delivery", pending, err)
+	}
+	if pending[0].Attempts != 1 || pending[0].LastError != "unexpected response: 503 Service Unavailable" {
+		t.Errorf("pending delivery = %+v", pending[0])
+	}
+
+	// Not yet due, so a normal flush leaves it alone
+	rc.setFail(false)
+	result, _ := d.Flush(false)
+	if result.Delivered != 0 || result.Pending != 1 || rc.count() != 1 {
+		t.Errorf("Flush(false) before backoff = %+v", result)
+	}
+
+	// A later invocation, with a fresh dispatcher, delivers it
+	later := New(s.Dir(), []config.Webhook{{URL: ts.URL}}, out)
+	later.now = func() time.Time { return now.Add(time.Minute) }
+	result, err = later.Flush(false)
+	if err != nil || result.Delivered != 1 || result.Pending != 0 {
+		t.Errorf("Flush(false) after backoff = %+v, %v", result, err)
+	}
+
+	var first, retried Payload
+	json.Unmarshal(rc.bodies[0], &first)
+	json.Unmarshal(rc.bodies[1], &retried)
+	if retried.TaskID != "aaa" || !retried.Time.Equal(first.Time) {
+		t.Errorf("retried payload = %+v, want the original", retried)
+	}
+	if rc.requests[0].Header.Get(DeliveryHeader) != rc.requests[1].Header.Get(DeliveryHeader) {
+		t.Error("a retry should keep its delivery ID")
+	}
+}
+
+func TestFlushAllAndDropped(t *testing.T) {
+	rc := &receiver{fail: true}
+	ts := httptest.NewServer(rc)
+	defer ts.Close()
+
+	s, d, _ := setup(t, config.Webhook{URL: ts.URL}, config.Webhook{URL: ts.URL + "/other"})
+	s.Add(model.NewTask("aaa", "Queued", model.TypeTask))
+
+	rc.setFail(false)
+	removed := New(s.Dir(), []config.Webhook{{URL: ts.URL}}, &bytes.Buffer{})
+	result, err := removed.Flush(true)
+	if err != nil {
+		t.Fatalf("Flush(true) error = %v", err)
+	}
+	if result.Delivered != 1 || result.Dropped != 1 || result.Pending != 0 {
+		t.Errorf("Flush(true) = %+v, want 1 delivered and 1 dropped", result)
+	}
+
+	rc.setFail(true)
+	s.Add(model.NewTask("bbb", "Cleared", model.TypeTask))
+	if n, err := d.Clear(); err != nil || n != 2 {
+		t.Errorf("Clear() = %d, %v, want 2", n, err)
+	}
+	if pending, _ := d.Pending(); len(pending) != 0 {
+		t.Error("Clear() should empty the outbox")
+	}
+}
+
+func TestRun(t *testing.T) {
+	rc := &receiver{}
+	ts := httptest.NewServer(rc)
+	defer ts.Close()
+
+	s, d, _ := setup(t, config.Webhook{URL: ts.URL})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	s.Add(model.NewTask("aaa", "Sent in the background", model.TypeTask))
+	for deadline := time.Now().Add(5 * time.Second); rc.count() == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rc.count() != 1 {
+		t.Errorf("Run() sent %d requests, want 1", rc.count())
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return once its context was cancelled")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}